@@ -60,6 +60,42 @@ type DNSIntegrationSpec struct {
 	// The SecretRef has precedence over the `Env` field so any keys specified here will override
 	// values that would otherwise be defined in the `Env` field.
 	SecretRef *references.SecretRef `json:"secretRef,omitempty"`
+
+	// Registry enables the ownership registry for this integration. When set, the provider creates a companion
+	// TXT record for each DNS record it creates. The TXT record holds the OwnerID so the provider
+	// can tell which records it owns. The provider refuses to create, update or delete a record that exists on the provider
+	// but isn't owned by this integration. This is useful when multiple clusters share the same zones.
+	//
+	// The provider needs to support reading records for the registry to work.
+	Registry *RegistrySpec `json:"registry,omitempty"`
+
+	// Mode defines where the provider runs. With `Deployment`, a deployment is created for the provider. With
+	// `InProcess`, the provider runs inside the controller and no pod is created. The controller needs to be
+	// started with `--in-process-providers` for this mode to be available.
+	// +kubebuilder:validation:Enum=Deployment;InProcess
+	// +kubebuilder:default=Deployment
+	// +optional
+	Mode IntegrationMode `json:"mode,omitempty"`
+}
+
+type IntegrationMode string
+
+const (
+	DeploymentMode IntegrationMode = "Deployment"
+	InProcessMode  IntegrationMode = "InProcess"
+)
+
+type RegistrySpec struct {
+	// OwnerID identifies this integration as the owner of the records it creates. Each cluster
+	// that shares a zone needs to use a different OwnerID.
+	// +kubebuilder:validation:MinLength=1
+	OwnerID string `json:"ownerID"`
+
+	// Prefix added to the name of the companion TXT record, ie. a record `www` of type `A` would
+	// have a companion TXT record named `phonebook-a-www`.
+	// +kubebuilder:default="phonebook-"
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
 }
 
 type DNSProviderSpec struct {
@@ -68,15 +104,81 @@ type DNSProviderSpec struct {
 	Name string `json:"name"`
 
 	// Image name if you want to use a different image name than the default one used
-	// by Phonebook. If this value isn't set, Phonebook will generate an image name
-	// based off the Provider's name and Phonebook's default repository.
-	// It will also always use the `latest` tag
+	// by Phonebook. If this value isn't set, Phonebook uses the image that includes every
+	// provider that ships with Phonebook, and selects the provider by its name.
 	Image *string `json:"image,omitempty"`
 
 	// Command can be spceifici
 	Command []string `json:"cmd,omitempty"`
 
 	Args []string `json:"args,omitempty"`
+
+	// PodTemplate customizes the pod that runs the provider. The values are merged into the
+	// deployment that Phonebook generates for this integration.
+	PodTemplate *ProviderPodTemplate `json:"podTemplate,omitempty"`
+}
+
+// ProviderPodTemplate holds the fields of the provider's deployment that can be customized. Fields that aren't
+// set keep the values Phonebook generates. Labels and annotations are added to the pod's, but can't
+// override the ones set by Phonebook.
+type ProviderPodTemplate struct {
+	// Number of pods running the provider. Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Labels added to the provider's pod.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations added to the provider's pod.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Resources of the provider's container.
+	// +optional
+	Resources *core.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeSelector of the provider's pod.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations of the provider's pod.
+	// +optional
+	Tolerations []core.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity of the provider's pod.
+	// +optional
+	Affinity *core.Affinity `json:"affinity,omitempty"`
+
+	// ServiceAccountName used by the provider's pod. Defaults to the service account
+	// installed with Phonebook's chart.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// ImagePullSecrets used to pull the provider's image.
+	// +optional
+	ImagePullSecrets []core.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// PriorityClassName of the provider's pod.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// SecurityContext of the provider's pod.
+	// +optional
+	SecurityContext *core.PodSecurityContext `json:"securityContext,omitempty"`
+
+	// ContainerSecurityContext is the security context of the provider's container.
+	// +optional
+	ContainerSecurityContext *core.SecurityContext `json:"containerSecurityContext,omitempty"`
+
+	// Volumes added to the provider's pod.
+	// +optional
+	Volumes []core.Volume `json:"volumes,omitempty"`
+
+	// VolumeMounts added to the provider's container.
+	// +optional
+	VolumeMounts []core.VolumeMount `json:"volumeMounts,omitempty"`
 }
 
 // DNSProviderStatus defines the observed state of DNSProvider
@@ -88,6 +190,10 @@ type DNSIntegrationStatus struct {
 	// Reference to the deployment that was created for this
 	// Integration.
 	Deployment *references.Reference `json:"deployment,omitempty"`
+
+	// Generation of the integration that was last applied to
+	// the deployment.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -27,11 +27,24 @@ const (
 	ProviderCondition konditions.ConditionType = "Provider"
 
 	IntegrationCondition konditions.ConditionType = "Integration"
+
+	// Status set on a drift condition when the record stored by the provider
+	// doesn't match the DNSRecord's spec anymore.
+	ConditionDrifted konditions.ConditionStatus = "Drifted"
+
+	// Status set on a provider condition when the provider refused to modify a record
+	// it doesn't own, ie. a record with the same name and type was created outside of Phonebook.
+	ConditionRefused konditions.ConditionStatus = "Refused"
+
+	// Status set on the propagation condition once the record is visible on all the authoritative
+	// nameservers of its zone.
+	ConditionPropagated konditions.ConditionStatus = "Propagated"
 )
 
 // DNSRecordSpec defines the desired state of DNSRecord and represents
 // a single DNS Record. It is expected that each DNS Record won't conflict with each other
 // and it's the user's job to make sure that each record have a unique spec.
+// +kubebuilder:validation:XValidation:rule="has(self.zone) == has(oldSelf.zone)",message="zone is immutable"
 type DNSRecordSpec struct {
 	// Zone is the the DNS Zone that you want to create a record for.
 	// If you want to create a CNAME called foo.mydomain.com,
@@ -41,15 +54,32 @@ type DNSRecordSpec struct {
 	// cluster. Unless the optional `Provider` field is set, Phonebook will look
 	// at all the providers configured to try to find a match for the zone.
 	//
+	// The zone is optional. When it's empty, `Name` is expected to be a fully qualified
+	// name (ie. foo.mydomain.com) and Phonebook uses the longest zone, among all
+	// the DNSIntegrations, that the name ends with. The zone and the name relative to it are
+	// stored in the record's status.
+	//
 	// If no provider matches the zone, the record won't be created.
-	Zone string `json:"zone"`
+	//
+	// The zone cannot be changed once the record is created.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="zone is immutable"
+	// +optional
+	Zone string `json:"zone,omitempty"`
 
 	// RecordType represent the type for the Record you want to create.
 	// Can be A, AAAA, CNAME, TXT, etc.
+	//
+	// The record type cannot be changed once the record is created.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="recordType is immutable"
 	RecordType string `json:"recordType"`
 
 	// Name of the record represents the subdomain in the CNAME example used for zone.
-	// In that example, the `Name` would be `foo`
+	// In that example, the `Name` would be `foo`. If the zone is empty, the name
+	// needs to be fully qualified, ie. `foo.mydomain.com`.
+	//
+	// The name cannot be changed once the record is created. Targets, TTL and properties
+	// can all be updated in place.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="name is immutable"
 	Name string `json:"name"`
 
 	// Targets represents where the record should point to. Depending on the record type,
@@ -95,9 +125,9 @@ type IntegrationInfo map[string]string
 // As the name of the methods and interface suggest, these operations are only staging. As a result, they aren't persisted
 // when those method returns. In fact, multiple calls overwrite the previously set values during the **same reconciliation loop**.
 //
-// When Create/Destroy returns, the server's reconciliation loop will update the Condition and the IntegrationInfo.
+// When Create/Update/Destroy returns, the server's reconciliation loop will update the Condition and the IntegrationInfo.
 //
-// It is important to note that in case of an error returning from Create/Destroy, the error will take precedence over
+// It is important to note that in case of an error returning from Create/Update/Destroy, the error will take precedence over
 // the staged condition. The Status will be set to Error and the reason will be set to the error message.
 // +kubebuilder:object:generate=false
 type StagingUpdater interface {
@@ -140,6 +170,19 @@ type DNSRecordStatus struct {
 	// A DNSIntegration can have multiple entries stored in this field and it's up the integration
 	// to make sure those fields are not stale.
 	RemoteInfo map[string]IntegrationInfo `json:"remoteInfo,omitempty"`
+
+	// ObservedGenerations keeps track of the DNSRecord's generation that each integration
+	// last applied to its provider. Like RemoteInfo, each entry is keyed by the name of the
+	// integration. When the DNSRecord's generation is greater than the one observed
+	// by an integration, the spec changed and the provider needs to update the record.
+	ObservedGenerations map[string]int64 `json:"observedGenerations,omitempty"`
+
+	// Zone is set by Phonebook when the DNSRecord's spec doesn't specify a zone. It is the
+	// longest zone, among all the DNSIntegrations, that the fully qualified name ends with.
+	Zone string `json:"zone,omitempty"`
+
+	// Name is the name of the record relative to the Zone stored in the status.
+	Name string `json:"name,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -161,6 +204,19 @@ func (d *DNSRecord) Conditions() *konditions.Conditions {
 	return &d.Status.Conditions
 }
 
+// Resolved returns a copy of the DNSRecord where the spec's Zone and Name are
+// relative to the zone. If the spec doesn't specify a zone, the values resolved
+// by Phonebook and stored in the status are used instead.
+func (d *DNSRecord) Resolved() DNSRecord {
+	record := *d.DeepCopy()
+	if record.Spec.Zone == "" {
+		record.Spec.Zone = record.Status.Zone
+		record.Spec.Name = record.Status.Name
+	}
+
+	return record
+}
+
 // +kubebuilder:object:root=true
 
 // DNSRecordList contains a list of DNSRecord
@@ -5,7 +5,18 @@ import "github.com/pier-oliviert/konditionner/pkg/konditions"
 const (
 	DeploymentCondition konditions.ConditionType = "Deployment"
 	HealthCondition     konditions.ConditionType = "Health"
+	RolloutCondition    konditions.ConditionType = "Rollout"
+
+	// Prefix of the conditions set by the provider for each of its zones, ie. `zone.mydomain.com`.
+	ZoneConditionPrefix = "zone."
 
 	DeploymentFinalizer = "phonebook.se.quencer.io/deployment"
 	DeploymentLabel     = "phonebook.se.quencer.io/deployment"
+
+	// Annotation set on the pod template of the provider's deployment. It holds a hash of
+	// the secret's values used by the provider so the pods are rolled when those values change.
+	SecretHashAnnotation = "phonebook.se.quencer.io/secret-hash"
+
+	// Field manager used when the deployment is applied.
+	FieldManager = "phonebook"
 )
@@ -20,7 +20,9 @@ import (
 	"context"
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -28,20 +30,25 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/utils/env"
 
+	core "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
 	"github.com/pier-oliviert/phonebook/internal/reconcilers/controller"
 	"github.com/pier-oliviert/phonebook/internal/solver"
+	"github.com/pier-oliviert/phonebook/pkg/server"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -53,6 +60,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(phonebook.AddToScheme(scheme))
+	utilruntime.Must(gatewayv1.Install(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -63,6 +71,8 @@ func main() {
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var enableSolver bool
+	var inProcess bool
+	var sources string
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -76,6 +86,10 @@ func main() {
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
 	flag.BoolVar(&enableSolver, "solver", false,
 		"Enable cert-manager solver for DNS-01 Challenges.")
+	flag.BoolVar(&inProcess, "in-process-providers", false,
+		"Run the providers of integrations in the InProcess mode inside the controller instead of their own deployment.")
+	flag.StringVar(&sources, "sources", "",
+		"Comma separated list of sources that generate DNSRecords from other resources, ie. service,ingress,gateway.")
 
 	opts := zap.Options{
 		Development: true,
@@ -136,6 +150,17 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "dcf9568b.provider.phonebook.se.quencer.io",
+		// Secrets are only watched in the namespace where the providers are deployed, which is where
+		// the secrets referenced by the integrations live.
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&core.Secret{}: {
+					Namespaces: map[string]cache.Config{
+						env.GetString("PB_NAMESPACE", "phonebook-system"): {},
+					},
+				},
+			},
+		},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -162,14 +187,62 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controller.DNSIntegrationReconciler{
+	integrationReconciler := &controller.DNSIntegrationReconciler{
 		Client:        mgr.GetClient(),
 		Scheme:        mgr.GetScheme(),
 		EventRecorder: mgr.GetEventRecorderFor("dnsintegration"),
-	}).SetupWithManager(mgr); err != nil {
+	}
+
+	if inProcess {
+		runner := server.NewInProcess(mgr, inProcessProviders())
+		if err := mgr.Add(runner); err != nil {
+			logger.Error(err, "PB#0004: Unable to set up in-process providers")
+			os.Exit(1)
+		}
+
+		integrationReconciler.Runner = runner
+	}
+
+	if err = integrationReconciler.SetupWithManager(mgr); err != nil {
 		logger.Error(err, "PB#0004: Unable to create controller", "controller", "DNSIntegration")
 		os.Exit(1)
 	}
+
+	for _, source := range strings.Split(sources, ",") {
+		switch strings.TrimSpace(source) {
+		case "":
+		case "service":
+			err = (&controller.ServiceReconciler{
+				Client:        mgr.GetClient(),
+				Scheme:        mgr.GetScheme(),
+				EventRecorder: mgr.GetEventRecorderFor("service-source"),
+			}).SetupWithManager(mgr)
+		case "ingress":
+			err = (&controller.IngressReconciler{
+				Client:        mgr.GetClient(),
+				Scheme:        mgr.GetScheme(),
+				EventRecorder: mgr.GetEventRecorderFor("ingress-source"),
+			}).SetupWithManager(mgr)
+		case "gateway":
+			for _, kind := range []string{"HTTPRoute", "GRPCRoute"} {
+				if err = (&controller.RouteReconciler{
+					Client:        mgr.GetClient(),
+					Scheme:        mgr.GetScheme(),
+					EventRecorder: mgr.GetEventRecorderFor("gateway-source"),
+					Kind:          kind,
+				}).SetupWithManager(mgr); err != nil {
+					break
+				}
+			}
+		default:
+			err = fmt.Errorf("unknown source: %s", source)
+		}
+
+		if err != nil {
+			logger.Error(err, "PB#0004: Unable to create controller", "controller", source)
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
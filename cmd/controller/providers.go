@@ -0,0 +1,17 @@
+package main
+
+import (
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/all"
+)
+
+// Providers that can run inside the controller. The embedded provider isn't part of it as it serves
+// DNS queries from its own pods. The plugin provider isn't either as it executes the binary set in the
+// integration, which would run it in the controller's pod.
+func inProcessProviders() map[string]providers.Constructor {
+	constructors := providers.Constructors()
+	delete(constructors, "embedded")
+	delete(constructors, "plugin")
+
+	return constructors
+}
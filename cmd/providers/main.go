@@ -19,19 +19,29 @@ package main
 import (
 	"context"
 
-	"github.com/pier-oliviert/phonebook/pkg/providers/aws"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/all"
+	"github.com/pier-oliviert/phonebook/pkg/providers/plugin"
 	"github.com/pier-oliviert/phonebook/pkg/server"
+	"k8s.io/utils/env"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// A single binary for every provider that ships with Phonebook. The provider is selected by
+// its name, which the integration's deployment sets in PB_PROVIDER.
 func main() {
 	var err error
 
 	ctx := context.Background()
 	logger := log.FromContext(ctx)
 
-	logger.Info("Initializing AWS Client")
-	p, err := aws.NewClient(ctx)
+	// Make sure the plugin's process doesn't outlive the provider.
+	defer plugin.Cleanup()
+
+	name := env.GetString("PB_PROVIDER", "")
+
+	logger.Info("Initializing Client", "Provider", name)
+	p, err := providers.New(ctx, name)
 	if err != nil {
 		panic(err)
 	}
@@ -19,19 +19,24 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/env"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/pier-oliviert/konditionner/pkg/konditions"
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
@@ -44,11 +49,16 @@ type DNSIntegrationReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	record.EventRecorder
+
+	// Runner runs the providers of integrations that are in the InProcess mode. It's nil
+	// when the controller wasn't started with in-process providers.
+	Runner tasks.Runner
 }
 
 // +kubebuilder:rbac:groups=se.quencer.io.se.quencer.io,resources=dnsintegrations,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=se.quencer.io.se.quencer.io,resources=dnsintegrations/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=se.quencer.io.se.quencer.io,resources=dnsintegrations/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,namespace=phonebook-system,resources=secrets,verbs=get;list;watch
 func (r *DNSIntegrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	integration, err := r.GetIntegration(ctx, req)
 	if k8sErrors.IsNotFound(err) {
@@ -62,6 +72,10 @@ func (r *DNSIntegrationReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	log.FromContext(ctx).Info("Reconciling for", "Integration", integration.Name)
 
 	if !integration.DeletionTimestamp.IsZero() {
+		if r.Runner != nil {
+			r.Runner.Stop(integration.Name)
+		}
+
 		condition := integration.Status.Conditions.FindOrInitializeFor(integrations.DeploymentCondition)
 		if condition.Status == konditions.ConditionTerminated {
 			if controllerutil.RemoveFinalizer(integration, integrations.DeploymentFinalizer) {
@@ -80,7 +94,21 @@ func (r *DNSIntegrationReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{Requeue: true}, r.Update(ctx, integration)
 	}
 
-	if condition := integration.Conditions().FindOrInitializeFor(integrations.DeploymentCondition); condition.Status == konditions.ConditionInitialized {
+	if integration.Spec.Mode == phonebook.InProcessMode {
+		return r.reconcileInProcess(ctx, integration)
+	}
+
+	// The integration might have been running in the controller before.
+	if r.Runner != nil {
+		r.Runner.Stop(integration.Name)
+	}
+
+	outdated, err := tasks.DeploymentOutdated(ctx, r.Client, integration)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if condition := integration.Conditions().FindOrInitializeFor(integrations.DeploymentCondition); condition.Status == konditions.ConditionInitialized || outdated {
 		lock := konditions.NewLock(integration, r.Client, integrations.DeploymentCondition)
 		err := lock.Execute(ctx, tasks.DeploymentTask(ctx, r.Client, integration))
 		if err != nil {
@@ -89,8 +117,49 @@ func (r *DNSIntegrationReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, err
 	}
 
+	// The rollout's progress is reported on every reconciliation, which happens every time the
+	// deployment's status changes. The status is only updated when the condition changed.
+	condition := integration.Conditions().FindOrInitializeFor(integrations.RolloutCondition)
+	rollout, err := tasks.RolloutTask(ctx, r.Client, integration)(condition)
+	if err != nil {
+		r.Event(integration, core.EventTypeWarning, string(integrations.RolloutCondition), err.Error())
+	}
+
+	if rollout.Status != condition.Status || rollout.Reason != condition.Reason {
+		if err := integration.Status.Conditions.SetCondition(rollout); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := r.Status().Update(ctx, integration); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return r.health(ctx, integration)
+}
+
+// The provider runs inside the controller, no deployment is created for the integration.
+func (r *DNSIntegrationReconciler) reconcileInProcess(ctx context.Context, integration *phonebook.DNSIntegration) (ctrl.Result, error) {
+	outdated, err := tasks.InProcessOutdated(ctx, r.Client, r.Runner, integration)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if condition := integration.Conditions().FindOrInitializeFor(integrations.DeploymentCondition); condition.Status == konditions.ConditionInitialized || outdated {
+		lock := konditions.NewLock(integration, r.Client, integrations.DeploymentCondition)
+		err := lock.Execute(ctx, tasks.InProcessTask(ctx, r.Client, r.Runner, integration))
+		if err != nil {
+			r.Event(integration, core.EventTypeWarning, string(lock.Condition().Type), err.Error())
+		}
+		return ctrl.Result{}, err
+	}
+
+	return r.health(ctx, integration)
+}
+
+func (r *DNSIntegrationReconciler) health(ctx context.Context, integration *phonebook.DNSIntegration) (ctrl.Result, error) {
 	lock := konditions.NewLock(integration, r.Client, integrations.HealthCondition)
-	err = lock.Execute(ctx, tasks.HealthTask(ctx, r.Client, integration))
+	err := lock.Execute(ctx, tasks.HealthTask(ctx, r.Client, integration))
 	if err != nil {
 		r.Event(integration, core.EventTypeWarning, string(lock.Condition().Type), err.Error())
 	}
@@ -114,8 +183,9 @@ func (r *DNSIntegrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			oldObj := e.ObjectOld.(*phonebook.DNSIntegration)
 			newObj := e.ObjectNew.(*phonebook.DNSIntegration)
 
-			// Trigger reconciliation only if the spec.size field has changed
-			return oldObj.GetGeneration() != newObj.GetGeneration()
+			// Trigger reconciliation only if the spec changed, or if the provider reported a new result for
+			// one of its zones so the integration's health reflects it.
+			return oldObj.GetGeneration() != newObj.GetGeneration() || zonesChanged(oldObj, newObj)
 		},
 		CreateFunc: func(e event.CreateEvent) bool {
 			return true
@@ -135,5 +205,57 @@ func (r *DNSIntegrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&phonebook.DNSIntegration{}, p).
 		Owns(&apps.Deployment{}).
+		Watches(&core.Secret{}, handler.EnqueueRequestsFromMapFunc(r.integrationsForSecret)).
 		Complete(r)
 }
+
+// zonesChanged returns true if one of the zone conditions, set by the provider, is different between the two integrations.
+func zonesChanged(oldObj, newObj *phonebook.DNSIntegration) bool {
+	zones := func(integration *phonebook.DNSIntegration) map[konditions.ConditionType]konditions.Condition {
+		conditions := map[konditions.ConditionType]konditions.Condition{}
+		for _, c := range integration.Status.Conditions {
+			if strings.HasPrefix(string(c.Type), integrations.ZoneConditionPrefix) {
+				conditions[c.Type] = c
+			}
+		}
+		return conditions
+	}
+
+	before, after := zones(oldObj), zones(newObj)
+	if len(before) != len(after) {
+		return true
+	}
+
+	for t, c := range after {
+		if previous, ok := before[t]; !ok || previous.Status != c.Status || previous.Reason != c.Reason {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Map a secret to the integrations that reference it so the provider's deployment is
+// rolled when the secret's values change.
+func (r *DNSIntegrationReconciler) integrationsForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	if obj.GetNamespace() != env.GetString("PB_NAMESPACE", "phonebook-system") {
+		return nil
+	}
+
+	var list phonebook.DNSIntegrationList
+	if err := r.List(ctx, &list); err != nil {
+		log.FromContext(ctx).Error(err, "PB#0002: Couldn't list the integrations", "Secret", obj.GetName())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, integration := range list.Items {
+		if ref := integration.Spec.SecretRef; ref != nil && ref.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: integration.Name},
+			})
+		}
+	}
+
+	return requests
+}
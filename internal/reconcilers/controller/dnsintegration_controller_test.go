@@ -19,6 +19,8 @@ package controller
 import (
 	"context"
 
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -83,4 +85,27 @@ var _ = Describe("DNSIntegration Controller", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 	})
+
+	Context("zonesChanged", func() {
+		integration := func(conditions ...konditions.Condition) *sequenceriov1alpha1.DNSIntegration {
+			i := &sequenceriov1alpha1.DNSIntegration{}
+			for _, c := range conditions {
+				i.Status.Conditions.SetCondition(c)
+			}
+			return i
+		}
+
+		passed := konditions.Condition{Type: "zone.mydomain.com", Status: konditions.ConditionCompleted, Reason: "Zone passed the provider's check"}
+		failed := konditions.Condition{Type: "zone.mydomain.com", Status: konditions.ConditionError, Reason: "zone not found"}
+		health := konditions.Condition{Type: "Health", Status: konditions.ConditionCompleted, Reason: "Healthy"}
+
+		It("returns true when the provider reports a zone", func() {
+			Expect(zonesChanged(integration(), integration(passed))).To(BeTrue())
+			Expect(zonesChanged(integration(passed), integration(failed))).To(BeTrue())
+		})
+
+		It("returns false when other conditions change", func() {
+			Expect(zonesChanged(integration(passed), integration(passed, health))).To(BeFalse())
+		})
+	})
 })
@@ -19,7 +19,6 @@ package controller
 import (
 	"context"
 	"fmt"
-	"slices"
 	"strings"
 
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
@@ -88,31 +87,41 @@ func (r *DNSRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 				return c, err
 			}
 
-			for _, integration := range integrations.Items {
-				if record.Spec.Integration != nil {
-					if *record.Spec.Integration != integration.Name {
-						continue
-					}
+			zone := record.Spec.Zone
+			if zone == "" {
+				// The record only specifies a fully qualified name, the zone is the longest one
+				// that the name ends with.
+				z, name, ok := splitHostname(integrations.Items, record.Spec.Name, record.Spec.Integration)
+				if !ok {
+					c.Status = konditions.ConditionError
+					c.Reason = fmt.Sprintf("No Integration has a zone that matches this record: %s", record.Spec.Name)
+					return c, nil
 				}
 
-				if slices.Contains(integration.Spec.Zones, record.Spec.Zone) {
+				zone = z
+				record.Status.Zone = z
+				record.Status.Name = name
+			}
+
+			for _, integration := range integrations.Items {
+				if hasAuthority(integration, zone, record.Spec.Integration) {
 					found += 1
 					record.Status.Conditions.SetCondition(konditions.Condition{
 						Type:   konditions.ConditionType(fmt.Sprintf("provider.%s", integration.Name)),
 						Status: konditions.ConditionInitialized,
-						Reason: fmt.Sprintf("Integration has authority over %s", record.Spec.Zone),
+						Reason: fmt.Sprintf("Integration has authority over %s", zone),
 					})
 				}
 			}
 
 			if found == 0 {
 				c.Status = konditions.ConditionError
-				c.Reason = fmt.Sprintf("No Integration matches the zone for this record: %s", record.Spec.Zone)
+				c.Reason = fmt.Sprintf("No Integration matches the zone for this record: %s", zone)
 				return c, nil
 			}
 
 			c.Status = konditions.ConditionCompleted
-			c.Reason = fmt.Sprintf("Found %d integration that has authority over %s", found, record.Spec.Zone)
+			c.Reason = fmt.Sprintf("Found %d integration that has authority over %s", found, zone)
 			return c, nil
 		})
 	}
@@ -98,6 +98,83 @@ var _ = Describe("DNSRecord Controller", func() {
 		})
 	})
 
+	Context("When reconciling a record with a fully qualified name", func() {
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "fqdn-record",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			integrations := []*phonebook.DNSIntegration{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "fqdn-root"},
+					Spec: phonebook.DNSIntegrationSpec{
+						Provider: phonebook.DNSProviderSpec{Name: "aws"},
+						Zones:    []string{"mydomain.com"},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "fqdn-dev"},
+					Spec: phonebook.DNSIntegrationSpec{
+						Provider: phonebook.DNSProviderSpec{Name: "aws"},
+						Zones:    []string{"dev.mydomain.com"},
+					},
+				},
+			}
+			for _, integration := range integrations {
+				Expect(k8sClient.Create(ctx, integration)).To(Succeed())
+			}
+
+			Expect(k8sClient.Create(ctx, &phonebook.DNSRecord{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      typeNamespacedName.Name,
+					Namespace: typeNamespacedName.Namespace,
+				},
+				Spec: phonebook.DNSRecordSpec{
+					RecordType: "TXT",
+					Name:       "_acme-challenge.api.dev.mydomain.com.",
+					Targets:    []string{"challenge"},
+				},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			record := &phonebook.DNSRecord{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, record)).To(Succeed())
+			record.Finalizers = nil
+			Expect(k8sClient.Update(ctx, record)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, record)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &phonebook.DNSIntegration{ObjectMeta: metav1.ObjectMeta{Name: "fqdn-root"}})).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &phonebook.DNSIntegration{ObjectMeta: metav1.ObjectMeta{Name: "fqdn-dev"}})).To(Succeed())
+		})
+
+		It("resolves the longest zone and stores it in the status", func() {
+			controllerReconciler := &DNSRecordReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			// The first reconciliation adds the finalizer.
+			for range 2 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			record := &phonebook.DNSRecord{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, record)).To(Succeed())
+			Expect(record.Status.Zone).To(Equal("dev.mydomain.com"))
+			Expect(record.Status.Name).To(Equal("_acme-challenge.api"))
+			Expect(record.Status.Conditions.FindType("provider.fqdn-dev")).NotTo(BeNil())
+			Expect(record.Status.Conditions.FindType("provider.fqdn-root")).To(BeNil())
+
+			resolved := record.Resolved()
+			Expect(resolved.Spec.Zone).To(Equal("dev.mydomain.com"))
+			Expect(resolved.Spec.Name).To(Equal("_acme-challenge.api"))
+		})
+	})
+
 	Context("AllProvidersMatchesOneOf", func() {
 		It("returns false if no conditions are present", func() {
 			r := &DNSRecordReconciler{}
@@ -0,0 +1,413 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+const (
+	// Name used by Phonebook when it reports its own status in a route's parents.
+	GatewayControllerName gatewayv1.GatewayController = "phonebook.se.quencer.io/gateway-source"
+
+	// Condition set on each of the route's parent to reflect if the DNSRecords generated for the route are live.
+	RouteConditionDNSRecordsReady = "DNSRecordsReady"
+)
+
+// RouteReconciler generates DNSRecords for the hostnames of a Gateway API route (HTTPRoute or GRPCRoute).
+// The records point at the addresses of the Gateways that accepted the route. If the route doesn't specify any
+// hostnames, the hostnames of the Gateway's listeners the route is attached to are used instead.
+//
+// The reconciler reports whether the DNSRecords are live through the DNSRecordsReady condition in the route's status.
+type RouteReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	record.EventRecorder
+
+	// Kind of route this reconciler manages, either HTTPRoute or GRPCRoute.
+	Kind string
+}
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways;httproutes;grpcroutes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes/status;grpcroutes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=se.quencer.io,resources=dnsrecords,verbs=get;list;watch;create;update;patch;delete
+func (r *RouteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	route, err := r.newRoute()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.Get(ctx, req.NamespacedName, route); err != nil {
+		// DNSRecords are garbage collected by Kubernetes through their owner reference.
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !route.GetDeletionTimestamp().IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	hostnames, parentRefs, status := routeSpec(route)
+
+	var targets routeTargets
+	var processed []gatewayv1.ParentReference
+	for _, ref := range parentRefs {
+		if !isGateway(ref) || !accepted(status, ref, route.GetNamespace()) {
+			continue
+		}
+
+		var gateway gatewayv1.Gateway
+		if err := r.Get(ctx, parentKey(ref, route.GetNamespace()), &gateway); err != nil {
+			if client.IgnoreNotFound(err) == nil {
+				continue
+			}
+			return ctrl.Result{}, err
+		}
+
+		hosts := routeHostnames(hostnames)
+		if len(hosts) == 0 {
+			hosts = listenerHostnames(&gateway, ref.SectionName)
+		}
+
+		targets.add(hosts, gatewayAddresses(&gateway))
+		processed = append(processed, ref)
+	}
+
+	endpoints := targets.endpoints()
+
+	log.FromContext(ctx).Info("Reconciling", r.Kind, req.NamespacedName, "Endpoints", len(endpoints))
+
+	records, err := syncRecords(ctx, r.Client, r.Scheme, r.EventRecorder, r.Kind, route, endpoints)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, r.updateStatus(ctx, route, status, processed, readiness(records))
+}
+
+// updateStatus sets the DNSRecordsReady condition on each of the Gateway Phonebook generated records for. The entries
+// Phonebook set for other parents, ie. a Gateway that doesn't accept the route anymore, are removed. The route's
+// status is only updated if the condition changed as each update would trigger a new reconciliation.
+func (r *RouteReconciler) updateStatus(ctx context.Context, route client.Object, status *gatewayv1.RouteStatus, parentRefs []gatewayv1.ParentReference, condition metav1.Condition) error {
+	original := status.DeepCopy()
+	condition.ObservedGeneration = route.GetGeneration()
+
+	parents := status.Parents[:0]
+	for _, parent := range status.Parents {
+		if parent.ControllerName == GatewayControllerName && !slices.ContainsFunc(parentRefs, func(ref gatewayv1.ParentReference) bool {
+			return equality.Semantic.DeepEqual(parent.ParentRef, ref)
+		}) {
+			continue
+		}
+		parents = append(parents, parent)
+	}
+	status.Parents = parents
+
+	for _, ref := range parentRefs {
+		index := -1
+		for i, parent := range status.Parents {
+			if parent.ControllerName == GatewayControllerName && equality.Semantic.DeepEqual(parent.ParentRef, ref) {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			status.Parents = append(status.Parents, gatewayv1.RouteParentStatus{
+				ParentRef:      ref,
+				ControllerName: GatewayControllerName,
+			})
+			index = len(status.Parents) - 1
+		}
+
+		meta.SetStatusCondition(&status.Parents[index].Conditions, condition)
+	}
+
+	if equality.Semantic.DeepEqual(original, status) {
+		return nil
+	}
+
+	return r.Status().Update(ctx, route)
+}
+
+// SetupWithManager sets up the controller with the Manager. Routes are reconciled when they change, when
+// one of the DNSRecord they own changes so their status reflects the DNSRecords' state, when a Gateway they reference changes
+// and when a DNSIntegration changes as it could now have authority over one of the hostnames.
+func (r *RouteReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	route, err := r.newRoute()
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(fmt.Sprintf("%s-source", strings.ToLower(r.Kind))).
+		For(route).
+		Owns(&phonebook.DNSRecord{}).
+		Watches(&gatewayv1.Gateway{}, handler.EnqueueRequestsFromMapFunc(r.routesFor)).
+		Watches(&phonebook.DNSIntegration{}, handler.EnqueueRequestsFromMapFunc(r.routesFor)).
+		Complete(r)
+}
+
+// routesFor returns all the routes that reference the Gateway. If the object isn't a Gateway, ie. a DNSIntegration,
+// all the routes are returned.
+func (r *RouteReconciler) routesFor(ctx context.Context, obj client.Object) []reconcile.Request {
+	var routes []client.Object
+	switch r.Kind {
+	case "HTTPRoute":
+		var list gatewayv1.HTTPRouteList
+		if err := r.List(ctx, &list); err != nil {
+			log.FromContext(ctx).Error(err, "Could not list routes", "Kind", r.Kind)
+			return nil
+		}
+		for i := range list.Items {
+			routes = append(routes, &list.Items[i])
+		}
+	case "GRPCRoute":
+		var list gatewayv1.GRPCRouteList
+		if err := r.List(ctx, &list); err != nil {
+			log.FromContext(ctx).Error(err, "Could not list routes", "Kind", r.Kind)
+			return nil
+		}
+		for i := range list.Items {
+			routes = append(routes, &list.Items[i])
+		}
+	}
+
+	gateway, isGatewayObj := obj.(*gatewayv1.Gateway)
+
+	var requests []reconcile.Request
+	for _, route := range routes {
+		_, parentRefs, _ := routeSpec(route)
+		if isGatewayObj && !referencesGateway(parentRefs, route.GetNamespace(), gateway) {
+			continue
+		}
+
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: route.GetName(), Namespace: route.GetNamespace()},
+		})
+	}
+
+	return requests
+}
+
+func (r *RouteReconciler) newRoute() (client.Object, error) {
+	switch r.Kind {
+	case "HTTPRoute":
+		return &gatewayv1.HTTPRoute{}, nil
+	case "GRPCRoute":
+		return &gatewayv1.GRPCRoute{}, nil
+	}
+
+	return nil, fmt.Errorf("PB#0009: Unsupported route kind: %s", r.Kind)
+}
+
+// routeSpec returns the fields shared by all the route kinds.
+func routeSpec(route client.Object) ([]gatewayv1.Hostname, []gatewayv1.ParentReference, *gatewayv1.RouteStatus) {
+	switch route := route.(type) {
+	case *gatewayv1.HTTPRoute:
+		return route.Spec.Hostnames, route.Spec.ParentRefs, &route.Status.RouteStatus
+	case *gatewayv1.GRPCRoute:
+		return route.Spec.Hostnames, route.Spec.ParentRefs, &route.Status.RouteStatus
+	}
+
+	return nil, nil, &gatewayv1.RouteStatus{}
+}
+
+// isGateway returns true if the parent reference points to a Gateway. Gateway is the default
+// kind for a parent reference.
+func isGateway(ref gatewayv1.ParentReference) bool {
+	if ref.Group != nil && *ref.Group != gatewayv1.GroupName {
+		return false
+	}
+
+	return ref.Kind == nil || *ref.Kind == "Gateway"
+}
+
+// parentKey returns the Gateway's key. A parent reference without a namespace refers to
+// a Gateway in the same namespace as the route.
+func parentKey(ref gatewayv1.ParentReference, namespace string) types.NamespacedName {
+	if ref.Namespace != nil {
+		namespace = string(*ref.Namespace)
+	}
+
+	return types.NamespacedName{Name: string(ref.Name), Namespace: namespace}
+}
+
+// accepted returns true if the Gateway's controller accepted the route for the parent reference given. The
+// entry Phonebook sets for itself in the route's status is ignored.
+func accepted(status *gatewayv1.RouteStatus, ref gatewayv1.ParentReference, namespace string) bool {
+	for _, parent := range status.Parents {
+		if parent.ControllerName == GatewayControllerName {
+			continue
+		}
+
+		if parentKey(parent.ParentRef, namespace) != parentKey(ref, namespace) {
+			continue
+		}
+
+		if meta.IsStatusConditionTrue(parent.Conditions, string(gatewayv1.RouteConditionAccepted)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// routeTargets collects the addresses of every Gateway that accepted the route, for each hostname. A hostname
+// served by more than one Gateway gets a single set of records that points at all of them.
+type routeTargets struct {
+	hostnames []string
+	addresses map[string][]string
+}
+
+func (t *routeTargets) add(hostnames []string, addresses []string) {
+	if t.addresses == nil {
+		t.addresses = map[string][]string{}
+	}
+
+	for _, hostname := range hostnames {
+		existing, ok := t.addresses[hostname]
+		if !ok {
+			t.hostnames = append(t.hostnames, hostname)
+		}
+
+		for _, address := range addresses {
+			if !slices.Contains(existing, address) {
+				existing = append(existing, address)
+			}
+		}
+		t.addresses[hostname] = existing
+	}
+}
+
+func (t *routeTargets) endpoints() []endpoint {
+	var endpoints []endpoint
+	for _, hostname := range t.hostnames {
+		endpoints = append(endpoints, endpointsFor([]string{hostname}, t.addresses[hostname])...)
+	}
+
+	return endpoints
+}
+
+func referencesGateway(parentRefs []gatewayv1.ParentReference, namespace string, gateway *gatewayv1.Gateway) bool {
+	for _, ref := range parentRefs {
+		if isGateway(ref) && parentKey(ref, namespace) == client.ObjectKeyFromObject(gateway) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func routeHostnames(hostnames []gatewayv1.Hostname) []string {
+	hosts := make([]string, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		hosts = append(hosts, string(hostname))
+	}
+
+	return hosts
+}
+
+// listenerHostnames returns the hostnames of the Gateway's listeners. If a section name is given, only
+// the listener with that name is used.
+func listenerHostnames(gateway *gatewayv1.Gateway, section *gatewayv1.SectionName) []string {
+	var hosts []string
+	for _, listener := range gateway.Spec.Listeners {
+		if section != nil && listener.Name != *section {
+			continue
+		}
+
+		if listener.Hostname != nil {
+			hosts = append(hosts, string(*listener.Hostname))
+		}
+	}
+
+	return hosts
+}
+
+// gatewayAddresses returns the IPs and hostnames the Gateway reports in its status. Named addresses are
+// specific to each implementation and can't be resolved so they are ignored.
+func gatewayAddresses(gateway *gatewayv1.Gateway) []string {
+	var addresses []string
+	for _, address := range gateway.Status.Addresses {
+		if address.Type != nil && *address.Type != gatewayv1.IPAddressType && *address.Type != gatewayv1.HostnameAddressType {
+			continue
+		}
+
+		addresses = append(addresses, address.Value)
+	}
+
+	return addresses
+}
+
+// readiness returns the condition that reflects the state of the DNSRecords. The DNSRecords are ready when every
+// integration reports the record as created.
+func readiness(records []phonebook.DNSRecord) metav1.Condition {
+	if len(records) == 0 {
+		return metav1.Condition{
+			Type:    RouteConditionDNSRecordsReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NoRecords",
+			Message: "No DNSRecord was generated for this route",
+		}
+	}
+
+	pending := 0
+	for _, record := range records {
+		providers, created := 0, 0
+		for _, c := range record.Status.Conditions {
+			if !strings.HasPrefix(string(c.Type), "provider.") {
+				continue
+			}
+
+			providers += 1
+			switch c.Status {
+			case konditions.ConditionError:
+				return metav1.Condition{
+					Type:    RouteConditionDNSRecordsReady,
+					Status:  metav1.ConditionFalse,
+					Reason:  "Error",
+					Message: fmt.Sprintf("DNSRecord %s: %s", record.Name, c.Reason),
+				}
+			case konditions.ConditionCreated:
+				created += 1
+			}
+		}
+
+		if providers == 0 || created != providers {
+			pending += 1
+		}
+	}
+
+	if pending != 0 {
+		return metav1.Condition{
+			Type:    RouteConditionDNSRecordsReady,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "Pending",
+			Message: fmt.Sprintf("Waiting on %d DNSRecord(s) to be created", pending),
+		}
+	}
+
+	return metav1.Condition{
+		Type:    RouteConditionDNSRecordsReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Created",
+		Message: fmt.Sprintf("%d DNSRecord(s) are live", len(records)),
+	}
+}
@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+var _ = Describe("Gateway Controller", func() {
+	Context("listenerHostnames", func() {
+		hostname := gatewayv1.Hostname("www.mydomain.com")
+		wildcard := gatewayv1.Hostname("*.mydomain.com")
+		gateway := &gatewayv1.Gateway{
+			Spec: gatewayv1.GatewaySpec{
+				Listeners: []gatewayv1.Listener{
+					{Name: "www", Hostname: &hostname},
+					{Name: "all", Hostname: &wildcard},
+					{Name: "none"},
+				},
+			},
+		}
+
+		It("returns the hostnames of all the listeners", func() {
+			Expect(listenerHostnames(gateway, nil)).To(Equal([]string{"www.mydomain.com", "*.mydomain.com"}))
+		})
+
+		It("only returns the listener matching the section name", func() {
+			section := gatewayv1.SectionName("all")
+			Expect(listenerHostnames(gateway, &section)).To(Equal([]string{"*.mydomain.com"}))
+		})
+	})
+
+	Context("gatewayAddresses", func() {
+		It("ignores named addresses", func() {
+			ip := gatewayv1.IPAddressType
+			named := gatewayv1.NamedAddressType
+			gateway := &gatewayv1.Gateway{
+				Status: gatewayv1.GatewayStatus{
+					Addresses: []gatewayv1.GatewayStatusAddress{
+						{Type: &ip, Value: "10.0.0.1"},
+						{Type: &named, Value: "internal-pool"},
+						{Value: "10.0.0.2"},
+					},
+				},
+			}
+
+			Expect(gatewayAddresses(gateway)).To(Equal([]string{"10.0.0.1", "10.0.0.2"}))
+		})
+	})
+
+	Context("accepted", func() {
+		ref := gatewayv1.ParentReference{Name: "public"}
+
+		It("is true when the gateway's controller accepted the route", func() {
+			status := &gatewayv1.RouteStatus{
+				Parents: []gatewayv1.RouteParentStatus{{
+					ParentRef:      ref,
+					ControllerName: "example.com/gateway",
+					Conditions: []metav1.Condition{
+						{Type: string(gatewayv1.RouteConditionAccepted), Status: metav1.ConditionTrue},
+					},
+				}},
+			}
+
+			Expect(accepted(status, ref, "default")).To(BeTrue())
+		})
+
+		It("ignores the status set by Phonebook", func() {
+			status := &gatewayv1.RouteStatus{
+				Parents: []gatewayv1.RouteParentStatus{{
+					ParentRef:      ref,
+					ControllerName: GatewayControllerName,
+					Conditions: []metav1.Condition{
+						{Type: string(gatewayv1.RouteConditionAccepted), Status: metav1.ConditionTrue},
+					},
+				}},
+			}
+
+			Expect(accepted(status, ref, "default")).To(BeFalse())
+		})
+	})
+
+	Context("readiness", func() {
+		record := func(statuses ...konditions.ConditionStatus) phonebook.DNSRecord {
+			var r phonebook.DNSRecord
+			for i, status := range statuses {
+				r.Status.Conditions = append(r.Status.Conditions, konditions.Condition{
+					Type:   konditions.ConditionType("provider." + string(rune('a'+i))),
+					Status: status,
+				})
+			}
+			return r
+		}
+
+		It("is true when every provider created the records", func() {
+			condition := readiness([]phonebook.DNSRecord{record(konditions.ConditionCreated, konditions.ConditionCreated)})
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		})
+
+		It("is pending while a provider hasn't created the record", func() {
+			condition := readiness([]phonebook.DNSRecord{record(konditions.ConditionCreated, konditions.ConditionLocked)})
+			Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+			Expect(condition.Reason).To(Equal("Pending"))
+		})
+
+		It("is false when a provider failed", func() {
+			condition := readiness([]phonebook.DNSRecord{record(konditions.ConditionCreated), record(konditions.ConditionError)})
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("Error"))
+		})
+
+		It("is false when there are no records", func() {
+			Expect(readiness(nil).Reason).To(Equal("NoRecords"))
+		})
+	})
+
+	Context("When a route is attached to two Gateways", func() {
+		ctx := context.Background()
+
+		gateway := func(name string, addresses ...string) *gatewayv1.Gateway {
+			gw := &gatewayv1.Gateway{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+			for _, address := range addresses {
+				gw.Status.Addresses = append(gw.Status.Addresses, gatewayv1.GatewayStatusAddress{Value: address})
+			}
+			return gw
+		}
+
+		parent := func(name string) gatewayv1.RouteParentStatus {
+			return gatewayv1.RouteParentStatus{
+				ParentRef:      gatewayv1.ParentReference{Name: gatewayv1.ObjectName(name)},
+				ControllerName: "example.com/gateway",
+				Conditions: []metav1.Condition{{
+					Type:               string(gatewayv1.RouteConditionAccepted),
+					Status:             metav1.ConditionTrue,
+					Reason:             "Accepted",
+					LastTransitionTime: metav1.Now(),
+				}},
+			}
+		}
+
+		var reconciler *RouteReconciler
+		var route *gatewayv1.HTTPRoute
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+			Expect(phonebook.AddToScheme(scheme)).To(Succeed())
+			Expect(gatewayv1.Install(scheme)).To(Succeed())
+
+			route = &gatewayv1.HTTPRoute{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: gatewayv1.HTTPRouteSpec{
+					CommonRouteSpec: gatewayv1.CommonRouteSpec{
+						ParentRefs: []gatewayv1.ParentReference{{Name: "east"}, {Name: "west"}, {Name: "other"}},
+					},
+					Hostnames: []gatewayv1.Hostname{"www.mydomain.com"},
+				},
+				Status: gatewayv1.HTTPRouteStatus{
+					RouteStatus: gatewayv1.RouteStatus{
+						Parents: []gatewayv1.RouteParentStatus{parent("east"), parent("west")},
+					},
+				},
+			}
+
+			integration := &phonebook.DNSIntegration{
+				ObjectMeta: metav1.ObjectMeta{Name: "gateways"},
+				Spec: phonebook.DNSIntegrationSpec{
+					Provider: phonebook.DNSProviderSpec{Name: "aws"},
+					Zones:    []string{"mydomain.com"},
+				},
+			}
+
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithStatusSubresource(&gatewayv1.HTTPRoute{}).
+				WithObjects(route, integration, gateway("east", "10.0.0.1", "10.0.0.2"), gateway("west", "10.0.0.2", "10.0.0.3"), gateway("other", "10.0.0.4")).
+				Build()
+
+			reconciler = &RouteReconciler{
+				Client:        c,
+				Scheme:        scheme,
+				EventRecorder: record.NewFakeRecorder(10),
+				Kind:          "HTTPRoute",
+			}
+		})
+
+		It("creates a single record that points at both Gateways", func() {
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "web", Namespace: "default"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			var records phonebook.DNSRecordList
+			Expect(reconciler.List(ctx, &records, client.InNamespace("default"))).To(Succeed())
+			Expect(records.Items).To(HaveLen(1))
+			Expect(records.Items[0].Spec.RecordType).To(Equal("A"))
+			Expect(records.Items[0].Spec.Targets).To(Equal([]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}))
+		})
+
+		It("only reports its status for the Gateways that accepted the route", func() {
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "web", Namespace: "default"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(route), route)).To(Succeed())
+
+			var reported []gatewayv1.ObjectName
+			for _, p := range route.Status.Parents {
+				if p.ControllerName != GatewayControllerName {
+					continue
+				}
+				reported = append(reported, p.ParentRef.Name)
+				Expect(meta.FindStatusCondition(p.Conditions, RouteConditionDNSRecordsReady)).NotTo(BeNil())
+			}
+			Expect(reported).To(ConsistOf(gatewayv1.ObjectName("east"), gatewayv1.ObjectName("west")))
+		})
+	})
+})
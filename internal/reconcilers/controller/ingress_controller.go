@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"context"
+	"slices"
+
+	networking "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+// Legacy annotation used to specify the class of an Ingress before `spec.ingressClassName` existed.
+const kLegacyIngressClassAnnotation = "kubernetes.io/ingress.class"
+
+// IngressReconciler generates DNSRecords for the hosts defined in an Ingress' rules. The records point at the
+// addresses the Ingress reports in its status.
+//
+// Ingresses are opt-in so they can be migrated from other tools one at a time. An Ingress is
+// managed by Phonebook if it has the EnabledAnnotation set to "true", or if its IngressClass has it. Setting the annotation
+// to "false" on an Ingress opts it out even when its IngressClass is enabled.
+type IngressReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses;ingressclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=se.quencer.io,resources=dnsrecords,verbs=get;list;watch;create;update;patch;delete
+func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var ingress networking.Ingress
+	if err := r.Get(ctx, req.NamespacedName, &ingress); err != nil {
+		// DNSRecords are garbage collected by Kubernetes through their owner reference.
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !ingress.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	enabled, err := r.enabled(ctx, &ingress)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var endpoints []endpoint
+	if enabled {
+		endpoints = endpointsFor(hostsFor(&ingress), addressesFor(ingress.Status.LoadBalancer.Ingress))
+	}
+
+	log.FromContext(ctx).Info("Reconciling", "Ingress", req.NamespacedName, "Enabled", enabled, "Endpoints", len(endpoints))
+
+	_, err = syncRecords(ctx, r.Client, r.Scheme, r.EventRecorder, "Ingress", &ingress, endpoints)
+	return ctrl.Result{}, err
+}
+
+// enabled returns true if the Ingress, or its IngressClass, opted in.
+func (r *IngressReconciler) enabled(ctx context.Context, ingress *networking.Ingress) (bool, error) {
+	if value, ok := ingress.Annotations[EnabledAnnotation]; ok {
+		return value == "true", nil
+	}
+
+	className := ingress.Annotations[kLegacyIngressClassAnnotation]
+	if ingress.Spec.IngressClassName != nil {
+		className = *ingress.Spec.IngressClassName
+	}
+
+	if className == "" {
+		return false, nil
+	}
+
+	var class networking.IngressClass
+	if err := r.Get(ctx, types.NamespacedName{Name: className}, &class); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return class.Annotations[EnabledAnnotation] == "true", nil
+}
+
+// hostsFor returns the hosts from the Ingress' rules as well as the ones from the hostname
+// annotation, if any.
+func hostsFor(ingress *networking.Ingress) []string {
+	hosts := hostnamesFrom(ingress)
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != "" && !slices.Contains(hosts, rule.Host) {
+			hosts = append(hosts, rule.Host)
+		}
+	}
+
+	return hosts
+}
+
+// SetupWithManager sets up the controller with the Manager. Ingresses are reconciled when they change, when
+// one of the DNSRecord they own changes, when an IngressClass changes as it could opt in (or out) its ingresses, and
+// when a DNSIntegration changes as it could now have authority over one of the hosts.
+func (r *IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("ingress-source").
+		For(&networking.Ingress{}).
+		Owns(&phonebook.DNSRecord{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&networking.IngressClass{}, handler.EnqueueRequestsFromMapFunc(r.allIngresses)).
+		Watches(&phonebook.DNSIntegration{}, handler.EnqueueRequestsFromMapFunc(r.allIngresses)).
+		Complete(r)
+}
+
+func (r *IngressReconciler) allIngresses(ctx context.Context, _ client.Object) []reconcile.Request {
+	var ingresses networking.IngressList
+	if err := r.List(ctx, &ingresses); err != nil {
+		log.FromContext(ctx).Error(err, "Could not list ingresses")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, len(ingresses.Items))
+	for i, ingress := range ingresses.Items {
+		requests[i] = reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: ingress.Name, Namespace: ingress.Namespace},
+		}
+	}
+
+	return requests
+}
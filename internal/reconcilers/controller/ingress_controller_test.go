@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+var _ = Describe("Ingress Controller", func() {
+	Context("When reconciling an Ingress", func() {
+		ctx := context.Background()
+		className := "phonebook-test"
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "web",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			By("creating an integration for the zone")
+			integration := &phonebook.DNSIntegration{
+				ObjectMeta: metav1.ObjectMeta{Name: "ingress-source"},
+				Spec: phonebook.DNSIntegrationSpec{
+					Provider: phonebook.DNSProviderSpec{Name: "aws"},
+					Zones:    []string{"mydomain.com"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, integration)).To(Succeed())
+
+			By("creating an IngressClass that opted in")
+			class := &networking.IngressClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        className,
+					Annotations: map[string]string{EnabledAnnotation: "true"},
+				},
+				Spec: networking.IngressClassSpec{Controller: "example.com/ingress"},
+			}
+			Expect(k8sClient.Create(ctx, class)).To(Succeed())
+
+			By("creating the ingress with a load balancer address")
+			ingress := &networking.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      typeNamespacedName.Name,
+					Namespace: typeNamespacedName.Namespace,
+				},
+				Spec: networking.IngressSpec{
+					IngressClassName: &className,
+					Rules: []networking.IngressRule{
+						{Host: "www.mydomain.com"},
+						{Host: "api.mydomain.com"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+
+			ingress.Status.LoadBalancer.Ingress = []networking.IngressLoadBalancerIngress{{Hostname: "lb.example.com"}}
+			Expect(k8sClient.Status().Update(ctx, ingress)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.DeleteAllOf(ctx, &phonebook.DNSRecord{}, client.InNamespace("default"))).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &networking.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}})).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &networking.IngressClass{ObjectMeta: metav1.ObjectMeta{Name: className}})).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &phonebook.DNSIntegration{ObjectMeta: metav1.ObjectMeta{Name: "ingress-source"}})).To(Succeed())
+		})
+
+		It("creates a DNSRecord for each host when the class opted in", func() {
+			controllerReconciler := &IngressReconciler{
+				Client:        k8sClient,
+				Scheme:        k8sClient.Scheme(),
+				EventRecorder: record.NewFakeRecorder(10),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var records phonebook.DNSRecordList
+			Expect(k8sClient.List(ctx, &records, client.InNamespace("default"), client.MatchingLabels{
+				SourceKindLabel: "Ingress",
+				SourceNameLabel: "web",
+			})).To(Succeed())
+
+			Expect(records.Items).To(HaveLen(2))
+			for _, record := range records.Items {
+				Expect(record.Spec.RecordType).To(Equal("CNAME"))
+				Expect(record.Spec.Targets).To(Equal([]string{"lb.example.com"}))
+			}
+		})
+
+		It("deletes the DNSRecords when the ingress opts out", func() {
+			controllerReconciler := &IngressReconciler{
+				Client:        k8sClient,
+				Scheme:        k8sClient.Scheme(),
+				EventRecorder: record.NewFakeRecorder(10),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			var ingress networking.Ingress
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &ingress)).To(Succeed())
+			ingress.Annotations = map[string]string{EnabledAnnotation: "false"}
+			Expect(k8sClient.Update(ctx, &ingress)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			var records phonebook.DNSRecordList
+			Expect(k8sClient.List(ctx, &records, client.InNamespace("default"), client.MatchingLabels{
+				SourceKindLabel: "Ingress",
+				SourceNameLabel: "web",
+			})).To(Succeed())
+			Expect(records.Items).To(BeEmpty())
+		})
+	})
+
+	Context("hostsFor", func() {
+		It("merges the rules' hosts with the annotation", func() {
+			ingress := &networking.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{HostnameAnnotation: "extra.mydomain.com, www.mydomain.com"},
+				},
+				Spec: networking.IngressSpec{
+					Rules: []networking.IngressRule{
+						{Host: "www.mydomain.com"},
+						{Host: ""},
+						{Host: "api.mydomain.com"},
+					},
+				},
+			}
+
+			Expect(hostsFor(ingress)).To(Equal([]string{"extra.mydomain.com", "www.mydomain.com", "api.mydomain.com"}))
+		})
+	})
+})
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+// ServiceReconciler generates DNSRecords for Services of type LoadBalancer that have the
+// hostname annotation. The records point at the addresses the load balancer reports in its status.
+type ServiceReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+// +kubebuilder:rbac:groups=se.quencer.io,resources=dnsrecords,verbs=get;list;watch;create;update;patch;delete
+func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var service core.Service
+	if err := r.Get(ctx, req.NamespacedName, &service); err != nil {
+		// DNSRecords are garbage collected by Kubernetes through their owner reference.
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !service.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	var endpoints []endpoint
+	if service.Spec.Type == core.ServiceTypeLoadBalancer {
+		endpoints = endpointsFor(hostnamesFrom(&service), addressesFor(service.Status.LoadBalancer.Ingress))
+	}
+
+	log.FromContext(ctx).Info("Reconciling", "Service", req.NamespacedName, "Endpoints", len(endpoints))
+
+	_, err := syncRecords(ctx, r.Client, r.Scheme, r.EventRecorder, "Service", &service, endpoints)
+	return ctrl.Result{}, err
+}
+
+// SetupWithManager sets up the controller with the Manager. Services are reconciled when they change, when
+// one of the DNSRecord they own changes and when a DNSIntegration changes as it could now have authority over one
+// of the hostnames.
+func (r *ServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("service-source").
+		For(&core.Service{}).
+		Owns(&phonebook.DNSRecord{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&phonebook.DNSIntegration{}, handler.EnqueueRequestsFromMapFunc(r.annotatedServices)).
+		Complete(r)
+}
+
+func (r *ServiceReconciler) annotatedServices(ctx context.Context, _ client.Object) []reconcile.Request {
+	var services core.ServiceList
+	if err := r.List(ctx, &services); err != nil {
+		log.FromContext(ctx).Error(err, "Could not list services")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, service := range services.Items {
+		if _, ok := service.Annotations[HostnameAnnotation]; ok {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: service.Name, Namespace: service.Namespace},
+			})
+		}
+	}
+
+	return requests
+}
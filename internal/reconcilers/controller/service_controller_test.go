@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+var _ = Describe("Service Controller", func() {
+	Context("When reconciling an annotated LoadBalancer", func() {
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "web",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			By("creating an integration for the zone")
+			integration := &phonebook.DNSIntegration{
+				ObjectMeta: metav1.ObjectMeta{Name: "service-source"},
+				Spec: phonebook.DNSIntegrationSpec{
+					Provider: phonebook.DNSProviderSpec{Name: "aws"},
+					Zones:    []string{"mydomain.com"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, integration)).To(Succeed())
+
+			By("creating the service with a load balancer address")
+			service := &core.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      typeNamespacedName.Name,
+					Namespace: typeNamespacedName.Namespace,
+					Annotations: map[string]string{
+						HostnameAnnotation: "www.mydomain.com",
+					},
+				},
+				Spec: core.ServiceSpec{
+					Type:  core.ServiceTypeLoadBalancer,
+					Ports: []core.ServicePort{{Port: 80, TargetPort: intstr.FromInt(80)}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, service)).To(Succeed())
+
+			service.Status.LoadBalancer.Ingress = []core.LoadBalancerIngress{{IP: "10.0.0.1"}}
+			Expect(k8sClient.Status().Update(ctx, service)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.DeleteAllOf(ctx, &phonebook.DNSRecord{}, client.InNamespace("default"))).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &core.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}})).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &phonebook.DNSIntegration{ObjectMeta: metav1.ObjectMeta{Name: "service-source"}})).To(Succeed())
+		})
+
+		It("creates a DNSRecord owned by the service", func() {
+			controllerReconciler := &ServiceReconciler{
+				Client:        k8sClient,
+				Scheme:        k8sClient.Scheme(),
+				EventRecorder: record.NewFakeRecorder(10),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var records phonebook.DNSRecordList
+			Expect(k8sClient.List(ctx, &records, client.InNamespace("default"), client.MatchingLabels{
+				SourceKindLabel: "Service",
+				SourceNameLabel: "web",
+			})).To(Succeed())
+
+			Expect(records.Items).To(HaveLen(1))
+			Expect(records.Items[0].Spec.Zone).To(Equal("mydomain.com"))
+			Expect(records.Items[0].Spec.Name).To(Equal("www"))
+			Expect(records.Items[0].Spec.RecordType).To(Equal("A"))
+			Expect(records.Items[0].Spec.Targets).To(Equal([]string{"10.0.0.1"}))
+			Expect(records.Items[0].OwnerReferences).To(HaveLen(1))
+		})
+	})
+
+	Context("endpointsFor", func() {
+		It("creates A and AAAA records for IP addresses", func() {
+			endpoints := endpointsFor([]string{"www.mydomain.com"}, []string{"10.0.0.1", "2001:db8::1", "lb.aws.com"})
+			Expect(endpoints).To(Equal([]endpoint{
+				{hostname: "www.mydomain.com", recordType: "A", targets: []string{"10.0.0.1"}},
+				{hostname: "www.mydomain.com", recordType: "AAAA", targets: []string{"2001:db8::1"}},
+			}))
+		})
+
+		It("creates a CNAME when there are only hostnames", func() {
+			endpoints := endpointsFor([]string{"www.mydomain.com"}, []string{"lb.aws.com"})
+			Expect(endpoints).To(Equal([]endpoint{
+				{hostname: "www.mydomain.com", recordType: "CNAME", targets: []string{"lb.aws.com"}},
+			}))
+		})
+	})
+
+	Context("splitHostname", func() {
+		integrations := []phonebook.DNSIntegration{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "root"},
+				Spec:       phonebook.DNSIntegrationSpec{Zones: []string{"mydomain.com"}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "dev"},
+				Spec:       phonebook.DNSIntegrationSpec{Zones: []string{"dev.mydomain.com"}},
+			},
+		}
+
+		It("uses the longest zone", func() {
+			zone, name, found := splitHostname(integrations, "api.dev.mydomain.com", nil)
+			Expect(found).To(BeTrue())
+			Expect(zone).To(Equal("dev.mydomain.com"))
+			Expect(name).To(Equal("api"))
+		})
+
+		It("only uses the integration specified", func() {
+			integration := "root"
+			zone, name, found := splitHostname(integrations, "api.dev.mydomain.com", &integration)
+			Expect(found).To(BeTrue())
+			Expect(zone).To(Equal("mydomain.com"))
+			Expect(name).To(Equal("api.dev"))
+		})
+
+		It("doesn't match the apex or other zones", func() {
+			_, _, found := splitHostname(integrations, "mydomain.com", nil)
+			Expect(found).To(BeFalse())
+
+			_, _, found = splitHostname(integrations, "www.otherdomain.com", nil)
+			Expect(found).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+const (
+	// Comma separated list of hostnames that Phonebook should create DNSRecords for.
+	HostnameAnnotation = "phonebook.se.quencer.io/hostname"
+
+	// Optional TTL, in seconds, for the DNSRecords generated.
+	TTLAnnotation = "phonebook.se.quencer.io/ttl"
+
+	// Optional name of the DNSIntegration to use for the DNSRecords generated.
+	IntegrationAnnotation = "phonebook.se.quencer.io/integration"
+
+	// Opt-in annotation for sources that aren't managed by default, ie. Ingress.
+	EnabledAnnotation = "phonebook.se.quencer.io/enabled"
+
+	// Labels set on DNSRecords generated from a source so they can be listed and garbage collected
+	// when the source doesn't need them anymore.
+	SourceKindLabel = "phonebook.se.quencer.io/source-kind"
+	SourceNameLabel = "phonebook.se.quencer.io/source-name"
+)
+
+// endpoint is a DNS record that a source (ie. Service) wants to exist.
+type endpoint struct {
+	hostname   string
+	recordType string
+	targets    []string
+}
+
+// endpointsFor returns the endpoints for the hostnames given. IP addresses generate A and AAAA
+// records while hostnames generate a CNAME. A CNAME can't coexist with other records of the same name, so
+// hostnames are only used when there are no IP addresses.
+func endpointsFor(hostnames []string, addresses []string) []endpoint {
+	var ipv4, ipv6, names []string
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		switch {
+		case ip == nil:
+			names = append(names, address)
+		case ip.To4() != nil:
+			ipv4 = append(ipv4, address)
+		default:
+			ipv6 = append(ipv6, address)
+		}
+	}
+
+	var endpoints []endpoint
+	for _, hostname := range hostnames {
+		if len(ipv4) != 0 {
+			endpoints = append(endpoints, endpoint{hostname: hostname, recordType: "A", targets: ipv4})
+		}
+
+		if len(ipv6) != 0 {
+			endpoints = append(endpoints, endpoint{hostname: hostname, recordType: "AAAA", targets: ipv6})
+		}
+
+		if len(ipv4) == 0 && len(ipv6) == 0 && len(names) != 0 {
+			endpoints = append(endpoints, endpoint{hostname: hostname, recordType: "CNAME", targets: names[:1]})
+		}
+	}
+
+	return endpoints
+}
+
+// addressesFor returns the IPs and hostnames from a load balancer's status. Services and Ingresses
+// report their load balancer with their own type.
+func addressesFor[T core.LoadBalancerIngress | networking.IngressLoadBalancerIngress](ingresses []T) []string {
+	var addresses []string
+	for _, ingress := range ingresses {
+		var ip, hostname string
+		switch lb := any(ingress).(type) {
+		case core.LoadBalancerIngress:
+			ip, hostname = lb.IP, lb.Hostname
+		case networking.IngressLoadBalancerIngress:
+			ip, hostname = lb.IP, lb.Hostname
+		}
+
+		if ip != "" {
+			addresses = append(addresses, ip)
+		}
+
+		if hostname != "" {
+			addresses = append(addresses, hostname)
+		}
+	}
+
+	return addresses
+}
+
+// hostnamesFrom returns the hostnames listed in the hostname annotation of the object.
+func hostnamesFrom(obj client.Object) []string {
+	var hostnames []string
+	for _, hostname := range strings.Split(obj.GetAnnotations()[HostnameAnnotation], ",") {
+		if hostname = strings.TrimSpace(hostname); hostname != "" {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+
+	return hostnames
+}
+
+// syncRecords makes sure the DNSRecords owned by the source matches the endpoints. It is shared by all the controllers
+// that generate DNSRecords from other resources. DNSRecords are created or updated for each endpoint, and the DNSRecords
+// that were previously created for the source but aren't needed anymore are deleted. Each DNSRecord has an owner
+// reference to the source so Kubernetes deletes them when the source is deleted.
+//
+// The DNSRecords that matches the endpoints are returned so the source can report their status.
+func syncRecords(ctx context.Context, c client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, kind string, owner client.Object, endpoints []endpoint) ([]phonebook.DNSRecord, error) {
+	var integrations phonebook.DNSIntegrationList
+	if err := c.List(ctx, &integrations); err != nil {
+		return nil, err
+	}
+
+	var ttl *int64
+	if value, ok := owner.GetAnnotations()[TTLAnnotation]; ok {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("PB#0008: Invalid TTL annotation (%s) -- %w", value, err)
+		}
+		ttl = &parsed
+	}
+
+	var integration *string
+	if value, ok := owner.GetAnnotations()[IntegrationAnnotation]; ok {
+		integration = &value
+	}
+
+	labels := map[string]string{
+		SourceKindLabel: kind,
+		SourceNameLabel: owner.GetName(),
+	}
+
+	var records phonebook.DNSRecordList
+	if err := c.List(ctx, &records, client.InNamespace(owner.GetNamespace()), client.MatchingLabels(labels)); err != nil {
+		return nil, err
+	}
+
+	existing := map[string]bool{}
+	for i := range records.Items {
+		if meta.IsControlledBy(&records.Items[i], owner) {
+			existing[records.Items[i].Name] = true
+		}
+	}
+
+	desired := map[string]bool{}
+	var synced []phonebook.DNSRecord
+	for _, ep := range endpoints {
+		zone, hostname, found := splitHostname(integrations.Items, ep.hostname, integration)
+		if !found {
+			recorder.Event(owner, core.EventTypeWarning, "NoIntegration", fmt.Sprintf("No integration has authority over %s", ep.hostname))
+			continue
+		}
+
+		// Records created before the source's kind was part of the name keep their name, otherwise the
+		// record would be deleted and created again on the provider.
+		name := recordName(kind, owner.GetName(), ep)
+		if legacy := legacyRecordName(owner.GetName(), ep); existing[legacy] {
+			name = legacy
+		}
+
+		record := &phonebook.DNSRecord{
+			ObjectMeta: meta.ObjectMeta{
+				Name:      name,
+				Namespace: owner.GetNamespace(),
+			},
+		}
+		desired[record.Name] = true
+
+		result, err := controllerutil.CreateOrUpdate(ctx, c, record, func() error {
+			if record.Labels == nil {
+				record.Labels = map[string]string{}
+			}
+
+			for k, v := range labels {
+				record.Labels[k] = v
+			}
+
+			if record.CreationTimestamp.IsZero() {
+				// Zone, name and record type are immutable.
+				record.Spec.Zone = zone
+				record.Spec.Name = hostname
+				record.Spec.RecordType = ep.recordType
+			}
+
+			record.Spec.Targets = ep.targets
+			record.Spec.TTL = ttl
+			record.Spec.Integration = integration
+
+			return controllerutil.SetControllerReference(owner, record, scheme)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if result != controllerutil.OperationResultNone {
+			log.FromContext(ctx).Info("DNSRecord synced from source", "Kind", kind, "Source", owner.GetName(), "Record", record.Name, "Operation", result)
+		}
+
+		synced = append(synced, *record)
+	}
+
+	for i := range records.Items {
+		record := &records.Items[i]
+		if desired[record.Name] || !meta.IsControlledBy(record, owner) {
+			continue
+		}
+
+		if err := c.Delete(ctx, record); err != nil && !k8sErrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	return synced, nil
+}
+
+// recordName generates a unique name for the DNSRecord. The hostname is hashed as it
+// could include characters that are not valid for a resource's name, ie. wildcard. The source's
+// kind is part of the hash so sources of different kinds with the same name don't share records.
+func recordName(kind, source string, ep endpoint) string {
+	h := fnv.New32a()
+	h.Write([]byte(kind + "/" + ep.hostname))
+
+	return fmt.Sprintf("%s-%s-%08x", source, strings.ToLower(ep.recordType), h.Sum32())
+}
+
+// Name of the records created before the source's kind was part of the hash.
+func legacyRecordName(source string, ep endpoint) string {
+	h := fnv.New32a()
+	h.Write([]byte(ep.hostname))
+
+	return fmt.Sprintf("%s-%s-%08x", source, strings.ToLower(ep.recordType), h.Sum32())
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+var _ = Describe("Sources", func() {
+	Context("When sources of different kinds share a name and a hostname", func() {
+		ctx := context.Background()
+		ep := endpoint{hostname: "www.mydomain.com", recordType: "A", targets: []string{"10.0.0.1"}}
+
+		BeforeEach(func() {
+			integration := &phonebook.DNSIntegration{
+				ObjectMeta: metav1.ObjectMeta{Name: "sources"},
+				Spec: phonebook.DNSIntegrationSpec{
+					Provider: phonebook.DNSProviderSpec{Name: "aws"},
+					Zones:    []string{"mydomain.com"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, integration)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.DeleteAllOf(ctx, &phonebook.DNSRecord{}, client.InNamespace("default"))).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &core.Service{ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "default"}})).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &networking.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "default"}})).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &phonebook.DNSIntegration{ObjectMeta: metav1.ObjectMeta{Name: "sources"}})).To(Succeed())
+		})
+
+		It("generates different names for each kind", func() {
+			Expect(recordName("Service", "shared", ep)).NotTo(Equal(recordName("Ingress", "shared", ep)))
+			Expect(recordName("HTTPRoute", "shared", ep)).NotTo(Equal(recordName("GRPCRoute", "shared", ep)))
+		})
+
+		It("creates a DNSRecord for each source", func() {
+			service := &core.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "default"},
+				Spec:       core.ServiceSpec{Ports: []core.ServicePort{{Port: 80}}},
+			}
+			Expect(k8sClient.Create(ctx, service)).To(Succeed())
+
+			ingress := &networking.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "default"},
+				Spec:       networking.IngressSpec{Rules: []networking.IngressRule{{Host: "www.mydomain.com"}}},
+			}
+			Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(10)
+			_, err := syncRecords(ctx, k8sClient, k8sClient.Scheme(), recorder, "Service", service, []endpoint{ep})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = syncRecords(ctx, k8sClient, k8sClient.Scheme(), recorder, "Ingress", ingress, []endpoint{ep})
+			Expect(err).NotTo(HaveOccurred())
+
+			var records phonebook.DNSRecordList
+			Expect(k8sClient.List(ctx, &records, client.InNamespace("default"))).To(Succeed())
+			Expect(records.Items).To(HaveLen(2))
+		})
+
+		It("keeps the name of records created before the kind was part of it", func() {
+			service := &core.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "default"},
+				Spec:       core.ServiceSpec{Ports: []core.ServicePort{{Port: 80}}},
+			}
+			Expect(k8sClient.Create(ctx, service)).To(Succeed())
+			Expect(k8sClient.Create(ctx, &networking.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "default"},
+				Spec:       networking.IngressSpec{Rules: []networking.IngressRule{{Host: "www.mydomain.com"}}},
+			})).To(Succeed())
+
+			legacy := &phonebook.DNSRecord{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      legacyRecordName("shared", ep),
+					Namespace: "default",
+					Labels:    map[string]string{SourceKindLabel: "Service", SourceNameLabel: "shared"},
+				},
+				Spec: phonebook.DNSRecordSpec{Zone: "mydomain.com", Name: "www", RecordType: "A", Targets: []string{"10.0.0.1"}},
+			}
+			Expect(controllerutil.SetControllerReference(service, legacy, k8sClient.Scheme())).To(Succeed())
+			Expect(k8sClient.Create(ctx, legacy)).To(Succeed())
+
+			synced, err := syncRecords(ctx, k8sClient, k8sClient.Scheme(), record.NewFakeRecorder(10), "Service", service, []endpoint{ep})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(synced).To(HaveLen(1))
+			Expect(synced[0].Name).To(Equal(legacy.Name))
+		})
+	})
+
+	Context("addressesFor", func() {
+		It("returns the IPs and hostnames of Services and Ingresses load balancers", func() {
+			Expect(addressesFor([]core.LoadBalancerIngress{
+				{IP: "10.0.0.1"},
+				{Hostname: "lb.mydomain.com"},
+			})).To(Equal([]string{"10.0.0.1", "lb.mydomain.com"}))
+
+			Expect(addressesFor([]networking.IngressLoadBalancerIngress{
+				{IP: "10.0.0.2", Hostname: "ingress.mydomain.com"},
+			})).To(Equal([]string{"10.0.0.2", "ingress.mydomain.com"}))
+		})
+	})
+})
@@ -2,7 +2,10 @@ package integrations
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/pier-oliviert/konditionner/pkg/konditions"
@@ -12,7 +15,10 @@ import (
 	"github.com/pier-oliviert/phonebook/pkg/providers"
 	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/env"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -33,34 +39,112 @@ func DeploymentTask(ctx context.Context, c client.Client, integration *phonebook
 	return t.Run
 }
 
+// Apply the desired deployment for the integration. The deployment is server-side applied so
+// changes made to the integration's spec are reflected on the deployment, and the fields
+// that are no longer part of the spec are removed.
 func (t deployment) Run(condition konditions.Condition) (konditions.Condition, error) {
-	d := t.deployment()
-	if err := t.Create(t.ctx, d); err != nil {
+	hash, err := SecretHash(t.ctx, t.Client, t.integration)
+	if err != nil {
 		return condition, err
 	}
 
+	d := t.deployment(hash)
+	if err := t.Patch(t.ctx, d, client.Apply, client.FieldOwner(integrations.FieldManager), client.ForceOwnership); err != nil {
+		return condition, fmt.Errorf("PB#0010: Could not apply the deployment (%s) -- %w", d.Name, err)
+	}
+
 	t.integration.Status.Deployment = references.NewReference(d)
+	t.integration.Status.ObservedGeneration = t.integration.Generation
 	condition.Status = konditions.ConditionCreated
-	condition.Reason = fmt.Sprintf("Deployment Created: %s", d.Name)
+	condition.Reason = fmt.Sprintf("Deployment Applied: %s (Generation: %d)", d.Name, t.integration.Generation)
 
 	return condition, nil
 }
 
-func (t deployment) deployment() *apps.Deployment {
-	img := ""
-	if t.integration.Spec.Provider.Image != nil {
-		img = *t.integration.Spec.Provider.Image
-	} else {
-		img = providers.ProviderImages[t.integration.Spec.Provider.Name]
+// DeploymentOutdated returns true when the deployment needs to be applied again: the integration changed since
+// it was last applied, the secret's values used by the provider changed, or the deployment doesn't exist.
+func DeploymentOutdated(ctx context.Context, c client.Client, integration *phonebook.DNSIntegration) (bool, error) {
+	if integration.Generation != integration.Status.ObservedGeneration {
+		return true, nil
+	}
+
+	hash, err := SecretHash(ctx, c, integration)
+	if err != nil {
+		return false, err
+	}
+
+	var d apps.Deployment
+	err = c.Get(ctx, types.NamespacedName{Namespace: namespace(), Name: deploymentName(integration)}, &d)
+	if k8sErrors.IsNotFound(err) {
+		return true, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("PB#0011: Couldn't retrieve the deployment for %s -- %w", integration.Name, err)
+	}
+
+	return d.Spec.Template.Annotations[integrations.SecretHashAnnotation] != hash, nil
+}
+
+// SecretHash returns a hash of the secret's values used by the provider. The hash is empty when the integration
+// doesn't reference a secret, or when the secret doesn't exist yet. In that case, the pod fails to start until
+// the secret is created and the hash changes.
+func SecretHash(ctx context.Context, c client.Client, integration *phonebook.DNSIntegration) (string, error) {
+	ref := integration.Spec.SecretRef
+	if ref == nil || len(ref.Keys) == 0 {
+		return "", nil
+	}
+
+	var secret core.Secret
+	err := c.Get(ctx, types.NamespacedName{Namespace: namespace(), Name: ref.Name}, &secret)
+	if k8sErrors.IsNotFound(err) {
+		return "", nil
 	}
 
+	if err != nil {
+		return "", fmt.Errorf("PB#0011: Couldn't retrieve the secret (%s) -- %w", ref.Name, err)
+	}
+
+	return hashSecret(ref, &secret), nil
+}
+
+// Only the keys referenced by the integration are hashed so other values stored in the
+// same secret don't restart the provider.
+func hashSecret(ref *references.SecretRef, secret *core.Secret) string {
+	keys := []string{}
+	for _, sk := range ref.Keys {
+		keys = append(keys, sk.Key)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, key := range keys {
+		value := secret.Data[key]
+		fmt.Fprintf(h, "%s=%d:", key, len(value))
+		h.Write(value)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func deploymentName(integration *phonebook.DNSIntegration) string {
+	return fmt.Sprintf("provider-%s", integration.Name)
+}
+
+func namespace() string {
+	return env.GetString("PB_NAMESPACE", "phonebook-system")
+}
+
+// The environment of the provider's container. The secret's keys come after the integration's env so they take
+// precedence over it, and the values set by Phonebook come last.
+func providerEnv(integration *phonebook.DNSIntegration) []core.EnvVar {
 	envs := []core.EnvVar{}
-	if len(t.integration.Spec.Env) != 0 {
-		envs = append(envs, t.integration.Spec.Env...)
+	if len(integration.Spec.Env) != 0 {
+		envs = append(envs, integration.Spec.Env...)
 	}
 
-	if t.integration.Spec.SecretRef != nil {
-		secret := t.integration.Spec.SecretRef
+	if integration.Spec.SecretRef != nil {
+		secret := integration.Spec.SecretRef
 		for _, sk := range secret.Keys {
 			envs = append(envs, core.EnvVar{
 				Name: sk.Name,
@@ -76,19 +160,61 @@ func (t deployment) deployment() *apps.Deployment {
 
 	envs = append(envs,
 		core.EnvVar{
+			Name:  "PB_PROVIDER",
+			Value: integration.Spec.Provider.Name,
+		}, core.EnvVar{
 			Name:  "PB_INTEGRATION",
-			Value: t.integration.Name,
+			Value: integration.Name,
 		}, core.EnvVar{
 			Name:  "PB_ZONES",
-			Value: strings.Join(t.integration.Spec.Zones, ","),
+			Value: strings.Join(integration.Spec.Zones, ","),
 		},
 	)
 
+	if registry := integration.Spec.Registry; registry != nil {
+		envs = append(envs,
+			core.EnvVar{
+				Name:  "PB_REGISTRY_OWNER_ID",
+				Value: registry.OwnerID,
+			}, core.EnvVar{
+				Name:  "PB_REGISTRY_PREFIX",
+				Value: registry.Prefix,
+			},
+		)
+	}
+
+	return envs
+}
+
+func (t deployment) deployment(hash string) *apps.Deployment {
+	img := ""
+	if t.integration.Spec.Provider.Image != nil {
+		img = *t.integration.Spec.Provider.Image
+	} else {
+		img = providers.ProviderImages[t.integration.Spec.Provider.Name]
+	}
+
+	// The provider serves its probes on port 8081. The pod is ready once every zone passed
+	// the provider's check.
 	container := core.Container{
 		Name:            "provider",
-		Env:             envs,
+		Env:             providerEnv(t.integration),
 		Image:           img,
 		ImagePullPolicy: core.PullIfNotPresent,
+		LivenessProbe: &core.Probe{
+			ProbeHandler: core.ProbeHandler{
+				HTTPGet: &core.HTTPGetAction{Path: "/healthz", Port: intstr.FromInt32(8081)},
+			},
+			InitialDelaySeconds: 15,
+			PeriodSeconds:       20,
+		},
+		ReadinessProbe: &core.Probe{
+			ProbeHandler: core.ProbeHandler{
+				HTTPGet: &core.HTTPGetAction{Path: "/readyz", Port: intstr.FromInt32(8081)},
+			},
+			InitialDelaySeconds: 5,
+			PeriodSeconds:       10,
+		},
 	}
 
 	if len(t.integration.Spec.Provider.Command) != 0 {
@@ -102,10 +228,21 @@ func (t deployment) deployment() *apps.Deployment {
 	var replicaCount int32 = 1
 	var controller bool = true
 
+	var annotations map[string]string
+	if hash != "" {
+		annotations = map[string]string{
+			integrations.SecretHashAnnotation: hash,
+		}
+	}
+
 	deployment := &apps.Deployment{
+		TypeMeta: meta.TypeMeta{
+			APIVersion: apps.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+		},
 		ObjectMeta: meta.ObjectMeta{
-			Name:      fmt.Sprintf("provider-%s", t.integration.Name),
-			Namespace: env.GetString("PB_NAMESPACE", "phonebook-system"),
+			Name:      deploymentName(t.integration),
+			Namespace: namespace(),
 			OwnerReferences: []meta.OwnerReference{{
 				APIVersion: t.integration.APIVersion,
 				Kind:       t.integration.Kind,
@@ -129,6 +266,7 @@ func (t deployment) deployment() *apps.Deployment {
 					Labels: map[string]string{
 						integrations.DeploymentLabel: t.integration.Name,
 					},
+					Annotations: annotations,
 				},
 				Spec: core.PodSpec{
 					ServiceAccountName: env.GetString("PB_PROVIDER_SERVICE_ACC", "phonebook-providers"),
@@ -138,5 +276,55 @@ func (t deployment) deployment() *apps.Deployment {
 		},
 	}
 
+	if tpl := t.integration.Spec.Provider.PodTemplate; tpl != nil {
+		applyPodTemplate(deployment, tpl)
+	}
+
 	return deployment
 }
+
+// Merge the integration's pod template into the deployment. Labels and annotations set by Phonebook
+// are kept since the deployment's selector and rollouts depend on them.
+func applyPodTemplate(d *apps.Deployment, tpl *phonebook.ProviderPodTemplate) {
+	pod := &d.Spec.Template
+	container := &pod.Spec.Containers[0]
+
+	if tpl.Replicas != nil {
+		replicas := *tpl.Replicas
+		d.Spec.Replicas = &replicas
+	}
+
+	for key, value := range tpl.Labels {
+		if _, ok := pod.Labels[key]; !ok {
+			pod.Labels[key] = value
+		}
+	}
+
+	for key, value := range tpl.Annotations {
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+
+		if _, ok := pod.Annotations[key]; !ok {
+			pod.Annotations[key] = value
+		}
+	}
+
+	if tpl.Resources != nil {
+		container.Resources = *tpl.Resources
+	}
+
+	if tpl.ServiceAccountName != "" {
+		pod.Spec.ServiceAccountName = tpl.ServiceAccountName
+	}
+
+	pod.Spec.NodeSelector = tpl.NodeSelector
+	pod.Spec.Tolerations = tpl.Tolerations
+	pod.Spec.Affinity = tpl.Affinity
+	pod.Spec.ImagePullSecrets = tpl.ImagePullSecrets
+	pod.Spec.PriorityClassName = tpl.PriorityClassName
+	pod.Spec.SecurityContext = tpl.SecurityContext
+	pod.Spec.Volumes = tpl.Volumes
+	container.SecurityContext = tpl.ContainerSecurityContext
+	container.VolumeMounts = tpl.VolumeMounts
+}
@@ -0,0 +1,108 @@
+package integrations
+
+import (
+	"testing"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/api/v1alpha1/integrations"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestDeploymentPodTemplate(t *testing.T) {
+	replicas := int32(2)
+	nonRoot := true
+
+	integration := &phonebook.DNSIntegration{
+		Spec: phonebook.DNSIntegrationSpec{
+			Provider: phonebook.DNSProviderSpec{
+				Name: "cloudflare",
+				PodTemplate: &phonebook.ProviderPodTemplate{
+					Replicas:    &replicas,
+					Labels:      map[string]string{"team": "dns", integrations.DeploymentLabel: "other"},
+					Annotations: map[string]string{"prometheus.io/scrape": "true", integrations.SecretHashAnnotation: "other"},
+					Resources: &core.ResourceRequirements{
+						Limits: core.ResourceList{core.ResourceMemory: resource.MustParse("64Mi")},
+					},
+					NodeSelector:       map[string]string{"kubernetes.io/os": "linux"},
+					ServiceAccountName: "cloudflare",
+					ImagePullSecrets:   []core.LocalObjectReference{{Name: "registry"}},
+					PriorityClassName:  "system-cluster-critical",
+					SecurityContext:    &core.PodSecurityContext{RunAsNonRoot: &nonRoot},
+					Volumes:            []core.Volume{{Name: "certs"}},
+					VolumeMounts:       []core.VolumeMount{{Name: "certs", MountPath: "/certs"}},
+				},
+			},
+		},
+	}
+	integration.Name = "cloudflare"
+
+	d := deployment{integration: integration}.deployment("hash")
+	pod := d.Spec.Template
+
+	if *d.Spec.Replicas != 2 {
+		t.Errorf("Expected 2 replicas, got: %d", *d.Spec.Replicas)
+	}
+
+	if pod.Labels["team"] != "dns" || pod.Labels[integrations.DeploymentLabel] != "cloudflare" {
+		t.Errorf("Expected the labels to be merged without overriding Phonebook's, got: %v", pod.Labels)
+	}
+
+	if pod.Annotations["prometheus.io/scrape"] != "true" || pod.Annotations[integrations.SecretHashAnnotation] != "hash" {
+		t.Errorf("Expected the annotations to be merged without overriding Phonebook's, got: %v", pod.Annotations)
+	}
+
+	container := pod.Spec.Containers[0]
+	if container.Resources.Limits.Memory().String() != "64Mi" {
+		t.Errorf("Expected the container's resources to be set, got: %v", container.Resources)
+	}
+
+	if len(container.VolumeMounts) != 1 || len(pod.Spec.Volumes) != 1 {
+		t.Errorf("Expected the volumes to be mounted, got: %v", container.VolumeMounts)
+	}
+
+	if pod.Spec.ServiceAccountName != "cloudflare" || pod.Spec.PriorityClassName != "system-cluster-critical" {
+		t.Errorf("Expected the pod's spec to be customized, got: %s, %s", pod.Spec.ServiceAccountName, pod.Spec.PriorityClassName)
+	}
+
+	if pod.Spec.SecurityContext == nil || !*pod.Spec.SecurityContext.RunAsNonRoot {
+		t.Errorf("Expected the pod's security context to be set")
+	}
+}
+
+func TestDeploymentDefaults(t *testing.T) {
+	integration := &phonebook.DNSIntegration{
+		Spec: phonebook.DNSIntegrationSpec{Provider: phonebook.DNSProviderSpec{Name: "cloudflare"}},
+	}
+	integration.Name = "cloudflare"
+
+	d := deployment{integration: integration}.deployment("")
+	if *d.Spec.Replicas != 1 {
+		t.Errorf("Expected 1 replica, got: %d", *d.Spec.Replicas)
+	}
+
+	if d.Spec.Template.Spec.ServiceAccountName != "phonebook-providers" {
+		t.Errorf("Expected the default service account, got: %s", d.Spec.Template.Spec.ServiceAccountName)
+	}
+
+	if len(d.Spec.Template.Annotations) != 0 {
+		t.Errorf("Expected no annotations, got: %v", d.Spec.Template.Annotations)
+	}
+
+	container := d.Spec.Template.Spec.Containers[0]
+	if container.Image != providers.ProvidersImage {
+		t.Errorf("Expected the image with every provider, got: %s", container.Image)
+	}
+
+	found := false
+	for _, e := range container.Env {
+		if e.Name == "PB_PROVIDER" {
+			found = e.Value == "cloudflare"
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected the provider's name to be set in PB_PROVIDER, got: %v", container.Env)
+	}
+}
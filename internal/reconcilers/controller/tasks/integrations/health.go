@@ -3,6 +3,7 @@ package integrations
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/pier-oliviert/konditionner/pkg/konditions"
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
@@ -35,6 +36,11 @@ func (t health) Run(condition konditions.Condition) (konditions.Condition, error
 	var err error
 	var label labels.Selector
 
+	// There's no deployment when the provider runs in the controller.
+	if t.integration.Spec.Mode == phonebook.InProcessMode {
+		goto Zones
+	}
+
 	label, err = labels.Parse(fmt.Sprintf("%s=%s", integrations.DeploymentLabel, t.integration.Name))
 	if err != nil {
 		return condition, fmt.Errorf("PB#0006: Could not parse the label selector: %w", err)
@@ -67,9 +73,33 @@ func (t health) Run(condition konditions.Condition) (konditions.Condition, error
 		}
 	}
 
+Zones:
+	// The provider reports the result of its check for each zone in the integration's status. A zone that
+	// fails its check makes the integration unhealthy even if its deployment is running.
+	if zones := failedZones(t.integration); len(zones) != 0 {
+		err = fmt.Errorf("PB#0013: The provider's check failed for %s. Check the integration's zone conditions for more info.", strings.Join(zones, ", "))
+		goto Done
+	}
+
 	condition.Status = konditions.ConditionCompleted
 	condition.Reason = "Healthy"
 
 Done:
 	return condition, err
 }
+
+// failedZones returns the integration's zones whose condition is in error. Conditions left over from
+// zones that were removed from the integration are ignored.
+func failedZones(integration *phonebook.DNSIntegration) []string {
+	zones := []string{}
+	for _, zone := range integration.Spec.Zones {
+		zone = strings.TrimSuffix(zone, ".")
+
+		conditionType := konditions.ConditionType(integrations.ZoneConditionPrefix + zone)
+		if integration.Status.Conditions.TypeHasStatus(conditionType, konditions.ConditionError) {
+			zones = append(zones, zone)
+		}
+	}
+
+	return zones
+}
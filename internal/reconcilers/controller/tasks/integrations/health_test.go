@@ -0,0 +1,37 @@
+package integrations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+func TestHealthZones(t *testing.T) {
+	integration := &phonebook.DNSIntegration{
+		Spec: phonebook.DNSIntegrationSpec{
+			Mode:  phonebook.InProcessMode,
+			Zones: []string{"mydomain.com", "otherdomain.com"},
+		},
+		Status: phonebook.DNSIntegrationStatus{
+			Conditions: konditions.Conditions{
+				{Type: "zone.mydomain.com", Status: konditions.ConditionCompleted},
+				{Type: "zone.otherdomain.com", Status: konditions.ConditionError},
+			},
+		},
+	}
+
+	condition, err := HealthTask(context.TODO(), nil, integration)(konditions.Condition{})
+	if err == nil || condition.Status == konditions.ConditionCompleted {
+		t.Fatalf("Expected the failing zone to make the integration unhealthy, got: %v", condition)
+	}
+
+	// The failing zone is removed from the integration before the provider removed its condition.
+	integration.Spec.Zones = []string{"mydomain.com"}
+
+	condition, err = HealthTask(context.TODO(), nil, integration)(konditions.Condition{})
+	if err != nil || condition.Status != konditions.ConditionCompleted {
+		t.Errorf("Expected the integration to be healthy once the failing zone is removed, got: %v, %v", condition, err)
+	}
+}
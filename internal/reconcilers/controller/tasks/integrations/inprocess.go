@@ -0,0 +1,157 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Runner runs providers inside the controller, see server.InProcess.
+type Runner interface {
+	Supports(provider string) bool
+	Running(integration, revision string) bool
+	Run(integration, provider string, values map[string]string, revision string) error
+	Stop(integration string)
+}
+
+type inProcess struct {
+	ctx         context.Context
+	integration *phonebook.DNSIntegration
+	runner      Runner
+	client.Client
+}
+
+// InProcessTask runs the integration's provider inside the controller. The runner is nil when the controller
+// wasn't started with in-process providers.
+func InProcessTask(ctx context.Context, c client.Client, runner Runner, integration *phonebook.DNSIntegration) konditions.Task {
+	t := inProcess{
+		ctx:         ctx,
+		integration: integration,
+		runner:      runner,
+		Client:      c,
+	}
+
+	return t.Run
+}
+
+func (t inProcess) Run(condition konditions.Condition) (konditions.Condition, error) {
+	if t.runner == nil {
+		return condition, fmt.Errorf("PB#0014: In-process providers are disabled, the controller needs to be started with --in-process-providers")
+	}
+
+	// The integration is rejected until its mode or its provider changes, trying again wouldn't help.
+	if !t.runner.Supports(t.integration.Spec.Provider.Name) {
+		t.integration.Status.ObservedGeneration = t.integration.Generation
+		condition.Status = konditions.ConditionError
+		condition.Reason = fmt.Sprintf("PB#0014: Provider %s can't run in the controller, the integration needs to use the Deployment mode", t.integration.Spec.Provider.Name)
+		return condition, nil
+	}
+
+	// The integration might have been running in its own deployment before.
+	d := &apps.Deployment{ObjectMeta: meta.ObjectMeta{Name: deploymentName(t.integration), Namespace: namespace()}}
+	if err := t.Delete(t.ctx, d); client.IgnoreNotFound(err) != nil {
+		return condition, fmt.Errorf("PB#0010: Could not delete the deployment (%s) -- %w", d.Name, err)
+	}
+
+	values, err := ProviderValues(t.ctx, t.Client, t.integration)
+	if err != nil {
+		return condition, err
+	}
+
+	revision, err := revision(t.ctx, t.Client, t.integration)
+	if err != nil {
+		return condition, err
+	}
+
+	if err := t.runner.Run(t.integration.Name, t.integration.Spec.Provider.Name, values, revision); err != nil {
+		return condition, err
+	}
+
+	t.integration.Status.Deployment = nil
+	t.integration.Status.ObservedGeneration = t.integration.Generation
+	condition.Status = konditions.ConditionCreated
+	condition.Reason = fmt.Sprintf("Running in the controller (Generation: %d)", t.integration.Generation)
+
+	return condition, nil
+}
+
+// InProcessOutdated returns true when the integration's provider needs to be started again: the integration or the secret's
+// values changed since it was started, or it isn't running in this controller, ie. after a restart or a new leader was elected.
+func InProcessOutdated(ctx context.Context, c client.Client, runner Runner, integration *phonebook.DNSIntegration) (bool, error) {
+	if integration.Generation != integration.Status.ObservedGeneration {
+		return true, nil
+	}
+
+	if runner == nil {
+		return false, nil
+	}
+
+	revision, err := revision(ctx, c, integration)
+	if err != nil {
+		return false, err
+	}
+
+	return !runner.Running(integration.Name, revision), nil
+}
+
+// ProviderValues resolves the configuration of the integration's provider, as it would be set in the
+// environment of the provider's container. Only literal values and values from secrets located
+// in Phonebook's namespace are supported.
+func ProviderValues(ctx context.Context, c client.Client, integration *phonebook.DNSIntegration) (map[string]string, error) {
+	values := map[string]string{}
+	secrets := map[string]*core.Secret{}
+
+	for _, e := range providerEnv(integration) {
+		if e.ValueFrom == nil {
+			values[e.Name] = e.Value
+			continue
+		}
+
+		ref := e.ValueFrom.SecretKeyRef
+		if ref == nil {
+			return nil, fmt.Errorf("PB#0014: %s can only be set with a value or a secret when the provider runs in the controller", e.Name)
+		}
+
+		secret, ok := secrets[ref.Name]
+		if !ok {
+			secret = &core.Secret{}
+			err := c.Get(ctx, types.NamespacedName{Namespace: namespace(), Name: ref.Name}, secret)
+			if k8sErrors.IsNotFound(err) && ref.Optional != nil && *ref.Optional {
+				continue
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("PB#0011: Couldn't retrieve the secret (%s) -- %w", ref.Name, err)
+			}
+
+			secrets[ref.Name] = secret
+		}
+
+		value, ok := secret.Data[ref.Key]
+		if !ok && (ref.Optional == nil || !*ref.Optional) {
+			return nil, fmt.Errorf("PB#0014: Key %s not found in the secret (%s)", ref.Key, ref.Name)
+		}
+
+		values[e.Name] = string(value)
+	}
+
+	return values, nil
+}
+
+// The revision identifies the integration's spec and the secret's values the provider was started with.
+func revision(ctx context.Context, c client.Client, integration *phonebook.DNSIntegration) (string, error) {
+	hash, err := SecretHash(ctx, c, integration)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d-%s", integration.Generation, hash), nil
+}
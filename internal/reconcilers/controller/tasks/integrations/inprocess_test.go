@@ -0,0 +1,140 @@
+package integrations
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/api/v1alpha1/references"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeRunner struct {
+	values  map[string]map[string]string
+	running map[string]string
+}
+
+func (r *fakeRunner) Supports(provider string) bool {
+	return provider != "embedded" && provider != "plugin"
+}
+
+func (r *fakeRunner) Running(integration, revision string) bool {
+	rev, ok := r.running[integration]
+	return ok && rev == revision
+}
+
+func (r *fakeRunner) Run(integration, provider string, values map[string]string, revision string) error {
+	r.values[integration] = values
+	r.running[integration] = revision
+	return nil
+}
+
+func (r *fakeRunner) Stop(integration string) {
+	delete(r.running, integration)
+}
+
+func newInProcessTest(t *testing.T, objs ...client.Object) (client.Client, *phonebook.DNSIntegration) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	integration := &phonebook.DNSIntegration{
+		ObjectMeta: meta.ObjectMeta{Name: "cloudflare", Generation: 2},
+		Spec: phonebook.DNSIntegrationSpec{
+			Mode:     phonebook.InProcessMode,
+			Provider: phonebook.DNSProviderSpec{Name: "cloudflare"},
+			Zones:    []string{"mydomain.com", "otherdomain.com"},
+			Env:      []core.EnvVar{{Name: "PB_RESYNC_INTERVAL", Value: "1m"}},
+			SecretRef: &references.SecretRef{
+				Name: "cloudflare",
+				Keys: []references.SecretKey{{Name: "CF_API_TOKEN", Key: "token"}},
+			},
+		},
+	}
+
+	objs = append(objs, &core.Secret{
+		ObjectMeta: meta.ObjectMeta{Name: "cloudflare", Namespace: namespace()},
+		Data:       map[string][]byte{"token": []byte("secret")},
+	})
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(), integration
+}
+
+func TestInProcessTask(t *testing.T) {
+	d := &apps.Deployment{ObjectMeta: meta.ObjectMeta{Name: "provider-cloudflare", Namespace: namespace()}}
+	c, integration := newInProcessTest(t, d)
+	runner := &fakeRunner{values: map[string]map[string]string{}, running: map[string]string{}}
+
+	condition, err := InProcessTask(context.TODO(), c, runner, integration)(konditions.Condition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if condition.Status != konditions.ConditionCreated {
+		t.Errorf("Expected the condition to be created, got: %s", condition.Status)
+	}
+
+	values := runner.values["cloudflare"]
+	if values["CF_API_TOKEN"] != "secret" || values["PB_RESYNC_INTERVAL"] != "1m" {
+		t.Errorf("Expected the values to be resolved, got: %v", values)
+	}
+
+	if values["PB_INTEGRATION"] != "cloudflare" || values["PB_ZONES"] != "mydomain.com,otherdomain.com" {
+		t.Errorf("Expected Phonebook's values to be set, got: %v", values)
+	}
+
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: d.Name, Namespace: d.Namespace}, d); !k8sErrors.IsNotFound(err) {
+		t.Errorf("Expected the deployment to be deleted, got: %v", err)
+	}
+
+	integration.Status.ObservedGeneration = integration.Generation
+	if outdated, err := InProcessOutdated(context.TODO(), c, runner, integration); err != nil || outdated {
+		t.Errorf("Expected the provider to be up to date, got: %t, %v", outdated, err)
+	}
+
+	runner.Stop("cloudflare")
+	if outdated, err := InProcessOutdated(context.TODO(), c, runner, integration); err != nil || !outdated {
+		t.Errorf("Expected the provider to be outdated once stopped, got: %t, %v", outdated, err)
+	}
+}
+
+func TestInProcessTaskErrors(t *testing.T) {
+	c, integration := newInProcessTest(t)
+	runner := &fakeRunner{values: map[string]map[string]string{}, running: map[string]string{}}
+
+	if _, err := InProcessTask(context.TODO(), c, nil, integration)(konditions.Condition{}); err == nil || !strings.HasPrefix(err.Error(), "PB#0014") {
+		t.Errorf("Expected an error when in-process providers are disabled, got: %v", err)
+	}
+
+	for _, name := range []string{"embedded", "plugin"} {
+		integration.Spec.Provider.Name = name
+		condition, err := InProcessTask(context.TODO(), c, runner, integration)(konditions.Condition{})
+		if err != nil || condition.Status != konditions.ConditionError || !strings.HasPrefix(condition.Reason, "PB#0014") || !strings.Contains(condition.Reason, "Deployment mode") {
+			t.Errorf("Expected %s to be rejected with the condition's reason, got: %v, %v", name, condition, err)
+		}
+
+		if _, ok := runner.running[integration.Name]; ok {
+			t.Errorf("Expected %s to not be started", name)
+		}
+	}
+
+	integration.Spec.Provider.Name = "cloudflare"
+	integration.Spec.Env = []core.EnvVar{{
+		Name:      "CF_ZONE_ID",
+		ValueFrom: &core.EnvVarSource{ConfigMapKeyRef: &core.ConfigMapKeySelector{Key: "zone"}},
+	}}
+	if _, err := ProviderValues(context.TODO(), c, integration); err == nil || !strings.HasPrefix(err.Error(), "PB#0014") {
+		t.Errorf("Expected an error for a value from a config map, got: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const ConditionProgressing konditions.ConditionStatus = "Progressing"
+
+type rollout struct {
+	ctx         context.Context
+	integration *phonebook.DNSIntegration
+	client.Client
+}
+
+func RolloutTask(ctx context.Context, c client.Client, integration *phonebook.DNSIntegration) konditions.Task {
+	t := rollout{
+		ctx:         ctx,
+		integration: integration,
+		Client:      c,
+	}
+
+	return t.Run
+}
+
+// Report the progress of the deployment's rollout, the same way `kubectl rollout status` does.
+func (t rollout) Run(condition konditions.Condition) (konditions.Condition, error) {
+	if t.integration.Status.Deployment == nil {
+		condition.Status = konditions.ConditionStatus("Waiting")
+		condition.Reason = "Waiting for deployment to be created"
+		return condition, nil
+	}
+
+	var d apps.Deployment
+	err := t.Get(t.ctx, t.integration.Status.Deployment.NamespacedName(), &d)
+	if k8sErrors.IsNotFound(err) {
+		condition.Status = konditions.ConditionStatus("Waiting")
+		condition.Reason = "Waiting for deployment to be created"
+		return condition, nil
+	}
+
+	if err != nil {
+		return condition, fmt.Errorf("PB#0011: Couldn't retrieve the deployment (%s) -- %w", t.integration.Status.Deployment, err)
+	}
+
+	condition.Status, condition.Reason, err = RolloutStatus(&d)
+	return condition, err
+}
+
+// RolloutStatus returns the status of the deployment's rollout and a reason describing it. An error
+// is returned when the rollout exceeded its progress deadline.
+func RolloutStatus(d *apps.Deployment) (konditions.ConditionStatus, string, error) {
+	if d.Generation > d.Status.ObservedGeneration {
+		return ConditionProgressing, "Waiting for the deployment's spec update to be observed", nil
+	}
+
+	for _, c := range d.Status.Conditions {
+		if c.Type == apps.DeploymentProgressing && c.Status == core.ConditionFalse && c.Reason == "ProgressDeadlineExceeded" {
+			return konditions.ConditionError, "Deployment exceeded its progress deadline", fmt.Errorf("PB#0012: Deployment(%s) exceeded its progress deadline. Check the logs of the pods for more info.", d.Name)
+		}
+	}
+
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+
+	if d.Status.UpdatedReplicas < replicas {
+		return ConditionProgressing, fmt.Sprintf("%d out of %d new replicas have been updated", d.Status.UpdatedReplicas, replicas), nil
+	}
+
+	if d.Status.Replicas > d.Status.UpdatedReplicas {
+		return ConditionProgressing, fmt.Sprintf("%d old replicas are pending termination", d.Status.Replicas-d.Status.UpdatedReplicas), nil
+	}
+
+	if d.Status.AvailableReplicas < d.Status.UpdatedReplicas {
+		return ConditionProgressing, fmt.Sprintf("%d of %d updated replicas are available", d.Status.AvailableReplicas, d.Status.UpdatedReplicas), nil
+	}
+
+	return konditions.ConditionCompleted, "Deployment successfully rolled out", nil
+}
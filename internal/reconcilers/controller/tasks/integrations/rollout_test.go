@@ -0,0 +1,70 @@
+package integrations
+
+import (
+	"testing"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	"github.com/pier-oliviert/phonebook/api/v1alpha1/references"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+)
+
+func TestRolloutStatus(t *testing.T) {
+	replicas := int32(1)
+	tests := []struct {
+		name     string
+		status   apps.DeploymentStatus
+		expected konditions.ConditionStatus
+	}{
+		{name: "spec not observed", status: apps.DeploymentStatus{ObservedGeneration: 1}, expected: ConditionProgressing},
+		{name: "replica not updated", status: apps.DeploymentStatus{ObservedGeneration: 2, Replicas: 1}, expected: ConditionProgressing},
+		{name: "old replica terminating", status: apps.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 1}, expected: ConditionProgressing},
+		{name: "replica unavailable", status: apps.DeploymentStatus{ObservedGeneration: 2, Replicas: 1, UpdatedReplicas: 1}, expected: ConditionProgressing},
+		{name: "rolled out", status: apps.DeploymentStatus{ObservedGeneration: 2, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}, expected: konditions.ConditionCompleted},
+		{name: "deadline exceeded", status: apps.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 1, Conditions: []apps.DeploymentCondition{{
+			Type:   apps.DeploymentProgressing,
+			Status: core.ConditionFalse,
+			Reason: "ProgressDeadlineExceeded",
+		}}}, expected: konditions.ConditionError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &apps.Deployment{Spec: apps.DeploymentSpec{Replicas: &replicas}, Status: tt.status}
+			d.Generation = 2
+
+			status, reason, err := RolloutStatus(d)
+			if status != tt.expected {
+				t.Errorf("Expected %s, got: %s (%s)", tt.expected, status, reason)
+			}
+
+			if reason == "" {
+				t.Error("Expected a reason")
+			}
+
+			if (err != nil) != (tt.expected == konditions.ConditionError) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHashSecret(t *testing.T) {
+	ref := &references.SecretRef{
+		Name: "secrets",
+		Keys: []references.SecretKey{{Key: "token", Name: "API_TOKEN"}, {Key: "zone", Name: "ZONE_ID"}},
+	}
+
+	secret := &core.Secret{Data: map[string][]byte{"token": []byte("abc"), "zone": []byte("123"), "unrelated": []byte("value")}}
+	hash := hashSecret(ref, secret)
+
+	secret.Data["unrelated"] = []byte("changed")
+	if hashSecret(ref, secret) != hash {
+		t.Error("Expected keys that aren't referenced to not change the hash")
+	}
+
+	secret.Data["token"] = []byte("def")
+	if hashSecret(ref, secret) == hash {
+		t.Error("Expected the hash to change with the values of the referenced keys")
+	}
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"slices"
+	"strings"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+// hasAuthority returns true if the integration has authority over the zone. If the
+// name of an integration is set, only the integration with that name can match.
+func hasAuthority(integration phonebook.DNSIntegration, zone string, name *string) bool {
+	if name != nil && *name != integration.Name {
+		return false
+	}
+
+	return slices.Contains(integration.Spec.Zones, zone)
+}
+
+// splitHostname finds the zone the hostname belongs to among the zones the integrations have
+// authority over. The longest zone wins so a hostname like `foo.dev.mydomain.com` would use `dev.mydomain.com`
+// over `mydomain.com` if both zones exist. It returns the zone and the name of the record relative to the zone.
+//
+// The apex of a zone isn't supported as it can't be represented by a DNSRecord.
+func splitHostname(integrations []phonebook.DNSIntegration, hostname string, name *string) (zone string, record string, found bool) {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+
+	for _, integration := range integrations {
+		for _, z := range integration.Spec.Zones {
+			if !hasAuthority(integration, z, name) || len(z) <= len(zone) {
+				continue
+			}
+
+			if r, ok := strings.CutSuffix(hostname, "."+z); ok && r != "" {
+				zone = z
+				record = r
+				found = true
+			}
+		}
+	}
+
+	return zone, record, found
+}
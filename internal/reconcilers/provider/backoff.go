@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	kBackoffBase = 5 * time.Second
+	kBackoffMax  = 10 * time.Minute
+)
+
+// backoffTracker keeps track of the records for which the provider returned a retryable error. Each failure
+// doubles the delay before the provider is called again for the record, up to a maximum. The DNSRecord is
+// reconciled every time its status changes, so the delay needs to be tracked here instead of relying on the
+// requeue alone.
+//
+// Like the resyncTracker, it only lives in memory.
+type backoffTracker struct {
+	mu       sync.Mutex
+	attempts map[types.NamespacedName]int
+	until    map[types.NamespacedName]time.Time
+}
+
+// failed records a retryable failure for the record and returns how long to wait before the next
+// attempt. When the provider asked to wait for a specific delay, that delay is used instead.
+func (t *backoffTracker) failed(name types.NamespacedName, retryAfter time.Duration) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.attempts == nil {
+		t.attempts = make(map[types.NamespacedName]int)
+		t.until = make(map[types.NamespacedName]time.Time)
+	}
+
+	delay := kBackoffMax
+	if attempts := t.attempts[name]; attempts < 10 {
+		delay = min(kBackoffBase<<attempts, kBackoffMax)
+	}
+
+	if retryAfter > 0 {
+		delay = retryAfter
+	}
+
+	t.attempts[name]++
+	t.until[name] = time.Now().Add(delay)
+
+	return delay
+}
+
+// next returns how long to wait before the provider can be called again for the record. Zero
+// is returned when the record isn't backing off.
+func (t *backoffTracker) next(name types.NamespacedName) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return max(time.Until(t.until[name]), 0)
+}
+
+func (t *backoffTracker) forget(name types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.attempts, name)
+	delete(t.until, name)
+}
@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+)
+
+// resync reads the record back from the provider and compares it with the DNSRecord's spec. The result
+// is stored in a drift condition (drift.<integration>) so users can see when a record was modified
+// outside of Phonebook. If the reconciler is configured to remediate, the spec is re-applied to the provider
+// through the same lock used to create and update the record.
+//
+// The DNSRecord is reconciled every time its status changes, which can happen a lot when multiple integrations
+// manage the same zone. To avoid reading from the provider on every event, the time of the last read is tracked
+// and the record is only read again once the interval has elapsed.
+func (r *ProviderReconciler) resync(ctx context.Context, req ctrl.Request, record *phonebook.DNSRecord, lock *konditions.Lock) (ctrl.Result, error) {
+	reader, ok := r.Store.Provider().(providers.Reader)
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+
+	if wait := r.resyncs.next(req.NamespacedName, r.ResyncInterval); wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	remote, err := reader.Read(ctx, record.Resolved())
+	missing := errors.Is(err, providers.ErrRecordNotFound)
+	if err != nil && !missing {
+		// Error coming from a Provider should already be coded, so returning it as is.
+		return ctrl.Result{}, err
+	}
+
+	r.resyncs.mark(req.NamespacedName)
+	result := ctrl.Result{RequeueAfter: r.ResyncInterval}
+
+	drift := konditions.Condition{
+		Type:   konditions.ConditionType(fmt.Sprintf("drift.%s", r.Integration)),
+		Status: konditions.ConditionCompleted,
+		Reason: "Remote record matches the spec",
+	}
+
+	switch {
+	case missing:
+		drift.Status = phonebook.ConditionDrifted
+		drift.Reason = "Remote record doesn't exist on the provider"
+	case !remote.Matches(record.Spec):
+		drift.Status = phonebook.ConditionDrifted
+		drift.Reason = fmt.Sprintf("Remote record doesn't match the spec (targets: %v)", remote.Targets)
+	}
+
+	if drift.Status == phonebook.ConditionDrifted && r.Remediate {
+		r.Event(record, core.EventTypeWarning, string(phonebook.ConditionDrifted), drift.Reason)
+		log.FromContext(ctx).Info("Remediating drift", "Record", req.NamespacedName, "Reason", drift.Reason)
+
+		fn := r.Store.Provider().Update
+		if missing {
+			fn = r.Store.Provider().Create
+		}
+
+		task := r.stage(ctx, record, fn)
+		return result, lock.Execute(ctx, func(c konditions.Condition) (konditions.Condition, error) {
+			c, err := task(c)
+			if err == nil && r.backoff.next(req.NamespacedName) == 0 {
+				drift.Status = konditions.ConditionCompleted
+				drift.Reason = "Remote record was re-applied after drifting from the spec"
+			}
+
+			record.Status.Conditions.SetCondition(drift)
+			return c, err
+		})
+	}
+
+	existing := record.Status.Conditions.FindType(drift.Type)
+	if existing != nil && existing.Status == drift.Status && existing.Reason == drift.Reason {
+		return result, nil
+	}
+
+	if drift.Status == phonebook.ConditionDrifted {
+		r.Event(record, core.EventTypeWarning, string(phonebook.ConditionDrifted), drift.Reason)
+	}
+
+	record.Status.Conditions.SetCondition(drift)
+	return result, r.Status().Update(ctx, record)
+}
+
+// resyncTracker keeps track of the last time each record was read from the provider.
+// It only lives in memory, so all records are read again when the server restarts.
+type resyncTracker struct {
+	mu   sync.Mutex
+	last map[types.NamespacedName]time.Time
+}
+
+// next returns how long to wait before the record needs to be read again. A record that
+// was never read returns zero.
+func (t *resyncTracker) next(name types.NamespacedName, interval time.Duration) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last, ok := t.last[name]
+	if !ok {
+		return 0
+	}
+
+	return interval - time.Since(last)
+}
+
+func (t *resyncTracker) mark(name types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.last == nil {
+		t.last = make(map[types.NamespacedName]time.Time)
+	}
+
+	t.last[name] = time.Now()
+}
+
+func (t *resyncTracker) forget(name types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.last, name)
+}
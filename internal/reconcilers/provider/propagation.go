@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+// The propagation condition (propagation.<integration>) is separate from the provider's condition as the provider
+// only knows that its API accepted the change, not whether the record is visible to resolvers.
+func (r *ProviderReconciler) propagationType() konditions.ConditionType {
+	return konditions.ConditionType(fmt.Sprintf("propagation.%s", r.Integration))
+}
+
+// propagating returns true if the record was created or updated by the provider and hasn't been seen on
+// the zone's authoritative nameservers yet.
+func (r *ProviderReconciler) propagating(record *phonebook.DNSRecord) bool {
+	if r.Propagation == nil {
+		return false
+	}
+
+	condition := record.Status.Conditions.FindType(r.propagationType())
+	return condition != nil && condition.Status == konditions.ConditionInitialized
+}
+
+// verify queries the zone's authoritative nameservers and marks the record as propagated once all of them
+// answer with the record's targets. Until then, the record is verified again at the checker's interval. If the record
+// still isn't visible once the timeout elapsed, the condition is set to Error.
+//
+// Failures to reach the nameservers are not returned as errors, they are retried like any other record that hasn't
+// propagated yet and are reported in the condition's reason.
+func (r *ProviderReconciler) verify(ctx context.Context, record *phonebook.DNSRecord) (ctrl.Result, error) {
+	existing := record.Status.Conditions.FindType(r.propagationType())
+	condition := *existing
+
+	propagated, reason, err := r.Propagation.Propagated(ctx, record.Resolved())
+	if err != nil {
+		reason = err.Error()
+	}
+
+	result := ctrl.Result{RequeueAfter: r.Propagation.Interval}
+	condition.Reason = reason
+
+	switch {
+	case propagated:
+		condition.Status = phonebook.ConditionPropagated
+		condition.LastTransitionTime = metav1.Time{}
+		result = ctrl.Result{}
+
+	case time.Since(existing.LastTransitionTime.Time) > r.Propagation.Timeout:
+		condition.Status = konditions.ConditionError
+		condition.Reason = fmt.Sprintf("Record didn't propagate after %s: %s", r.Propagation.Timeout, reason)
+		condition.LastTransitionTime = metav1.Time{}
+		result = ctrl.Result{}
+		r.Event(record, core.EventTypeWarning, string(konditions.ConditionError), condition.Reason)
+
+	case condition.Reason == existing.Reason:
+		// Nothing changed since the last verification, the status doesn't need to be updated.
+		return result, nil
+	}
+
+	log.FromContext(ctx).Info("Propagation verified", "Record", record.Name, "Status", condition.Status, "Reason", condition.Reason)
+
+	record.Status.Conditions.SetCondition(condition)
+	return result, r.Status().Update(ctx, record)
+}
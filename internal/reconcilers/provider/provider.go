@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"time"
 
 	core "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
@@ -16,9 +17,12 @@ import (
 
 	"github.com/pier-oliviert/konditionner/pkg/konditions"
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/propagation"
 	"github.com/pier-oliviert/phonebook/pkg/providers"
 )
 
+const kEventRetrying = "Retrying"
+
 var ErrProviderDidNotSetCondition = errors.New("PB-#0100: Provider didn't set a condition status upon returning from function")
 
 // ProviderReconciler handles all incoming reconciliation requests
@@ -29,6 +33,21 @@ type ProviderReconciler struct {
 	Store       *providers.ProviderStore
 	Integration string
 
+	// ResyncInterval is the interval at which records are read back from the provider
+	// to detect drift. Drift detection is disabled when the interval is zero or when the
+	// provider doesn't implement providers.Reader.
+	ResyncInterval time.Duration
+
+	// Remediate re-applies the DNSRecord's spec to the provider when drift is detected.
+	Remediate bool
+
+	// Propagation verifies that the records created or updated by the provider are visible on
+	// the zone's authoritative nameservers. Verification is disabled when nil.
+	Propagation *propagation.Checker
+
+	resyncs resyncTracker
+	backoff backoffTracker
+
 	client.Client
 	Scheme *runtime.Scheme
 	record.EventRecorder
@@ -38,6 +57,8 @@ type ProviderReconciler struct {
 func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	record, err := r.GetRecord(ctx, req)
 	if k8sErrors.IsNotFound(err) {
+		r.resyncs.forget(req.NamespacedName)
+		r.backoff.forget(req.NamespacedName)
 		return ctrl.Result{}, nil
 	}
 
@@ -45,17 +66,13 @@ func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 		return result, err
 	}
 
-	su := &stageUpdater{
-		record: record,
-	}
-
 	conditionType := konditions.ConditionType(fmt.Sprintf("provider.%s", r.Integration))
 	condition := record.Status.Conditions.FindType(conditionType)
 	if condition == nil || condition.Status == konditions.ConditionError || condition.Status == konditions.ConditionCompleted {
 		return result, nil
 	}
 
-	if !slices.Contains(r.Store.Provider().Zones(), record.Spec.Zone) {
+	if !slices.Contains(r.Store.Provider().Zones(), record.Resolved().Spec.Zone) {
 		// This Provider doesn't have authority over the zone specified by the
 		// record.
 		return result, nil
@@ -66,57 +83,43 @@ func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 		return result, nil
 	}
 
+	if wait := r.backoff.next(req.NamespacedName); wait > 0 {
+		// The provider returned a retryable error the last time it was called for this record.
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
 	switch {
 	case !record.DeletionTimestamp.IsZero():
-		err = lock.Execute(ctx, func(c konditions.Condition) (konditions.Condition, error) {
-			if err = r.Store.Provider().Delete(ctx, *record.DeepCopy(), su); err != nil {
-				return c, err
-			}
-
-			if su.status == nil {
-				return c, ErrProviderDidNotSetCondition
-			}
-
-			c.Status = *su.status
-
-			if su.reason != nil {
-				c.Reason = *su.reason
-			}
-
-			if su.info != nil {
-				record.Status.RemoteInfo[r.Integration] = su.info
-			}
-
-			return c, nil
-		})
+		r.resyncs.forget(req.NamespacedName)
+		err = lock.Execute(ctx, r.stage(ctx, record, r.Store.Provider().Delete))
 
 	case lock.Condition().Status == konditions.ConditionInitialized:
 		// Execute will update the DNSRecord's Status subresource before
 		// it returns. Unless there is an error while updating, any field set on the status
 		// will be persisted by the end of this method.
-		err = lock.Execute(ctx, func(c konditions.Condition) (konditions.Condition, error) {
-			if err = r.Store.Provider().Create(ctx, *record.DeepCopy(), su); err != nil {
-				return c, err
-			}
-			if su.status == nil {
-				return c, ErrProviderDidNotSetCondition
-			}
-			c.Status = *su.status
+		err = lock.Execute(ctx, r.stage(ctx, record, r.Store.Provider().Create))
+		r.resyncs.mark(req.NamespacedName)
 
-			if su.reason != nil {
-				c.Reason = *su.reason
-			}
+	case lock.Condition().Status == konditions.ConditionCreated && !observed(record, r.Integration):
+		// Records created before the generations were tracked don't have one for this integration. The
+		// current generation is recorded instead of updating a record that didn't change.
+		err = r.observe(ctx, record)
 
-			if su.info != nil {
-				if record.Status.RemoteInfo == nil {
-					record.Status.RemoteInfo = make(map[string]phonebook.IntegrationInfo)
-				}
+	case lock.Condition().Status == konditions.ConditionCreated && record.Status.ObservedGenerations[r.Integration] != record.Generation:
+		// The spec changed since the provider last applied the record (ie. new targets or TTL), the
+		// provider needs to update the remote record so it reflects the new spec.
+		err = lock.Execute(ctx, r.stage(ctx, record, r.Store.Provider().Update))
+		r.resyncs.mark(req.NamespacedName)
 
-				record.Status.RemoteInfo[r.Integration] = su.info
-			}
+	case lock.Condition().Status == konditions.ConditionCreated && r.propagating(record):
+		result, err = r.verify(ctx, record)
 
-			return c, nil
-		})
+	case lock.Condition().Status == konditions.ConditionCreated && r.ResyncInterval > 0:
+		result, err = r.resync(ctx, req, record, lock)
+	}
+
+	if wait := r.backoff.next(req.NamespacedName); err == nil && wait > 0 {
+		result.RequeueAfter = wait
 	}
 
 	if k8sErrors.IsConflict(err) {
@@ -136,12 +139,96 @@ func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 	return result, err
 }
 
+// stage wraps one of the Provider's method into a task that can be executed by a lock. Once the provider returns,
+// the values it staged are copied over to the record's condition and status. The record's generation is stored
+// alongside so the reconciler knows when the spec changed and an update is needed.
+//
+// When the provider returns a retryable error (see providers.Retryable), the condition keeps the status it had before the
+// lock was acquired so the same operation runs again once the backoff delay elapsed. Any other error is terminal.
+// In both cases, the RemoteInfo the provider staged before failing is kept so the resources it created, if any,
+// are known to the next operation.
+func (r *ProviderReconciler) stage(ctx context.Context, record *phonebook.DNSRecord, fn func(context.Context, phonebook.DNSRecord, phonebook.StagingUpdater) error) konditions.Task {
+	return func(c konditions.Condition) (konditions.Condition, error) {
+		su := &stageUpdater{
+			record: record,
+		}
+
+		key := client.ObjectKeyFromObject(record)
+		err := fn(ctx, record.Resolved(), su)
+
+		if su.info != nil {
+			if record.Status.RemoteInfo == nil {
+				record.Status.RemoteInfo = make(map[string]phonebook.IntegrationInfo)
+			}
+
+			record.Status.RemoteInfo[r.Integration] = su.info
+		}
+
+		if err != nil {
+			if !providers.Retryable(err) {
+				r.backoff.forget(key)
+				return c, err
+			}
+
+			delay := r.backoff.failed(key, providers.RetryAfter(err))
+			c.Reason = fmt.Sprintf("Retrying in %s: %s", delay, err)
+			log.FromContext(ctx).Info("Provider returned a retryable error", "Record", key, "Kind", providers.Kind(err), "Delay", delay, "Error", err)
+			r.Event(record, core.EventTypeWarning, kEventRetrying, c.Reason)
+
+			return c, nil
+		}
+
+		r.backoff.forget(key)
+
+		if su.status == nil {
+			return c, ErrProviderDidNotSetCondition
+		}
+
+		c.Status = *su.status
+
+		if su.reason != nil {
+			c.Reason = *su.reason
+		}
+
+		if record.Status.ObservedGenerations == nil {
+			record.Status.ObservedGenerations = make(map[string]int64)
+		}
+
+		record.Status.ObservedGenerations[r.Integration] = record.Generation
+
+		if r.Propagation != nil && c.Status == konditions.ConditionCreated {
+			// The record changed on the provider, it needs to be verified again.
+			record.Status.Conditions.SetCondition(konditions.Condition{
+				Type:   r.propagationType(),
+				Status: konditions.ConditionInitialized,
+				Reason: "Waiting for the record to propagate",
+			})
+		}
+
+		return c, nil
+	}
+}
+
 func (r *ProviderReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&phonebook.DNSRecord{}).
 		Complete(r)
 }
 
+func observed(record *phonebook.DNSRecord, integration string) bool {
+	_, ok := record.Status.ObservedGenerations[integration]
+	return ok
+}
+
+func (r *ProviderReconciler) observe(ctx context.Context, record *phonebook.DNSRecord) error {
+	if record.Status.ObservedGenerations == nil {
+		record.Status.ObservedGenerations = make(map[string]int64)
+	}
+
+	record.Status.ObservedGenerations[r.Integration] = record.Generation
+	return r.Status().Update(ctx, record)
+}
+
 func (r *ProviderReconciler) GetRecord(ctx context.Context, req ctrl.Request) (*phonebook.DNSRecord, error) {
 	var record phonebook.DNSRecord
 	if err := r.Get(ctx, req.NamespacedName, &record); err != nil {
@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+)
+
+const kTestIntegration = "test"
+
+// testProvider counts the calls made by the reconciler. Each call runs the function set for it, if any.
+type testProvider struct {
+	calls map[string]int
+
+	create func(context.Context, phonebook.DNSRecord, phonebook.StagingUpdater) error
+}
+
+func (p *testProvider) Configure(ctx context.Context, integration string, zones []string) error {
+	return nil
+}
+
+func (p *testProvider) Zones() []string {
+	return []string{"mydomain.com"}
+}
+
+func (p *testProvider) call(name string, su phonebook.StagingUpdater, status konditions.ConditionStatus) error {
+	p.calls[name]++
+	su.StageCondition(status, name)
+	return nil
+}
+
+func (p *testProvider) Create(ctx context.Context, r phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if p.create != nil {
+		p.calls["Create"]++
+		return p.create(ctx, r, su)
+	}
+	return p.call("Create", su, konditions.ConditionCreated)
+}
+
+func (p *testProvider) Update(ctx context.Context, r phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	return p.call("Update", su, konditions.ConditionCreated)
+}
+
+func (p *testProvider) Delete(ctx context.Context, r phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	return p.call("Delete", su, konditions.ConditionTerminated)
+}
+
+func newTestReconciler(t *testing.T, p providers.Provider, r *phonebook.DNSRecord) *ProviderReconciler {
+	scheme := runtime.NewScheme()
+	if err := phonebook.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &providers.ProviderStore{}
+	store.Store(p)
+
+	return &ProviderReconciler{
+		Integration:   kTestIntegration,
+		Store:         store,
+		Client:        fake.NewClientBuilder().WithScheme(scheme).WithObjects(r).WithStatusSubresource(r).Build(),
+		Scheme:        scheme,
+		EventRecorder: record.NewFakeRecorder(10),
+	}
+}
+
+func newTestRecord(status konditions.ConditionStatus) *phonebook.DNSRecord {
+	r := &phonebook.DNSRecord{
+		ObjectMeta: meta.ObjectMeta{Name: "www", Namespace: "default", Generation: 2},
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1", "127.0.0.2"},
+		},
+	}
+
+	r.Status.Conditions.SetCondition(konditions.Condition{
+		Type:   konditions.ConditionType("provider." + kTestIntegration),
+		Status: status,
+		Reason: "Test",
+	})
+
+	return r
+}
+
+func reconcile(t *testing.T, r *ProviderReconciler, record *phonebook.DNSRecord) *phonebook.DNSRecord {
+	key := client.ObjectKeyFromObject(record)
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	var updated phonebook.DNSRecord
+	if err := r.Get(context.TODO(), key, &updated); err != nil {
+		t.Fatal(err)
+	}
+
+	return &updated
+}
+
+func TestReconcileWithoutObservedGeneration(t *testing.T) {
+	p := &testProvider{calls: map[string]int{}}
+	record := newTestRecord(konditions.ConditionCreated)
+	r := newTestReconciler(t, p, record)
+
+	updated := reconcile(t, r, record)
+	if p.calls["Update"] != 0 {
+		t.Errorf("Expected the record not to be updated, got %d calls", p.calls["Update"])
+	}
+
+	if generation, ok := updated.Status.ObservedGenerations[kTestIntegration]; !ok || generation != updated.Generation {
+		t.Errorf("Expected the current generation to be recorded, got: %v", updated.Status.ObservedGenerations)
+	}
+
+	// The spec changed after the generation was recorded.
+	updated.Generation++
+	if err := r.Update(context.TODO(), updated); err != nil {
+		t.Fatal(err)
+	}
+	updated.Status.ObservedGenerations[kTestIntegration] = 1
+	if err := r.Status().Update(context.TODO(), updated); err != nil {
+		t.Fatal(err)
+	}
+
+	reconcile(t, r, updated)
+	if p.calls["Update"] != 1 {
+		t.Errorf("Expected the record to be updated once its spec changed, got %d calls", p.calls["Update"])
+	}
+}
+
+func TestStageKeepsRemoteInfoOnFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "retryable", err: providers.Transient(errors.New("service unavailable"))},
+		{name: "terminal", err: providers.Permanent(errors.New("invalid record"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The provider creates a remote record for the first target and fails on the second one.
+			p := &testProvider{calls: map[string]int{}, create: func(ctx context.Context, r phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+				su.StageRemoteInfo(phonebook.IntegrationInfo{"ids": "1"})
+				return tt.err
+			}}
+
+			record := newTestRecord(konditions.ConditionInitialized)
+			r := newTestReconciler(t, p, record)
+
+			key := client.ObjectKeyFromObject(record)
+			r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+
+			var updated phonebook.DNSRecord
+			if err := r.Get(context.TODO(), key, &updated); err != nil {
+				t.Fatal(err)
+			}
+
+			if p.calls["Create"] != 1 {
+				t.Fatalf("Expected the provider to be called once, got %d calls", p.calls["Create"])
+			}
+
+			if ids := updated.Status.RemoteInfo[kTestIntegration]["ids"]; ids != "1" {
+				t.Errorf("Expected the RemoteInfo staged before the failure to be kept, got: %v", updated.Status.RemoteInfo)
+			}
+		})
+	}
+}
@@ -92,6 +92,9 @@ func (s *Solver) Group() string {
 // time is pretty low, the bandwidth/CPU to filter those records on the client side seems
 // acceptable at this time.
 //
+// The record doesn't specify a zone as the challenge only provides the ResolvedFQDN. Phonebook will
+// use the longest zone that the FQDN ends with, among all the DNSIntegrations.
+//
 // 1. https://kubernetes.io/docs/concepts/overview/working-with-objects/labels/#syntax-and-character-set
 // 2. https://www.ietf.org/rfc/rfc1034.txt
 func (s *Solver) Present(ch *whapi.ChallengeRequest) error {
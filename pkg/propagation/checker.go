@@ -0,0 +1,209 @@
+package propagation
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+)
+
+const (
+	kResolvConf  = "/etc/resolv.conf"
+	kDefaultPort = "53"
+
+	// Properties of records that nameservers never answer with the record's targets. They're the same keys
+	// the AWS and Cloudflare providers read from the record's properties.
+	kAWSAliasTarget    = "AliasHostedZoneID"
+	kCloudflareProxied = "proxied"
+)
+
+// Checker verifies that a record is visible on the authoritative nameservers of its zone. The nameservers
+// are discovered by looking up the zone's NS records through the resolvers, and each of them is then queried
+// directly so caches between Phonebook and the nameservers don't affect the result.
+type Checker struct {
+	// Resolvers used to discover the zone's nameservers. When empty, the resolvers
+	// configured in /etc/resolv.conf are used.
+	Resolvers []string
+
+	// Port used to query the authoritative nameservers.
+	Port string
+
+	// Interval between each verification while a record hasn't propagated yet.
+	Interval time.Duration
+
+	// Timeout after which a record that still hasn't propagated is reported as an error.
+	Timeout time.Duration
+
+	client *dns.Client
+}
+
+// NewChecker returns a checker that uses the resolvers given to discover the nameservers. Each resolver can
+// include a port (ie. 1.1.1.1:53), the default DNS port is used otherwise.
+func NewChecker(resolvers []string, interval, timeout time.Duration) (*Checker, error) {
+	if len(resolvers) == 0 {
+		config, err := dns.ClientConfigFromFile(kResolvConf)
+		if err != nil {
+			return nil, fmt.Errorf("PB-PROP-#0001: Could not load the resolvers from %s -- %w", kResolvConf, err)
+		}
+
+		for _, server := range config.Servers {
+			resolvers = append(resolvers, net.JoinHostPort(server, config.Port))
+		}
+	}
+
+	for i, resolver := range resolvers {
+		if _, _, err := net.SplitHostPort(resolver); err != nil {
+			resolvers[i] = net.JoinHostPort(resolver, kDefaultPort)
+		}
+	}
+
+	return &Checker{
+		Resolvers: resolvers,
+		Port:      kDefaultPort,
+		Interval:  interval,
+		Timeout:   timeout,
+		client:    &dns.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+// Propagated returns true when every authoritative nameserver of the record's zone answers with the
+// record's targets. The reason explains which nameserver doesn't match, if any. The record needs to be resolved, ie. its
+// spec has the zone and the name relative to the zone.
+func (c *Checker) Propagated(ctx context.Context, record phonebook.DNSRecord) (propagated bool, reason string, err error) {
+	if reason, ok := unverifiable(record.Spec); ok {
+		return true, reason, nil
+	}
+
+	nameservers, err := c.Nameservers(ctx, record.Spec.Zone)
+	if err != nil {
+		return false, "", err
+	}
+
+	fqdn := dns.Fqdn(fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone))
+	for _, ns := range nameservers {
+		answer, err := c.query(ctx, ns, fqdn, record.Spec.RecordType)
+		if err != nil {
+			return false, fmt.Sprintf("Nameserver %s couldn't be queried: %s", ns, err), nil
+		}
+
+		if !answer.Matches(record.Spec) {
+			return false, fmt.Sprintf("Nameserver %s answered with %v", ns, answer.Targets), nil
+		}
+	}
+
+	return true, fmt.Sprintf("Record is visible on %d nameserver(s)", len(nameservers)), nil
+}
+
+// unverifiable returns true for records whose targets are resolved by the provider itself, so the nameservers
+// answer with values that can't be compared to the targets. An AWS alias answers with the addresses of the alias' target
+// and a record proxied by Cloudflare answers with Cloudflare's addresses. Those records are considered propagated
+// as soon as the provider accepted them.
+func unverifiable(spec phonebook.DNSRecordSpec) (string, bool) {
+	if _, ok := spec.Properties[kAWSAliasTarget]; ok {
+		return "Alias records can't be verified on the nameservers", true
+	}
+
+	if strings.EqualFold(spec.Properties[kCloudflareProxied], "true") {
+		return "Proxied records can't be verified on the nameservers", true
+	}
+
+	return "", false
+}
+
+// Nameservers returns the addresses of the authoritative nameservers for the zone. When the resolver
+// includes the nameservers' addresses (glue records), they are used instead of the nameservers' names.
+func (c *Checker) Nameservers(ctx context.Context, zone string) ([]string, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(zone), dns.TypeNS)
+
+	var response *dns.Msg
+	var err error
+	for _, resolver := range c.Resolvers {
+		response, _, err = c.client.ExchangeContext(ctx, msg, resolver)
+		if err == nil && response.Rcode == dns.RcodeSuccess {
+			break
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("PB-PROP-#0002: Could not look up the nameservers for %s -- %w", zone, err)
+	}
+
+	if response == nil || response.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("PB-PROP-#0002: Could not look up the nameservers for %s", zone)
+	}
+
+	glue := map[string]string{}
+	for _, rr := range response.Extra {
+		if a, ok := rr.(*dns.A); ok {
+			glue[strings.ToLower(a.Hdr.Name)] = a.A.String()
+		}
+	}
+
+	var nameservers []string
+	for _, rr := range response.Answer {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+
+		host := ns.Ns
+		if ip, ok := glue[strings.ToLower(ns.Ns)]; ok {
+			host = ip
+		}
+
+		nameservers = append(nameservers, net.JoinHostPort(host, c.Port))
+	}
+
+	if len(nameservers) == 0 {
+		return nil, fmt.Errorf("PB-PROP-#0003: No nameservers found for %s", zone)
+	}
+
+	return nameservers, nil
+}
+
+// query the nameserver for the record and return the values it answered with. The values
+// are formatted the same way they would be in a zone file, which is also how the targets are
+// stored in a DNSRecord.
+func (c *Checker) query(ctx context.Context, nameserver, fqdn, recordType string) (*providers.RemoteRecord, error) {
+	qtype, ok := dns.StringToType[strings.ToUpper(recordType)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported record type %s", recordType)
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(fqdn, qtype)
+	msg.RecursionDesired = false
+
+	response, _, err := c.client.ExchangeContext(ctx, msg, nameserver)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := &providers.RemoteRecord{}
+	for _, rr := range response.Answer {
+		if rr.Header().Rrtype != qtype {
+			continue
+		}
+
+		ttl := int64(rr.Header().Ttl)
+		remote.TTL = &ttl
+
+		// Values longer than 255 characters are split in multiple strings by the nameserver. They're
+		// joined back as the DNSRecord stores them as a single value.
+		if txt, ok := rr.(*dns.TXT); ok {
+			remote.Targets = append(remote.Targets, strings.Join(txt.Txt, ""))
+			continue
+		}
+
+		remote.Targets = append(remote.Targets, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+
+	return remote, nil
+}
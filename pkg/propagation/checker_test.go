@@ -0,0 +1,246 @@
+package propagation
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+// nameserver is an in-process DNS server that acts as both the resolver and the authoritative
+// nameserver for mydomain.com.
+type nameserver struct {
+	mu      sync.Mutex
+	records map[string][]dns.RR
+}
+
+func (ns *nameserver) set(rr ...string) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	ns.records = map[string][]dns.RR{}
+	for _, value := range rr {
+		r, err := dns.NewRR(value)
+		if err != nil {
+			panic(err)
+		}
+
+		key := r.Header().Name + dns.TypeToString[r.Header().Rrtype]
+		ns.records[key] = append(ns.records[key], r)
+	}
+}
+
+func (ns *nameserver) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	msg := new(dns.Msg)
+	msg.SetReply(req)
+	msg.Authoritative = true
+
+	q := req.Question[0]
+	msg.Answer = ns.records[q.Name+dns.TypeToString[q.Qtype]]
+
+	if q.Qtype == dns.TypeNS {
+		glue, _ := dns.NewRR("ns1.mydomain.com. 300 IN A 127.0.0.1")
+		msg.Extra = append(msg.Extra, glue)
+	}
+
+	w.WriteMsg(msg)
+}
+
+func startNameserver(t *testing.T) (*nameserver, string) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ns := &nameserver{}
+	server := &dns.Server{PacketConn: pc, Handler: ns}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+
+	return ns, pc.LocalAddr().String()
+}
+
+func TestPropagated(t *testing.T) {
+	ns, addr := startNameserver(t)
+
+	checker, err := NewChecker([]string{addr}, time.Second, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, checker.Port, _ = net.SplitHostPort(addr)
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"10.0.0.1", "10.0.0.2"},
+		},
+	}
+
+	ns.set(
+		"mydomain.com. 300 IN NS ns1.mydomain.com.",
+		"www.mydomain.com. 60 IN A 10.0.0.1",
+	)
+
+	propagated, reason, err := checker.Propagated(context.TODO(), record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if propagated || !strings.Contains(reason, "10.0.0.1") {
+		t.Errorf("Expected the record to not be propagated yet, got: %s", reason)
+	}
+
+	ns.set(
+		"mydomain.com. 300 IN NS ns1.mydomain.com.",
+		"www.mydomain.com. 60 IN A 10.0.0.2",
+		"www.mydomain.com. 60 IN A 10.0.0.1",
+	)
+
+	propagated, reason, err = checker.Propagated(context.TODO(), record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !propagated {
+		t.Errorf("Expected the record to be propagated, got: %s", reason)
+	}
+}
+
+func TestPropagatedTXT(t *testing.T) {
+	ns, addr := startNameserver(t)
+
+	checker, err := NewChecker([]string{addr}, time.Second, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, checker.Port, _ = net.SplitHostPort(addr)
+
+	ns.set(
+		"mydomain.com. 300 IN NS ns1.mydomain.com.",
+		`_acme-challenge.mydomain.com. 60 IN TXT "challenge-key"`,
+	)
+
+	propagated, reason, err := checker.Propagated(context.TODO(), phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "_acme-challenge",
+			RecordType: "TXT",
+			Targets:    []string{"challenge-key"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !propagated {
+		t.Errorf("Expected the record to be propagated, got: %s", reason)
+	}
+}
+
+func TestPropagatedSplitTXT(t *testing.T) {
+	ns, addr := startNameserver(t)
+
+	checker, err := NewChecker([]string{addr}, time.Second, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, checker.Port, _ = net.SplitHostPort(addr)
+
+	value := strings.Repeat("a", 255) + strings.Repeat("b", 45)
+	ns.set(
+		"mydomain.com. 300 IN NS ns1.mydomain.com.",
+		fmt.Sprintf(`long.mydomain.com. 60 IN TXT "%s" "%s"`, value[:255], value[255:]),
+	)
+
+	propagated, reason, err := checker.Propagated(context.TODO(), phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "long",
+			RecordType: "TXT",
+			Targets:    []string{value},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !propagated {
+		t.Errorf("Expected the record to be propagated, got: %s", reason)
+	}
+}
+
+func TestPropagatedUnverifiable(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties map[string]string
+	}{
+		{name: "aws alias", properties: map[string]string{"AliasHostedZoneID": "Z2FDTNDATAQYW2"}},
+		{name: "cloudflare proxied", properties: map[string]string{"proxied": "true"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns, addr := startNameserver(t)
+
+			// The nameservers answer with the addresses of the alias' target, or Cloudflare's.
+			ns.set(
+				"mydomain.com. 300 IN NS ns1.mydomain.com.",
+				"www.mydomain.com. 60 IN A 104.16.0.1",
+			)
+
+			checker, err := NewChecker([]string{addr}, time.Second, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, checker.Port, _ = net.SplitHostPort(addr)
+
+			propagated, reason, err := checker.Propagated(context.TODO(), phonebook.DNSRecord{
+				Spec: phonebook.DNSRecordSpec{
+					Zone:       "mydomain.com",
+					Name:       "www",
+					RecordType: "A",
+					Targets:    []string{"d111111abcdef8.cloudfront.net"},
+					Properties: tt.properties,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !propagated {
+				t.Errorf("Expected the record to be propagated, got: %s", reason)
+			}
+		})
+	}
+}
+
+func TestNameserversNotFound(t *testing.T) {
+	ns, addr := startNameserver(t)
+	ns.set()
+
+	checker, err := NewChecker([]string{addr}, time.Second, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = checker.Nameservers(context.TODO(), "mydomain.com")
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-PROP-#0003") {
+		t.Errorf("Expected an error when the zone has no nameservers, got: %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+// Package all registers every provider that ships with Phonebook. Importing it makes them available
+// by name through providers.New.
+package all
+
+import (
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/aws"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/azure"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/cloudflare"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/coredns"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/desec"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/digitalocean"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/embedded"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/fake"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/gcore"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/googledns"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/hetzner"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/plugin"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/powerdns"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/rfc2136"
+	_ "github.com/pier-oliviert/phonebook/pkg/providers/webhook"
+)
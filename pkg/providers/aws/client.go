@@ -2,13 +2,20 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
 	"github.com/pier-oliviert/konditionner/pkg/konditions"
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
 	utils "github.com/pier-oliviert/phonebook/pkg/utils"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -21,16 +28,38 @@ import (
 )
 
 const (
-	kAWSZoneID  = "AWS_ZONE_ID"
-	AliasTarget = "AliasHostedZoneID"
-	defaultTTL  = int64(60) // Default TTL for DNS records in seconds if not specified
+	kAWSZoneID          = "AWS_ZONE_ID"
+	kAWSAccessKeyID     = "AWS_ACCESS_KEY_ID"
+	kAWSSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+	kAWSSessionToken    = "AWS_SESSION_TOKEN"
+	kAWSRegion          = "AWS_REGION"
+	AliasTarget         = "AliasHostedZoneID"
+	defaultTTL          = int64(60) // Default TTL for DNS records in seconds if not specified
 )
 
+// The subset of Route53's client used by this provider. It exists
+// so the client can be mocked in tests.
+type route53API interface {
+	ListHostedZonesByName(context.Context, *route53.ListHostedZonesByNameInput, ...func(*route53.Options)) (*route53.ListHostedZonesByNameOutput, error)
+	ChangeResourceRecordSets(context.Context, *route53.ChangeResourceRecordSetsInput, ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error)
+	ListResourceRecordSets(context.Context, *route53.ListResourceRecordSetsInput, ...func(*route53.Options)) (*route53.ListResourceRecordSetsOutput, error)
+}
+
 type r53 struct {
 	integration string
 	zones       []string
-	zoneID      string
-	*route53.Client
+
+	// Hosted Zone ID for each of the zones, resolved when the provider is configured. Zone IDs
+	// can be set explicitly with AWS_ZONE_ID, otherwise they're looked up by name.
+	overrides string
+	zoneIDs   map[string]string
+	route53API
+}
+
+func init() {
+	providers.Register("aws", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
 }
 
 // NewClient doesn't include arguments as all configuration/secret options should be stored
@@ -39,68 +68,177 @@ type r53 struct {
 // by using the tools available and return an error if the client cannot be created.
 func NewClient(ctx context.Context) (*r53, error) {
 	logger := log.FromContext(ctx)
-	cfg, err := config.LoadDefaultConfig(ctx)
+	cfg, err := config.LoadDefaultConfig(ctx, options(ctx)...)
 	if err != nil {
 		return nil, fmt.Errorf("PB-AWS-#0001: Failed to load AWS configuration -- %w", err)
 	}
-	zoneID, err := utils.RetrieveValueFromEnvOrFile(kAWSZoneID)
-	if err != nil {
-		return nil, fmt.Errorf("PB-AWS-#0002: Zone ID not found -- %w", err)
-	}
+	// Zone IDs are optional as they can be looked up for each zone when
+	// the provider is configured.
+	overrides, _ := utils.RetrieveValue(ctx, kAWSZoneID)
 
-	logger.Info("[Provider] AWS Configured", "Zone ID", zoneID)
+	logger.Info("[Provider] AWS Configured", "Zone ID Overrides", overrides)
 
 	return &r53{
-		zoneID: zoneID,
-		Client: route53.NewFromConfig(cfg),
+		overrides:  overrides,
+		route53API: route53.NewFromConfig(cfg),
 	}, nil
 }
 
+// The SDK reads the credentials from the process' environment. Reading them explicitly makes the values
+// set for the integration take precedence when the provider runs inside the controller.
+func options(ctx context.Context) []func(*config.LoadOptions) error {
+	opts := []func(*config.LoadOptions) error{}
+
+	if region, err := utils.RetrieveValue(ctx, kAWSRegion); err == nil {
+		opts = append(opts, config.WithRegion(region))
+	}
+
+	keyID, err := utils.RetrieveValue(ctx, kAWSAccessKeyID)
+	if err != nil {
+		return opts
+	}
+
+	secret, err := utils.RetrieveValue(ctx, kAWSSecretAccessKey)
+	if err != nil {
+		return opts
+	}
+
+	token, _ := utils.RetrieveValue(ctx, kAWSSessionToken)
+	return append(opts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(keyID, secret, token)))
+}
+
+// Configure resolves the Hosted Zone ID for each of the zones. Zones that don't have an ID
+// set explicitly through AWS_ZONE_ID are looked up by name.
 func (c *r53) Configure(ctx context.Context, integration string, zones []string) error {
 	c.zones = zones
 	c.integration = integration
 
+	zoneIDs, err := providers.ParseZoneOverrides(c.overrides, zones)
+	if err != nil {
+		return err
+	}
+
+	for _, zone := range zones {
+		if _, ok := zoneIDs[zone]; ok {
+			continue
+		}
+
+		id, err := c.lookupZoneID(ctx, zone)
+		if err != nil {
+			return err
+		}
+
+		zoneIDs[zone] = id
+	}
+
+	log.FromContext(ctx).Info("[Provider] AWS Zones resolved", "Zone IDs", zoneIDs)
+	c.zoneIDs = zoneIDs
+
 	return nil
 }
 
+// Find the Hosted Zone that matches the zone's name. Route53 can have more than one
+// hosted zone with the same name (ie. public and private), in which case the zone ID needs to be
+// set explicitly.
+func (c *r53) lookupZoneID(ctx context.Context, zone string) (string, error) {
+	name := fmt.Sprintf("%s.", strings.TrimSuffix(zone, "."))
+	output, err := c.ListHostedZonesByName(ctx, &route53.ListHostedZonesByNameInput{
+		DNSName: &name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("PB-AWS-#0008: Failed to list hosted zones -- %w", err)
+	}
+
+	var ids []string
+	for _, hz := range output.HostedZones {
+		if strings.EqualFold(*hz.Name, name) {
+			ids = append(ids, strings.TrimPrefix(*hz.Id, "/hostedzone/"))
+		}
+	}
+
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("PB-AWS-#0002: Zone ID not found for %s", zone)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("PB-AWS-#0009: Multiple hosted zones found for %s (%s), set the zone ID with %s", zone, strings.Join(ids, ", "), kAWSZoneID)
+	}
+}
+
+// Return the Hosted Zone ID for the zone the record belongs to.
+func (c *r53) zoneID(record *phonebook.DNSRecord) (*string, error) {
+	id, ok := c.zoneIDs[record.Spec.Zone]
+	if !ok {
+		return nil, fmt.Errorf("PB-AWS-#0002: Zone ID not found for %s", record.Spec.Zone)
+	}
+
+	return &id, nil
+}
+
 func (c *r53) Zones() []string {
 	return c.zones
 }
 
+// Check that the hosted zone's records can be listed with the credentials.
+func (c *r53) Check(ctx context.Context, zone string) error {
+	id, ok := c.zoneIDs[zone]
+	if !ok {
+		return fmt.Errorf("PB-AWS-#0002: Zone ID not found for %s", zone)
+	}
+
+	_, err := c.ListResourceRecordSets(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId: &id,
+		MaxItems:     to.Ptr(int32(1)),
+	})
+	if err != nil {
+		return fmt.Errorf("PB-AWS-#0010: Failed to read the hosted zone %s (%s) -- %w", zone, id, classify(err))
+	}
+
+	return nil
+}
+
 func (c *r53) Create(ctx context.Context, record phonebook.DNSRecord, updater phonebook.StagingUpdater) error {
-	inputs := route53.ChangeResourceRecordSetsInput{
-		HostedZoneId: &c.zoneID,
-		ChangeBatch: &types.ChangeBatch{
-			Changes: []types.Change{{
-				Action:            types.ChangeActionCreate,
-				ResourceRecordSet: c.resourceRecordSet(ctx, &record),
-			}},
-		},
+	input, err := c.changeInput(ctx, types.ChangeActionCreate, &record)
+	if err != nil {
+		return err
 	}
 
-	_, err := c.ChangeResourceRecordSets(ctx, &inputs)
+	_, err = c.ChangeResourceRecordSets(ctx, input)
 	if err != nil {
-		return fmt.Errorf("PB-AWS-#0003: Failed to create DNS record -- %w", err)
+		return classify(fmt.Errorf("PB-AWS-#0003: Failed to create DNS record -- %w", err))
 	}
 
 	updater.StageCondition(konditions.ConditionCreated, "Route53 created the record")
 	return nil
 }
 
+// Update uses Route53's UPSERT action which replaces the resource record set
+// with the same name and type as the record.
+func (c *r53) Update(ctx context.Context, record phonebook.DNSRecord, updater phonebook.StagingUpdater) error {
+	input, err := c.changeInput(ctx, types.ChangeActionUpsert, &record)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ChangeResourceRecordSets(ctx, input)
+	if err != nil {
+		return classify(fmt.Errorf("PB-AWS-#0006: Failed to update DNS record -- %w", err))
+	}
+
+	updater.StageCondition(konditions.ConditionCreated, "Route53 updated the record")
+	return nil
+}
+
 func (c *r53) Delete(ctx context.Context, record phonebook.DNSRecord, updater phonebook.StagingUpdater) error {
-	inputs := route53.ChangeResourceRecordSetsInput{
-		HostedZoneId: &c.zoneID,
-		ChangeBatch: &types.ChangeBatch{
-			Changes: []types.Change{{
-				Action:            types.ChangeActionDelete,
-				ResourceRecordSet: c.resourceRecordSet(ctx, &record),
-			}},
-		},
+	input, err := c.changeInput(ctx, types.ChangeActionDelete, &record)
+	if err != nil {
+		return err
 	}
 
-	_, err := c.ChangeResourceRecordSets(ctx, &inputs)
+	_, err = c.ChangeResourceRecordSets(ctx, input)
 	if err != nil {
-		return fmt.Errorf("PB-AWS-#0004: Failed to delete DNS record -- %w", err)
+		return classify(fmt.Errorf("PB-AWS-#0004: Failed to delete DNS record -- %w", err))
 	}
 
 	updater.StageCondition(konditions.ConditionTerminated, "Route53 record deleted")
@@ -108,6 +246,70 @@ func (c *r53) Delete(ctx context.Context, record phonebook.DNSRecord, updater ph
 	return nil
 }
 
+// Read the resource record set that matches the record's name and type. Route53 doesn't
+// have a way to retrieve a single record set, instead, the list is started at the record's name
+// and type, and the first result is checked to make sure it's the one Phonebook is looking for.
+func (c *r53) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	fullName := fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone)
+
+	zoneID, err := c.zoneID(&record)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.ListResourceRecordSets(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    zoneID,
+		StartRecordName: &fullName,
+		StartRecordType: types.RRType(record.Spec.RecordType),
+		MaxItems:        to.Ptr(int32(1)),
+	})
+	if err != nil {
+		return nil, classify(fmt.Errorf("PB-AWS-#0007: Failed to read DNS record -- %w", err))
+	}
+
+	if len(output.ResourceRecordSets) == 0 {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	set := output.ResourceRecordSets[0]
+	if !strings.EqualFold(strings.TrimSuffix(*set.Name, "."), fullName) || set.Type != types.RRType(record.Spec.RecordType) {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	remote := &providers.RemoteRecord{
+		TTL: set.TTL,
+	}
+
+	if set.AliasTarget != nil {
+		remote.Targets = append(remote.Targets, *set.AliasTarget.DNSName)
+	}
+
+	for _, rr := range set.ResourceRecords {
+		remote.Targets = append(remote.Targets, *rr.Value)
+	}
+
+	return remote, nil
+}
+
+// Wrap the record into a change batch for the given action. The change targets the
+// hosted zone of the zone the record belongs to.
+func (c *r53) changeInput(ctx context.Context, action types.ChangeAction, record *phonebook.DNSRecord) (*route53.ChangeResourceRecordSetsInput, error) {
+	zoneID, err := c.zoneID(record)
+	if err != nil {
+		return nil, err
+	}
+
+	return &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: zoneID,
+		ChangeBatch: &types.ChangeBatch{
+			Changes: []types.Change{{
+				Action:            action,
+				ResourceRecordSet: c.resourceRecordSet(ctx, record),
+			}},
+		},
+	}, nil
+}
+
 // Convert a DNSRecord to a resourceRecordSet
 func (c *r53) resourceRecordSet(ctx context.Context, record *phonebook.DNSRecord) *types.ResourceRecordSet {
 	fullName := fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone)
@@ -179,3 +381,22 @@ func (c *r53) resourceRecordSet(ctx context.Context, record *phonebook.DNSRecord
 
 	return &set
 }
+
+// classify wraps err with its kind so throttled and failed requests are retried once the SDK's
+// own retries are exhausted. Route53 throttles with a 400, so the API error code is checked before the
+// status code.
+func classify(err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if _, ok := retry.DefaultThrottleErrorCodes[apiErr.ErrorCode()]; ok || apiErr.ErrorCode() == "PriorRequestNotComplete" {
+			return providers.RateLimited(err, 0)
+		}
+	}
+
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return providers.FromHTTPStatus(err, respErr.HTTPStatusCode(), respErr.Response.Header.Get("Retry-After"))
+	}
+
+	return err
+}
@@ -2,21 +2,108 @@ package aws
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
 )
 
+type mockRoute53 struct {
+	zones []types.HostedZone
+	sets  []types.ResourceRecordSet
+	input *route53.ListResourceRecordSetsInput
+}
+
+func (m *mockRoute53) ListHostedZonesByName(context.Context, *route53.ListHostedZonesByNameInput, ...func(*route53.Options)) (*route53.ListHostedZonesByNameOutput, error) {
+	return &route53.ListHostedZonesByNameOutput{HostedZones: m.zones}, nil
+}
+
+func (m *mockRoute53) ChangeResourceRecordSets(context.Context, *route53.ChangeResourceRecordSetsInput, ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	return &route53.ChangeResourceRecordSetsOutput{}, nil
+}
+
+func (m *mockRoute53) ListResourceRecordSets(_ context.Context, input *route53.ListResourceRecordSetsInput, _ ...func(*route53.Options)) (*route53.ListResourceRecordSetsOutput, error) {
+	m.input = input
+	return &route53.ListResourceRecordSetsOutput{ResourceRecordSets: m.sets}, nil
+}
+
 func TestNewClient(t *testing.T) {
-	_, err := NewClient(context.TODO())
-	if !strings.HasPrefix(err.Error(), "PB-AWS-#0002: Zone ID not found --") {
-		t.Error("Client should require a Zone ID")
+	os.Setenv("AWS_ZONE_ID", "Some Value")
+	defer os.Unsetenv("AWS_ZONE_ID")
+
+	c, err := NewClient(context.TODO())
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	os.Setenv("AWS_ZONE_ID", "Some Value")
+	if c.overrides != "Some Value" {
+		t.Error("Expected the zone ID to be used as an override, got: ", c.overrides)
+	}
+}
+
+func TestConfigureZoneIDs(t *testing.T) {
+	mock := &mockRoute53{
+		zones: []types.HostedZone{
+			{Id: to.Ptr("/hostedzone/Z1111"), Name: to.Ptr("mydomain.com.")},
+			{Id: to.Ptr("/hostedzone/Z2222"), Name: to.Ptr("mydomain.com.ca.")},
+		},
+	}
+
+	c := &r53{
+		overrides:  "otherdomain.com=Z3333",
+		route53API: mock,
+	}
+
+	if err := c.Configure(context.TODO(), "aws", []string{"mydomain.com", "otherdomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.zoneIDs["mydomain.com"] != "Z1111" || c.zoneIDs["otherdomain.com"] != "Z3333" {
+		t.Error("Expected the zone IDs to be looked up or overridden, got: ", c.zoneIDs)
+	}
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			RecordType: string(types.RRTypeA),
+			Zone:       "otherdomain.com",
+			Name:       "subdomain",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+
+	input, err := c.changeInput(context.TODO(), types.ChangeActionCreate, &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *input.HostedZoneId != "Z3333" {
+		t.Error("Expected the change to target the record's zone, got: ", *input.HostedZoneId)
+	}
+
+	// Public and private hosted zones can share the same name
+	c.overrides = ""
+	mock.zones = append(mock.zones, types.HostedZone{Id: to.Ptr("/hostedzone/Z4444"), Name: to.Ptr("mydomain.com.")})
+	err = c.Configure(context.TODO(), "aws", []string{"mydomain.com"})
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-AWS-#0009") {
+		t.Error("Expected an error when multiple hosted zones match, got: ", err)
+	}
+
+	mock.zones = nil
+	err = c.Configure(context.TODO(), "aws", []string{"mydomain.com"})
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-AWS-#0002") {
+		t.Error("Expected an error when no hosted zone matches, got: ", err)
+	}
 }
 
 func TestDNSNameConcatenation(t *testing.T) {
@@ -29,7 +116,7 @@ func TestDNSNameConcatenation(t *testing.T) {
 	}
 
 	c := &r53{
-		zoneID: "MyZone123",
+		zoneIDs: map[string]string{"mydomain.com": "MyZone123"},
 	}
 
 	set := c.resourceRecordSet(context.TODO(), &record)
@@ -52,7 +139,7 @@ func TestAliastTargetProperty(t *testing.T) {
 	}
 
 	c := &r53{
-		zoneID: "MyZone123",
+		zoneIDs: map[string]string{"mydomain.com": "MyZone123"},
 	}
 
 	set := c.resourceRecordSet(context.TODO(), &record)
@@ -85,7 +172,7 @@ func TestTXTRecord(t *testing.T) {
 	}
 
 	c := &r53{
-		zoneID: "MyZone123",
+		zoneIDs: map[string]string{"mydomain.com": "MyZone123"},
 	}
 
 	set := c.resourceRecordSet(context.TODO(), &record)
@@ -100,3 +187,126 @@ func TestTXTRecord(t *testing.T) {
 		t.Error("Expected value to return the same value set with extra quotes, got: ", result.Value)
 	}
 }
+
+func TestUpdateChangeAction(t *testing.T) {
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			RecordType: string(types.RRTypeA),
+			Zone:       "mydomain.com",
+			Name:       "subdomain",
+			Targets:    []string{"127.0.0.2"},
+		},
+	}
+
+	c := &r53{
+		zoneIDs: map[string]string{"mydomain.com": "MyZone123"},
+	}
+
+	input, err := c.changeInput(context.TODO(), types.ChangeActionUpsert, &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *input.HostedZoneId != "MyZone123" {
+		t.Error("Expected the change to target the configured zone, got: ", *input.HostedZoneId)
+	}
+
+	if len(input.ChangeBatch.Changes) != 1 {
+		t.Fatal("Expected a single change for the record")
+	}
+
+	change := input.ChangeBatch.Changes[0]
+	if change.Action != types.ChangeActionUpsert {
+		t.Error("Expected the change to upsert the record set, got: ", change.Action)
+	}
+
+	if *change.ResourceRecordSet.ResourceRecords[0].Value != "127.0.0.2" {
+		t.Error("Expected the record set to have the new target, got: ", *change.ResourceRecordSet.ResourceRecords[0].Value)
+	}
+}
+
+func TestRead(t *testing.T) {
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			RecordType: string(types.RRTypeTxt),
+			Zone:       "mydomain.com",
+			Name:       "subdomain",
+			Targets:    []string{"some-values"},
+		},
+	}
+
+	mock := &mockRoute53{
+		sets: []types.ResourceRecordSet{{
+			Name:            to.Ptr("subdomain.mydomain.com."),
+			Type:            types.RRTypeTxt,
+			TTL:             to.Ptr(int64(60)),
+			ResourceRecords: []types.ResourceRecord{{Value: to.Ptr("\"some-values\"")}},
+		}},
+	}
+
+	c := &r53{
+		zoneIDs:    map[string]string{"mydomain.com": "MyZone123"},
+		route53API: mock,
+	}
+
+	remote, err := c.Read(context.TODO(), record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *mock.input.StartRecordName != "subdomain.mydomain.com" || mock.input.StartRecordType != types.RRTypeTxt {
+		t.Error("Expected the list to start at the record, got: ", *mock.input.StartRecordName, mock.input.StartRecordType)
+	}
+
+	if !remote.Matches(record.Spec) {
+		t.Error("Expected the remote record to match the spec, got: ", remote.Targets)
+	}
+
+	// The next record set in the list is returned when the record doesn't exist
+	mock.sets[0].Name = to.Ptr("zzz.mydomain.com.")
+	_, err = c.Read(context.TODO(), record)
+	if !errors.Is(err, providers.ErrRecordNotFound) {
+		t.Error("Expected the record to not be found, got: ", err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind providers.ErrorKind
+	}{
+		{
+			name: "Throttled",
+			err:  &smithy.GenericAPIError{Code: "Throttling", Message: "Rate exceeded"},
+			kind: providers.ErrorRateLimited,
+		},
+		{
+			name: "Prior request not complete",
+			err:  &smithy.GenericAPIError{Code: "PriorRequestNotComplete"},
+			kind: providers.ErrorRateLimited,
+		},
+		{
+			name: "Service unavailable",
+			err: &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}},
+				Err:      errors.New("service unavailable"),
+			}},
+			kind: providers.ErrorTransient,
+		},
+		{
+			name: "Invalid change batch",
+			err:  &smithy.GenericAPIError{Code: "InvalidChangeBatch"},
+			kind: providers.ErrorPermanent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classify(fmt.Errorf("PB-AWS-#0003: Failed to create DNS record -- %w", tt.err))
+			if kind := providers.Kind(err); kind != tt.kind {
+				t.Errorf("Expected kind %s, got %s", tt.kind, kind)
+			}
+		})
+	}
+}
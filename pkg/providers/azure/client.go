@@ -2,15 +2,19 @@ package azure
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
 	"github.com/pier-oliviert/konditionner/pkg/konditions"
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
 	utils "github.com/pier-oliviert/phonebook/pkg/utils"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -26,46 +30,56 @@ const (
 )
 
 type azureDNS struct {
-	integration      string
-	zones            []string
-	zoneName         string
-	resourceGroup    string
+	integration   string
+	zones         []string
+	resourceGroup string
+
+	// Azure DNS zones are named after the domain they serve, so each zone uses its own name
+	// unless a different name is set explicitly through AZURE_ZONE_NAME.
+	overrides string
+	zoneNames map[string]string
+
 	recordSetsClient interface {
 		CreateOrUpdate(ctx context.Context, resourceGroupName string, zoneName string, relativeRecordSetName string, recordType armdns.RecordType, parameters armdns.RecordSet, options *armdns.RecordSetsClientCreateOrUpdateOptions) (armdns.RecordSetsClientCreateOrUpdateResponse, error)
 		Delete(ctx context.Context, resourceGroupName string, zoneName string, relativeRecordSetName string, recordType armdns.RecordType, options *armdns.RecordSetsClientDeleteOptions) (armdns.RecordSetsClientDeleteResponse, error)
+		Get(ctx context.Context, resourceGroupName string, zoneName string, relativeRecordSetName string, recordType armdns.RecordType, options *armdns.RecordSetsClientGetOptions) (armdns.RecordSetsClientGetResponse, error)
 	}
 }
 
+func init() {
+	providers.Register("azure", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
+}
+
 // NewClient initializes an Azure DNS client
 func NewClient(ctx context.Context) (*azureDNS, error) {
 	logger := log.FromContext(ctx)
 
-	clientID, err := utils.RetrieveValueFromEnvOrFile(kAzureClientID)
+	clientID, err := utils.RetrieveValue(ctx, kAzureClientID)
 	if err != nil {
 		return nil, fmt.Errorf("PB-AZ-#0001: Azure Client ID not found -- %w", err)
 	}
 
-	clientSecret, err := utils.RetrieveValueFromEnvOrFile(kAzureClientSecret)
+	clientSecret, err := utils.RetrieveValue(ctx, kAzureClientSecret)
 	if err != nil {
 		return nil, fmt.Errorf("PB-AZ-#0002: Azure Client Secret not found -- %w", err)
 	}
 
-	tenantID, err := utils.RetrieveValueFromEnvOrFile(kAzureTenantID)
+	tenantID, err := utils.RetrieveValue(ctx, kAzureTenantID)
 	if err != nil {
 		return nil, fmt.Errorf("PB-AZ-#0003: Azure Tenant ID not found -- %w", err)
 	}
 
-	subscriptionID, err := utils.RetrieveValueFromEnvOrFile(kAzureSubscriptionID)
+	subscriptionID, err := utils.RetrieveValue(ctx, kAzureSubscriptionID)
 	if err != nil {
 		return nil, fmt.Errorf("PB-AZ-#0004: Azure Subscription ID not found -- %w", err)
 	}
 
-	zoneName, err := utils.RetrieveValueFromEnvOrFile(kAzureZoneName)
-	if err != nil {
-		return nil, fmt.Errorf("PB-AZ-#0005: Azure Zone Name not found -- %w", err)
-	}
+	// Zone names are optional as Azure DNS zones are named after their domain.
+	overrides, _ := utils.RetrieveValue(ctx, kAzureZoneName)
 
-	resourceGroup, err := utils.RetrieveValueFromEnvOrFile(kAzureResourceGroup)
+	resourceGroup, err := utils.RetrieveValue(ctx, kAzureResourceGroup)
 	if err != nil {
 		return nil, fmt.Errorf("PB-AZ-#0006: Azure Resource Group not found -- %w", err)
 	}
@@ -82,34 +96,64 @@ func NewClient(ctx context.Context) (*azureDNS, error) {
 		return nil, fmt.Errorf("PB-AZ-#0008: Unable to create Azure DNS client: %w", err)
 	}
 
-	logger.Info("[Provider] Azure Configured", "Zone Name", zoneName, "Resource Group", resourceGroup)
+	logger.Info("[Provider] Azure Configured", "Zone Name Overrides", overrides, "Resource Group", resourceGroup)
 
 	return &azureDNS{
-		zoneName:         zoneName,
+		overrides:        overrides,
 		resourceGroup:    resourceGroup,
 		recordSetsClient: dnsClient,
 	}, nil
 }
 
 func (c *azureDNS) Configure(ctx context.Context, integration string, zones []string) error {
+	zoneNames, err := providers.ParseZoneOverrides(c.overrides, zones)
+	if err != nil {
+		return err
+	}
+
 	c.zones = zones
 	c.integration = integration
+	c.zoneNames = zoneNames
 	return nil
 }
 
+// Return the name of the Azure DNS zone the record belongs to.
+func (c *azureDNS) zoneName(record *phonebook.DNSRecord) string {
+	if name, ok := c.zoneNames[record.Spec.Zone]; ok {
+		return name
+	}
+
+	return record.Spec.Zone
+}
+
 func (c *azureDNS) Zones() []string {
 	return c.zones
 }
 
+// Check that the zone's SOA record set can be read with the credentials. Every Azure DNS zone has one
+// at its apex, so the check fails when the zone doesn't exist in the resource group.
+func (c *azureDNS) Check(ctx context.Context, zone string) error {
+	name, ok := c.zoneNames[zone]
+	if !ok {
+		name = zone
+	}
+
+	if _, err := c.recordSetsClient.Get(ctx, c.resourceGroup, name, "@", armdns.RecordTypeSOA, nil); err != nil {
+		return fmt.Errorf("PB-AZ-#0018: Failed to read the zone %s in resource group %s -- %w", name, c.resourceGroup, classify(err))
+	}
+
+	return nil
+}
+
 // Create DNS record in Azure
 func (c *azureDNS) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
 	params, err := c.resourceRecordSet(ctx, &record)
 	if err != nil {
 		return fmt.Errorf("PB-AZ-#0009: Failed to create resource record set: %w", err)
 	}
-	response, err := c.recordSetsClient.CreateOrUpdate(ctx, c.resourceGroup, c.zoneName, record.Spec.Name, armdns.RecordType(record.Spec.RecordType), params, nil)
+	response, err := c.recordSetsClient.CreateOrUpdate(ctx, c.resourceGroup, c.zoneName(&record), record.Spec.Name, armdns.RecordType(record.Spec.RecordType), params, nil)
 	if err != nil {
-		return fmt.Errorf("PB-AZ-#0010: Failed to create Azure DNS record: %w", err)
+		return classify(fmt.Errorf("PB-AZ-#0010: Failed to create Azure DNS record: %w", err))
 	}
 
 	// Log the record creation to the console
@@ -124,11 +168,34 @@ func (c *azureDNS) Create(ctx context.Context, record phonebook.DNSRecord, su ph
 	return nil
 }
 
+// Update DNS record in Azure. CreateOrUpdate replaces the whole record set, so updating
+// a record is the same operation as creating it.
+func (c *azureDNS) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	params, err := c.resourceRecordSet(ctx, &record)
+	if err != nil {
+		return fmt.Errorf("PB-AZ-#0009: Failed to create resource record set: %w", err)
+	}
+	response, err := c.recordSetsClient.CreateOrUpdate(ctx, c.resourceGroup, c.zoneName(&record), record.Spec.Name, armdns.RecordType(record.Spec.RecordType), params, nil)
+	if err != nil {
+		return classify(fmt.Errorf("PB-AZ-#0016: Failed to update Azure DNS record: %w", err))
+	}
+
+	log.FromContext(ctx).Info("[Provider] Azure DNS Record Updated", "Name", record.Spec.Name, "Type", record.Spec.RecordType, "Targets", record.Spec.Targets, "TTL", *params.Properties.TTL)
+
+	su.StageRemoteInfo(phonebook.IntegrationInfo{
+		"recordID": *response.ID,
+	})
+
+	su.StageCondition(konditions.ConditionCreated, "Azure DNS record updated")
+
+	return nil
+}
+
 // Delete DNS record from Azure
 func (c *azureDNS) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
-	_, err := c.recordSetsClient.Delete(ctx, c.resourceGroup, c.zoneName, record.Spec.Name, armdns.RecordType(record.Spec.RecordType), nil)
+	_, err := c.recordSetsClient.Delete(ctx, c.resourceGroup, c.zoneName(&record), record.Spec.Name, armdns.RecordType(record.Spec.RecordType), nil)
 	if err != nil {
-		return fmt.Errorf("PB-AZ-#0011: failed to delete Azure DNS record: %w", err)
+		return classify(fmt.Errorf("PB-AZ-#0011: failed to delete Azure DNS record: %w", err))
 	}
 
 	// Log the record deletion to the console
@@ -138,6 +205,57 @@ func (c *azureDNS) Delete(ctx context.Context, record phonebook.DNSRecord, su ph
 	return nil
 }
 
+// Read the record set from Azure and convert it back to the targets format used by DNSRecord
+func (c *azureDNS) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	response, err := c.recordSetsClient.Get(ctx, c.resourceGroup, c.zoneName(&record), record.Spec.Name, armdns.RecordType(record.Spec.RecordType), nil)
+	if err != nil {
+		var respErr *azcore.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+			return nil, providers.ErrRecordNotFound
+		}
+
+		return nil, classify(fmt.Errorf("PB-AZ-#0017: Failed to read Azure DNS record: %w", err))
+	}
+
+	remote := &providers.RemoteRecord{}
+	properties := response.Properties
+	if properties == nil {
+		return remote, nil
+	}
+
+	remote.TTL = properties.TTL
+
+	for _, r := range properties.ARecords {
+		remote.Targets = append(remote.Targets, valueOf(r.IPv4Address))
+	}
+
+	for _, r := range properties.AaaaRecords {
+		remote.Targets = append(remote.Targets, valueOf(r.IPv6Address))
+	}
+
+	if properties.CnameRecord != nil {
+		remote.Targets = append(remote.Targets, valueOf(properties.CnameRecord.Cname))
+	}
+
+	for _, r := range properties.MxRecords {
+		remote.Targets = append(remote.Targets, fmt.Sprintf("%d %s", valueOf(r.Preference), valueOf(r.Exchange)))
+	}
+
+	for _, r := range properties.TxtRecords {
+		values := make([]string, len(r.Value))
+		for i, v := range r.Value {
+			values[i] = valueOf(v)
+		}
+		remote.Targets = append(remote.Targets, strings.Join(values, ""))
+	}
+
+	for _, r := range properties.SrvRecords {
+		remote.Targets = append(remote.Targets, fmt.Sprintf("%d %d %d %s", valueOf(r.Priority), valueOf(r.Weight), valueOf(r.Port), valueOf(r.Target)))
+	}
+
+	return remote, nil
+}
+
 // Convert a DNSRecord to an Azure DNS record set
 func (c *azureDNS) resourceRecordSet(ctx context.Context, record *phonebook.DNSRecord) (armdns.RecordSet, error) {
 	ttl := defaultTTL
@@ -229,3 +347,19 @@ func (c *azureDNS) resourceRecordSet(ctx context.Context, record *phonebook.DNSR
 
 	return params, nil
 }
+
+// classify wraps err with its kind based on the status code returned by Azure so throttled and
+// failed requests are retried.
+func classify(err error) error {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return err
+	}
+
+	var retryAfter string
+	if respErr.RawResponse != nil {
+		retryAfter = respErr.RawResponse.Header.Get("Retry-After")
+	}
+
+	return providers.FromHTTPStatus(err, respErr.StatusCode, retryAfter)
+}
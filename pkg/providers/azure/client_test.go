@@ -2,16 +2,21 @@ package azure
 
 import (
 	"context"
+	"net/http"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
 	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
 )
 
 // MockRecordSetsClient is a mock for the Azure RecordSetsClient
@@ -29,6 +34,11 @@ func (m *MockRecordSetsClient) Delete(ctx context.Context, resourceGroupName str
 	return args.Get(0).(armdns.RecordSetsClientDeleteResponse), args.Error(1)
 }
 
+func (m *MockRecordSetsClient) Get(ctx context.Context, resourceGroupName string, zoneName string, relativeRecordSetName string, recordType armdns.RecordType, options *armdns.RecordSetsClientGetOptions) (armdns.RecordSetsClientGetResponse, error) {
+	args := m.Called(ctx, resourceGroupName, zoneName, relativeRecordSetName, recordType, options)
+	return args.Get(0).(armdns.RecordSetsClientGetResponse), args.Error(1)
+}
+
 func TestNewClient(t *testing.T) {
 	// Mock environment variables
 	os.Setenv(kAzureSubscriptionID, "SomeSubscriptionID")
@@ -63,7 +73,6 @@ func TestDNSNameConcatenation(t *testing.T) {
 	}
 
 	c := &azureDNS{
-		zoneName:      "example.com",
 		resourceGroup: "SomeResourceGroup",
 	}
 
@@ -89,7 +98,6 @@ func TestAliasTargetProperty(t *testing.T) {
 	}
 
 	c := &azureDNS{
-		zoneName:      "example.com",
 		resourceGroup: "SomeResourceGroup",
 	}
 
@@ -138,7 +146,6 @@ func TestResourceRecordSetWithTTL(t *testing.T) {
 	}
 
 	c := &azureDNS{
-		zoneName:      "example.com",
 		resourceGroup: "SomeResourceGroup",
 	}
 
@@ -190,7 +197,6 @@ func TestCreateDNSRecordWithTTL(t *testing.T) {
 	// Create the azureDNS client with the mock
 	c := &azureDNS{
 		integration:      "azure-test",
-		zoneName:         "example.com",
 		resourceGroup:    "SomeResourceGroup",
 		recordSetsClient: mockClient,
 	}
@@ -242,7 +248,6 @@ func TestCreateDNSRecordWithDefaultTTL(t *testing.T) {
 
 	// Create the azureDNS client with the mock
 	c := &azureDNS{
-		zoneName:         "example.com",
 		resourceGroup:    "SomeResourceGroup",
 		recordSetsClient: mockClient,
 	}
@@ -259,6 +264,53 @@ func TestCreateDNSRecordWithDefaultTTL(t *testing.T) {
 	mockClient.AssertExpectations(t)
 }
 
+func TestUpdateDNSRecord(t *testing.T) {
+	record := &phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "example.com",
+			Name:       "testrecord",
+			Targets:    []string{"1.2.3.5", "1.2.3.6"},
+			RecordType: "A",
+			TTL:        to.Ptr(int64(120)),
+		},
+	}
+
+	mockClient := new(MockRecordSetsClient)
+
+	mockClient.On("CreateOrUpdate",
+		mock.Anything,                           // context
+		"SomeResourceGroup",                     // resourceGroupName
+		"example.com",                           // zoneName
+		"testrecord",                            // relativeRecordSetName
+		armdns.RecordTypeA,                      // recordType
+		mock.AnythingOfType("armdns.RecordSet"), // parameters
+		mock.Anything,                           // options
+	).Run(func(args mock.Arguments) {
+		// The record set sent to Azure needs to reflect the new spec
+		params := args.Get(5).(armdns.RecordSet)
+		assert.Equal(t, int64(120), *params.Properties.TTL)
+		assert.Len(t, params.Properties.ARecords, 2)
+	}).Return(armdns.RecordSetsClientCreateOrUpdateResponse{
+		RecordSet: armdns.RecordSet{
+			ID: to.Ptr("fake-id"),
+		},
+	}, nil)
+
+	c := &azureDNS{
+		resourceGroup:    "SomeResourceGroup",
+		recordSetsClient: mockClient,
+	}
+
+	updater := &mocks.Updater{}
+	err := c.Update(context.TODO(), *record, updater)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "fake-id", updater.Info["recordID"])
+	assert.Equal(t, konditions.ConditionCreated, *updater.Status)
+
+	mockClient.AssertExpectations(t)
+}
+
 func TestDeleteDNSRecord(t *testing.T) {
 	// Create a fake record
 	record := &phonebook.DNSRecord{
@@ -284,7 +336,6 @@ func TestDeleteDNSRecord(t *testing.T) {
 
 	// Create the azureDNS client with the mock
 	c := &azureDNS{
-		zoneName:         "example.com",
 		resourceGroup:    "SomeResourceGroup",
 		recordSetsClient: mockClient,
 	}
@@ -298,3 +349,127 @@ func TestDeleteDNSRecord(t *testing.T) {
 	// Verify that our expectations were met
 	mockClient.AssertExpectations(t)
 }
+
+func TestReadDNSRecord(t *testing.T) {
+	record := &phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "example.com",
+			Name:       "testrecord",
+			Targets:    []string{"10 mail.example.com"},
+			RecordType: "MX",
+		},
+	}
+
+	mockClient := new(MockRecordSetsClient)
+	mockClient.On("Get",
+		mock.Anything,       // context
+		"SomeResourceGroup", // resourceGroupName
+		"example.com",       // zoneName
+		"testrecord",        // relativeRecordSetName
+		armdns.RecordTypeMX, // recordType
+		mock.Anything,       // options
+	).Return(armdns.RecordSetsClientGetResponse{
+		RecordSet: armdns.RecordSet{
+			Properties: &armdns.RecordSetProperties{
+				TTL: to.Ptr(int64(60)),
+				MxRecords: []*armdns.MxRecord{
+					{Preference: to.Ptr(int32(10)), Exchange: to.Ptr("mail.example.com")},
+				},
+			},
+		},
+	}, nil)
+
+	c := &azureDNS{
+		resourceGroup:    "SomeResourceGroup",
+		recordSetsClient: mockClient,
+	}
+
+	remote, err := c.Read(context.TODO(), *record)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10 mail.example.com"}, remote.Targets)
+	assert.Equal(t, int64(60), *remote.TTL)
+	assert.True(t, remote.Matches(record.Spec))
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestReadMissingDNSRecord(t *testing.T) {
+	record := &phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "example.com",
+			Name:       "testrecord",
+			RecordType: "A",
+		},
+	}
+
+	mockClient := new(MockRecordSetsClient)
+	mockClient.On("Get",
+		mock.Anything,
+		"SomeResourceGroup",
+		"example.com",
+		"testrecord",
+		armdns.RecordTypeA,
+		mock.Anything,
+	).Return(armdns.RecordSetsClientGetResponse{}, &azcore.ResponseError{StatusCode: http.StatusNotFound})
+
+	c := &azureDNS{
+		resourceGroup:    "SomeResourceGroup",
+		recordSetsClient: mockClient,
+	}
+
+	_, err := c.Read(context.TODO(), *record)
+	assert.ErrorIs(t, err, providers.ErrRecordNotFound)
+}
+
+func TestZoneName(t *testing.T) {
+	c := &azureDNS{
+		overrides: "mydomain.com=mydomain-zone",
+	}
+
+	if err := c.Configure(context.TODO(), "azure", []string{"mydomain.com", "example.com"}); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+
+	record := phonebook.DNSRecord{Spec: phonebook.DNSRecordSpec{Zone: "mydomain.com"}}
+	if name := c.zoneName(&record); name != "mydomain-zone" {
+		t.Errorf("Expected the zone name to be overridden, got %s", name)
+	}
+
+	record.Spec.Zone = "example.com"
+	if name := c.zoneName(&record); name != "example.com" {
+		t.Errorf("Expected the zone name to default to the zone, got %s", name)
+	}
+}
+
+func TestThrottledDNSRecord(t *testing.T) {
+	record := &phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "example.com",
+			Name:       "testrecord",
+			RecordType: "A",
+		},
+	}
+
+	response := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	response.Header.Set("Retry-After", "30")
+
+	mockClient := new(MockRecordSetsClient)
+	mockClient.On("Get",
+		mock.Anything,
+		"SomeResourceGroup",
+		"example.com",
+		"testrecord",
+		armdns.RecordTypeA,
+		mock.Anything,
+	).Return(armdns.RecordSetsClientGetResponse{}, &azcore.ResponseError{StatusCode: http.StatusTooManyRequests, RawResponse: response})
+
+	c := &azureDNS{
+		resourceGroup:    "SomeResourceGroup",
+		recordSetsClient: mockClient,
+	}
+
+	_, err := c.Read(context.TODO(), *record)
+	assert.Equal(t, providers.ErrorRateLimited, providers.Kind(err))
+	assert.Equal(t, 30*time.Second, providers.RetryAfter(err))
+}
@@ -14,3 +14,13 @@ func toInt32Ptr(s string) *int32 {
 	}
 	return to.Ptr(int32(i))
 }
+
+// Helper function to dereference a pointer returned by Azure, returning
+// the zero value if the pointer is nil
+func valueOf[T any](p *T) T {
+	var v T
+	if p != nil {
+		v = *p
+	}
+	return v
+}
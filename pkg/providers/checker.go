@@ -0,0 +1,16 @@
+package providers
+
+import (
+	"context"
+)
+
+// Checker is an optional interface a Provider can implement to validate its configuration
+// against the DNS service. The server runs the check for each zone when the provider starts,
+// and periodically after that. The result is reported in the DNSIntegration's status and the
+// provider's pod is only ready when every zone passes its check.
+type Checker interface {
+	// Check that the credentials are valid and the provider can manage the zone, ie. the zone
+	// exists and the credentials have permissions to modify its records. The returned error
+	// is shown to the user so it should say what's wrong.
+	Check(ctx context.Context, zone string) error
+}
@@ -2,13 +2,17 @@ package cloudflare
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
 	client "github.com/cloudflare/cloudflare-go"
 	"github.com/pier-oliviert/konditionner/pkg/konditions"
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
 	"github.com/pier-oliviert/phonebook/pkg/utils"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 const (
@@ -23,12 +27,22 @@ const (
 
 type cf struct {
 	integration string
-	zoneID      string
 	zones       []string
 
+	// Zone ID for each of the zones, resolved when the provider is configured. Zone IDs
+	// can be set explicitly with CF_ZONE_ID, otherwise they're looked up by name.
+	overrides string
+	zoneIDs   map[string]string
+
 	client.API
 }
 
+func init() {
+	providers.Register("cloudflare", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
+}
+
 // Generate a new Cloudflare Provider that can be used to create DNS records. The
 // provider requires values to be defined by the user in order to be configured properly.
 //
@@ -36,15 +50,14 @@ type cf struct {
 // The file needs to be located at `${kProviderConfigPath}/CF_API_TOKEN`
 // The file path is preferred as that's easier to work with different providers and Kubernetes secret system.
 func NewClient(ctx context.Context) (*cf, error) {
-	token, err := utils.RetrieveValueFromEnvOrFile(kCloudflareAPIKeyName)
+	token, err := utils.RetrieveValue(ctx, kCloudflareAPIKeyName)
 	if err != nil {
 		return nil, fmt.Errorf("PB-CF-#0001: API Key not found -- %w", err)
 	}
 
-	zoneID, err := utils.RetrieveValueFromEnvOrFile(kCloudflareZoneID)
-	if err != nil {
-		return nil, fmt.Errorf("PB-CF-#0002: Zone ID not found -- %w", err)
-	}
+	// Zone IDs are optional as they can be looked up for each zone when
+	// the provider is configured.
+	overrides, _ := utils.RetrieveValue(ctx, kCloudflareZoneID)
 
 	// Trimming space in case the user included a space when copying the token over. This small
 	// quality of life fix might just make it easier to work with token (debugging white spaces when trying new tools can be frustrating)
@@ -54,22 +67,78 @@ func NewClient(ctx context.Context) (*cf, error) {
 	}
 
 	return &cf{
-		zoneID: zoneID,
-		API:    *api,
+		overrides: overrides,
+		API:       *api,
 	}, nil
 }
 
+// Configure resolves the Zone ID for each of the zones. Zones that don't have an ID
+// set explicitly through CF_ZONE_ID are looked up by name.
 func (c *cf) Configure(ctx context.Context, integration string, zones []string) error {
 	c.integration = integration
 	c.zones = zones
 
+	zoneIDs, err := providers.ParseZoneOverrides(c.overrides, zones)
+	if err != nil {
+		return err
+	}
+
+	for _, zone := range zones {
+		if _, ok := zoneIDs[zone]; ok {
+			continue
+		}
+
+		id, err := c.ZoneIDByName(zone)
+		if err != nil {
+			return fmt.Errorf("PB-CF-#0002: Zone ID not found for %s -- %w", zone, err)
+		}
+
+		zoneIDs[zone] = id
+	}
+
+	log.FromContext(ctx).Info("[Provider] Cloudflare Zones resolved", "Zone IDs", zoneIDs)
+	c.zoneIDs = zoneIDs
+
 	return nil
 }
 
+// Return the identifier of the zone the record belongs to.
+func (c *cf) zoneFor(record *phonebook.DNSRecord) (*client.ResourceContainer, error) {
+	id, ok := c.zoneIDs[record.Spec.Zone]
+	if !ok {
+		return nil, fmt.Errorf("PB-CF-#0002: Zone ID not found for %s", record.Spec.Zone)
+	}
+
+	return client.ZoneIdentifier(id), nil
+}
+
 func (c *cf) Zones() []string {
 	return c.zones
 }
 
+// Check that the API token is active and the zone can be retrieved with it.
+func (c *cf) Check(ctx context.Context, zone string) error {
+	token, err := c.VerifyAPIToken(ctx)
+	if err != nil {
+		return fmt.Errorf("PB-CF-#0001: API Token could not be verified -- %w", classify(err))
+	}
+
+	if token.Status != "active" {
+		return fmt.Errorf("PB-CF-#0001: API Token is %s", token.Status)
+	}
+
+	id, ok := c.zoneIDs[zone]
+	if !ok {
+		return fmt.Errorf("PB-CF-#0002: Zone ID not found for %s", zone)
+	}
+
+	if _, err := c.ZoneDetails(ctx, id); err != nil {
+		return fmt.Errorf("PB-CF-#0002: Zone %s could not be retrieved -- %w", zone, classify(err))
+	}
+
+	return nil
+}
+
 func (c *cf) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
 	dnsParams := client.CreateDNSRecordParams{
 		Type:    record.Spec.RecordType,
@@ -106,9 +175,14 @@ func (c *cf) Create(ctx context.Context, record phonebook.DNSRecord, su phoneboo
 		*dnsParams.Proxied = strings.EqualFold(proxied, "true")
 	}
 
-	response, err := c.CreateDNSRecord(ctx, client.ZoneIdentifier(c.zoneID), dnsParams)
+	zone, err := c.zoneFor(&record)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.CreateDNSRecord(ctx, zone, dnsParams)
 	if err != nil {
-		return fmt.Errorf("PB-CF-#0005: Failed to create DNS record -- %w", err)
+		return classify(fmt.Errorf("PB-CF-#0005: Failed to create DNS record -- %w", err))
 	}
 
 	su.StageRemoteInfo(phonebook.IntegrationInfo{
@@ -119,20 +193,156 @@ func (c *cf) Create(ctx context.Context, record phonebook.DNSRecord, su phoneboo
 	return nil
 }
 
-func (c *cf) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
-	if record.Status.RemoteInfo[c.integration] == nil {
-		// Nothing to delete if the RemoteID was never added to this resource. It could
-		// cause an orphan record in Cloudflare, but it might be the better option as the system would
-		// never be able to recover from a lack of remoteID.
-		return nil
+// Update the Cloudflare record that was created for this DNSRecord. The record ID
+// was stored in the RemoteInfo when the record was created and is required to update it.
+func (c *cf) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	info := record.Status.RemoteInfo[c.integration]
+	if info == nil || info["recordID"] == "" {
+		return fmt.Errorf("PB-CF-#0007: Record ID not found, the record cannot be updated")
+	}
+
+	if len(record.Spec.Targets) > 1 {
+		return fmt.Errorf("PB-CF-#0004: Cloudflare does not support multiple targets for the same hostname")
+	}
+
+	dnsParams := client.UpdateDNSRecordParams{
+		ID:      info["recordID"],
+		Type:    record.Spec.RecordType,
+		Name:    record.Spec.Name,
+		Content: record.Spec.Targets[0],
+		TTL:     int(defaultTTL),
 	}
 
-	err := c.DeleteDNSRecord(ctx, client.ZoneIdentifier(c.zoneID), record.Status.RemoteInfo[c.integration]["recordID"])
+	if record.Spec.TTL != nil {
+		dnsParams.TTL = int(*record.Spec.TTL)
+	}
+
+	// Unlike the creation, an empty comment or tags needs to be sent to Cloudflare
+	// so properties that were removed from the DNSRecord are also removed remotely.
+	comment := record.Spec.Properties[PropertiesComment]
+	dnsParams.Comment = &comment
+	dnsParams.Tags = []string{}
+
+	if tags, found := record.Spec.Properties[PropertiesTags]; found {
+		dnsParams.Tags = strings.Split(tags, ";")
+	}
+
+	if proxied, ok := record.Spec.Properties[kCloudflarePropertiesProxied]; ok {
+		dnsParams.Proxied = new(bool)
+		*dnsParams.Proxied = strings.EqualFold(proxied, "true")
+	}
+
+	zone, err := c.zoneFor(&record)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.UpdateDNSRecord(ctx, zone, dnsParams)
+	if err != nil {
+		return classify(fmt.Errorf("PB-CF-#0008: Failed to update DNS record -- %w", err))
+	}
+
+	su.StageRemoteInfo(phonebook.IntegrationInfo{
+		"recordID": response.ID,
+	})
+	su.StageCondition(konditions.ConditionCreated, "Cloudflare record updated")
+
+	return nil
+}
+
+// Read the Cloudflare records that match the record's name and type. The records are looked up by name
+// instead of using the record ID stored in the RemoteInfo so records that were not created by this integration
+// can also be found.
+func (c *cf) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	zone, err := c.zoneFor(&record)
+	if err != nil {
+		return nil, err
+	}
+
+	records, _, err := c.ListDNSRecords(ctx, zone, client.ListDNSRecordsParams{
+		Type: record.Spec.RecordType,
+		Name: fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone),
+	})
+	if err != nil {
+		return nil, classify(fmt.Errorf("PB-CF-#0009: Failed to read DNS record -- %w", err))
+	}
+
+	if len(records) == 0 {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	remote := &providers.RemoteRecord{}
+	for _, r := range records {
+		remote.Targets = append(remote.Targets, r.Content)
+	}
+
+	ttl := int64(records[0].TTL)
+	remote.TTL = &ttl
+
+	return remote, nil
+}
+
+// Delete the Cloudflare record that was created for this DNSRecord. When the record ID is missing from the
+// RemoteInfo (ie. the status was reset), the records are looked up by name and type, and only the ones
+// whose content matches one of the targets are deleted so records not managed by Phonebook are left untouched.
+func (c *cf) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	zone, err := c.zoneFor(&record)
 	if err != nil {
-		return fmt.Errorf("PB-CF-#0006: Failed to delete DNS record -- %w", err)
+		return err
+	}
+
+	var ids []string
+	if id := record.Status.RemoteInfo[c.integration]["recordID"]; id != "" {
+		ids = append(ids, id)
+	} else {
+		records, _, err := c.ListDNSRecords(ctx, zone, client.ListDNSRecordsParams{
+			Type: record.Spec.RecordType,
+			Name: fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone),
+		})
+		if err != nil {
+			return classify(fmt.Errorf("PB-CF-#0006: Failed to delete DNS record -- %w", err))
+		}
+
+		// Cloudflare only supports one target per record, each record is compared to the spec on its own.
+		for _, r := range records {
+			if (&providers.RemoteRecord{Targets: []string{r.Content}}).Matches(record.Spec) {
+				ids = append(ids, r.ID)
+			}
+		}
+	}
+
+	for _, id := range ids {
+		err = c.DeleteDNSRecord(ctx, zone, id)
+
+		// The record was already deleted.
+		var cfErr *client.Error
+		if errors.As(err, &cfErr) && cfErr.StatusCode == http.StatusNotFound {
+			continue
+		}
+
+		if err != nil {
+			return classify(fmt.Errorf("PB-CF-#0006: Failed to delete DNS record -- %w", err))
+		}
 	}
 
 	su.StageCondition(konditions.ConditionTerminated, "Cloudflare record deleted")
 
 	return nil
 }
+
+// classify wraps err with its kind based on the status code returned by Cloudflare's API so
+// rate limits and server errors are retried. Cloudflare's client retries those itself and, once it
+// runs out of retries, returns an error that only has a message to go by.
+func classify(err error) error {
+	var cfErr *client.Error
+	switch {
+	case errors.As(err, &cfErr):
+		return providers.FromHTTPStatus(err, cfErr.StatusCode, "")
+	case strings.Contains(err.Error(), "exceeded available rate limit retries"):
+		return providers.RateLimited(err, 0)
+	case strings.Contains(err.Error(), "please try again later"):
+		return providers.Transient(err)
+	}
+
+	return err
+}
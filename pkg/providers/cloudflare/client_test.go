@@ -2,12 +2,19 @@ package cloudflare
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
 
 	client "github.com/cloudflare/cloudflare-go"
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
 )
 
 type CloudflareAPI interface {
@@ -83,23 +90,64 @@ func TestNewClient(t *testing.T) {
 	// Set API token environment variable
 	os.Setenv("CF_API_TOKEN", "Some Value")
 
-	// Test missing Zone ID
-	_, err = NewClient(context.TODO())
-	if err == nil || !strings.HasPrefix(err.Error(), "PB-CF-#0002: Zone ID not found --") {
-		t.Error("Expected error for missing Zone ID")
+	// Zone IDs are optional as they can be looked up
+	client, err := NewClient(context.TODO())
+	if err != nil {
+		t.Errorf("Expected successful client creation, got error: %v", err)
+	}
+
+	if client == nil {
+		t.Error("Expected a valid client, got nil")
 	}
 
 	// Set Zone ID environment variable
 	os.Setenv("CF_ZONE_ID", "Some Zone ID")
 
-	// Test successful client creation
-	client, err := NewClient(context.TODO())
+	client, err = NewClient(context.TODO())
 	if err != nil {
 		t.Errorf("Expected successful client creation, got error: %v", err)
 	}
 
-	if client == nil {
-		t.Error("Expected a valid client, got nil")
+	if client.overrides != "Some Zone ID" {
+		t.Errorf("Expected the zone ID to be used as an override, got: %s", client.overrides)
+	}
+}
+
+// Test for the zone ID lookup
+func TestConfigureZoneIDs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/zones" || r.URL.Query().Get("name") != "mydomain.com" {
+			w.Write([]byte(`{"success": true, "errors": [], "messages": [], "result": [], "result_info": {"page": 1, "per_page": 50, "count": 0, "total_count": 0, "total_pages": 1}}`))
+			return
+		}
+
+		w.Write([]byte(`{"success": true, "errors": [], "messages": [], "result": [{"id": "mydomain-id", "name": "mydomain.com"}], "result_info": {"page": 1, "per_page": 50, "count": 1, "total_count": 1, "total_pages": 1}}`))
+	}))
+	defer server.Close()
+
+	api, err := client.NewWithAPIToken("Some Value", client.BaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &cf{
+		overrides: "otherdomain.com=otherdomain-id",
+		API:       *api,
+	}
+
+	if err := c.Configure(context.TODO(), "cloudflare-test", []string{"mydomain.com", "otherdomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.zoneIDs["mydomain.com"] != "mydomain-id" || c.zoneIDs["otherdomain.com"] != "otherdomain-id" {
+		t.Errorf("Expected the zone IDs to be looked up or overridden, got: %v", c.zoneIDs)
+	}
+
+	c.overrides = ""
+	err = c.Configure(context.TODO(), "cloudflare-test", []string{"unknown.com"})
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-CF-#0002") {
+		t.Errorf("Expected an error when the zone doesn't exist, got: %v", err)
 	}
 }
 
@@ -213,3 +261,226 @@ func TestDNSDeletion(t *testing.T) {
 		t.Errorf("Expected no error when deleting record with no RemoteID, but got: %v", err)
 	}
 }
+
+// Test for the DNS record update function
+func TestDNSUpdate(t *testing.T) {
+	var params client.UpdateDNSRecordParams
+	var path string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Error(err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success": true, "errors": [], "messages": [], "result": {"id": "fake-record-id"}}`))
+	}))
+	defer server.Close()
+
+	api, err := client.NewWithAPIToken("Some Value", client.BaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &cf{
+		integration: "cloudflare-test",
+		zoneIDs:     map[string]string{"mydomain.com": "zone-id"},
+		API:         *api,
+	}
+
+	ttl := int64(300)
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "subdomain",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.2"},
+			TTL:        &ttl,
+		},
+		Status: phonebook.DNSRecordStatus{
+			RemoteInfo: map[string]phonebook.IntegrationInfo{
+				"cloudflare-test": {
+					"recordID": "fake-record-id",
+				},
+			},
+		},
+	}
+
+	updater := &mocks.Updater{}
+	if err := c.Update(context.TODO(), record, updater); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	if path != "/zones/zone-id/dns_records/fake-record-id" {
+		t.Errorf("Expected the record to be updated through its ID, got: %s", path)
+	}
+
+	if params.Content != "127.0.0.2" || params.TTL != 300 {
+		t.Errorf("Expected the new target and TTL to be sent, got: %s, %d", params.Content, params.TTL)
+	}
+
+	if updater.Info["recordID"] != "fake-record-id" {
+		t.Errorf("Expected the record ID to be staged, got: %v", updater.Info)
+	}
+
+	// Records that were never created can't be updated
+	record.Status.RemoteInfo = nil
+	err = c.Update(context.TODO(), record, updater)
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-CF-#0007") {
+		t.Errorf("Expected an error when the record ID is missing, got: %v", err)
+	}
+}
+
+// Test for the DNS record read function
+func TestDNSRead(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/zones/zone-id/dns_records" || r.URL.Query().Get("type") != "A" {
+			t.Errorf("Unexpected request: %s", r.URL)
+		}
+
+		if r.URL.Query().Get("name") != "subdomain.mydomain.com" {
+			w.Write([]byte(`{"success": true, "errors": [], "messages": [], "result": [], "result_info": {"page": 1, "per_page": 100, "count": 0, "total_count": 0, "total_pages": 1}}`))
+			return
+		}
+
+		w.Write([]byte(`{"success": true, "errors": [], "messages": [], "result": [{"id": "fake-record-id", "content": "127.0.0.1", "ttl": 60}], "result_info": {"page": 1, "per_page": 100, "count": 1, "total_count": 1, "total_pages": 1}}`))
+	}))
+	defer server.Close()
+
+	api, err := client.NewWithAPIToken("Some Value", client.BaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &cf{
+		integration: "cloudflare-test",
+		zoneIDs:     map[string]string{"mydomain.com": "zone-id"},
+		API:         *api,
+	}
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "subdomain",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+
+	remote, err := c.Read(context.TODO(), record)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	if !remote.Matches(record.Spec) {
+		t.Errorf("Expected the remote record to match the spec, got: %v", remote.Targets)
+	}
+
+	record.Spec.Name = "deleted"
+	_, err = c.Read(context.TODO(), record)
+	if !errors.Is(err, providers.ErrRecordNotFound) {
+		t.Errorf("Expected the record to not be found, got: %v", err)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	status := http.StatusTooManyRequests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"success": false, "errors": [{"code": 1000, "message": "error"}], "messages": [], "result": null}`))
+	}))
+	defer server.Close()
+
+	api, err := client.NewWithAPIToken("Some Value", client.BaseURL(server.URL), client.UsingRetryPolicy(0, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &cf{
+		integration: "cloudflare-test",
+		zoneIDs:     map[string]string{"mydomain.com": "zone-id"},
+		API:         *api,
+	}
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "subdomain",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+
+	err = c.Create(context.TODO(), record, &mocks.Updater{})
+	if kind := providers.Kind(err); kind != providers.ErrorRateLimited {
+		t.Errorf("Expected a rate limited error, got %s: %v", kind, err)
+	}
+
+	if !strings.HasPrefix(err.Error(), "PB-CF-#0005") {
+		t.Errorf("Expected the error to keep its code, got: %v", err)
+	}
+
+	status = http.StatusForbidden
+	err = c.Create(context.TODO(), record, &mocks.Updater{})
+	if kind := providers.Kind(err); kind != providers.ErrorPermanent {
+		t.Errorf("Expected a permanent error, got %s: %v", kind, err)
+	}
+}
+
+// Records are looked up by name when the record ID is missing
+func TestDNSDeletionWithoutRecordID(t *testing.T) {
+	var deleted []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodDelete {
+			deleted = append(deleted, r.URL.Path)
+			w.Write([]byte(`{"success": true, "errors": [], "messages": [], "result": {"id": "fake-record-id"}}`))
+			return
+		}
+
+		if r.URL.Query().Get("name") != "subdomain.mydomain.com" || r.URL.Query().Get("type") != "A" {
+			t.Errorf("Unexpected request: %s", r.URL)
+		}
+
+		// The second record has the same name, but wasn't created for this DNSRecord.
+		w.Write([]byte(`{"success": true, "errors": [], "messages": [], "result": [{"id": "fake-record-id", "content": "127.0.0.1", "ttl": 60}, {"id": "other-record-id", "content": "127.0.0.2", "ttl": 60}], "result_info": {"page": 1, "per_page": 100, "count": 2, "total_count": 2, "total_pages": 1}}`))
+	}))
+	defer server.Close()
+
+	api, err := client.NewWithAPIToken("Some Value", client.BaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &cf{
+		integration: "cloudflare-test",
+		zoneIDs:     map[string]string{"mydomain.com": "zone-id"},
+		API:         *api,
+	}
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "subdomain",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+
+	updater := &mocks.Updater{}
+	if err := c.Delete(context.TODO(), record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deleted) != 1 || deleted[0] != "/zones/zone-id/dns_records/fake-record-id" {
+		t.Errorf("Expected only the matching record to be deleted, got: %v", deleted)
+	}
+
+	if updater.Status == nil || *updater.Status != konditions.ConditionTerminated {
+		t.Errorf("Expected the record to be terminated, got: %v", updater.Status)
+	}
+}
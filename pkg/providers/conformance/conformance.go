@@ -0,0 +1,200 @@
+// Package conformance is a test suite that defines how a providers.Provider is expected to behave. Providers run it
+// from their own tests with a provider that talks to a real (or faked) DNS service:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, conformance.Suite{
+//			Zone: "mydomain.com",
+//			NewProvider: func(t *testing.T) providers.Provider {
+//				return newTestProvider(t)
+//			},
+//		})
+//	}
+//
+// The checks that read records back are only run when the provider implements providers.Reader, and the
+// provider's own check is only run when it implements providers.Checker.
+package conformance
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+)
+
+const (
+	defaultIntegration = "conformance"
+	defaultUnsupported = "NOTATYPE"
+)
+
+// Suite describes the provider under test.
+type Suite struct {
+	// NewProvider returns a provider that isn't configured yet. It's called for every test
+	// so records don't leak from one test to another.
+	NewProvider func(t *testing.T) providers.Provider
+
+	// Zone the provider is configured with. Every record is created in that zone.
+	Zone string
+
+	// Integration the provider is configured with. Defaults to "conformance".
+	Integration string
+
+	// SingleTarget is set for providers that only support one target per record.
+	SingleTarget bool
+
+	// UnsupportedType is a record type the provider needs to refuse. Defaults to a type that doesn't exist.
+	UnsupportedType string
+}
+
+// Run every test of the suite against the provider.
+func Run(t *testing.T, s Suite) {
+	if s.Integration == "" {
+		s.Integration = defaultIntegration
+	}
+
+	if s.UnsupportedType == "" {
+		s.UnsupportedType = defaultUnsupported
+	}
+
+	tests := []struct {
+		name string
+		run  func(*testing.T, *harness)
+	}{
+		{name: "Lifecycle", run: testLifecycle},
+		{name: "CreateIsIdempotent", run: testCreateIsIdempotent},
+		{name: "DeleteIsIdempotent", run: testDeleteIsIdempotent},
+		{name: "DeleteWithoutRemoteInfo", run: testDeleteWithoutRemoteInfo},
+		{name: "MultipleTargets", run: testMultipleTargets},
+		{name: "TTL", run: testTTL},
+		{name: "TXTQuoting", run: testTXTQuoting},
+		{name: "UnsupportedType", run: testUnsupportedType},
+		{name: "ReadMissing", run: testReadMissing},
+		{name: "Check", run: testCheck},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &harness{Suite: s, provider: s.NewProvider(t)}
+			h.reader, _ = h.provider.(providers.Reader)
+
+			if err := h.provider.Configure(context.TODO(), s.Integration, []string{s.Zone}); err != nil {
+				t.Fatalf("Configure: %v", err)
+			}
+
+			tt.run(t, h)
+		})
+	}
+}
+
+// harness does what the reconciler does between operations: the values staged by the provider
+// are stored in the record's status.
+type harness struct {
+	Suite
+
+	provider providers.Provider
+	reader   providers.Reader
+}
+
+func (h *harness) record(name, recordType string, targets ...string) *phonebook.DNSRecord {
+	return &phonebook.DNSRecord{
+		ObjectMeta: meta.ObjectMeta{Name: name, Namespace: "conformance", UID: "00000000-0000-0000-0000-000000000000"},
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       h.Zone,
+			Name:       name,
+			RecordType: recordType,
+			Targets:    targets,
+		},
+	}
+}
+
+type operation func(context.Context, phonebook.DNSRecord, phonebook.StagingUpdater) error
+
+// Run the operation and make sure the provider staged the expected status, then store the
+// staged RemoteInfo in the record.
+func (h *harness) apply(t *testing.T, name string, op operation, record *phonebook.DNSRecord, expected konditions.ConditionStatus) {
+	t.Helper()
+
+	updater := &mocks.Updater{}
+	if err := op(context.TODO(), *record, updater); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+
+	if updater.Status == nil {
+		t.Fatalf("%s: the provider didn't stage a condition", name)
+	}
+
+	if *updater.Status != expected {
+		t.Fatalf("%s: expected the provider to stage %s, got: %s", name, expected, *updater.Status)
+	}
+
+	if updater.Info != nil {
+		if record.Status.RemoteInfo == nil {
+			record.Status.RemoteInfo = map[string]phonebook.IntegrationInfo{}
+		}
+		record.Status.RemoteInfo[h.Integration] = updater.Info
+	}
+}
+
+func (h *harness) create(t *testing.T, record *phonebook.DNSRecord) {
+	t.Helper()
+	h.apply(t, "Create", h.provider.Create, record, konditions.ConditionCreated)
+}
+
+func (h *harness) update(t *testing.T, record *phonebook.DNSRecord) {
+	t.Helper()
+	h.apply(t, "Update", h.provider.Update, record, konditions.ConditionCreated)
+}
+
+func (h *harness) delete(t *testing.T, record *phonebook.DNSRecord) {
+	t.Helper()
+	h.apply(t, "Delete", h.provider.Delete, record, konditions.ConditionTerminated)
+}
+
+// Read the record back and make sure it matches its spec. It's a no-op for providers that
+// can't read records.
+func (h *harness) expectMatch(t *testing.T, record *phonebook.DNSRecord) *providers.RemoteRecord {
+	t.Helper()
+
+	if h.reader == nil {
+		return nil
+	}
+
+	remote, err := h.reader.Read(context.TODO(), *record)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	if !remote.Matches(record.Spec) {
+		t.Fatalf("Read: expected %v (TTL: %v), got %v (TTL: %v)", record.Spec.Targets, value(record.Spec.TTL), remote.Targets, value(remote.TTL))
+	}
+
+	return remote
+}
+
+// Make sure the record doesn't exist on the provider. It's a no-op for providers that
+// can't read records.
+func (h *harness) expectMissing(t *testing.T, record *phonebook.DNSRecord) {
+	t.Helper()
+
+	if h.reader == nil {
+		return
+	}
+
+	remote, err := h.reader.Read(context.TODO(), *record)
+	if !errors.Is(err, providers.ErrRecordNotFound) {
+		t.Fatalf("Read: expected providers.ErrRecordNotFound, got: %v (targets: %v)", err, remote)
+	}
+}
+
+func value(ttl *int64) any {
+	if ttl == nil {
+		return nil
+	}
+
+	return *ttl
+}
@@ -0,0 +1,156 @@
+package conformance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+)
+
+// A record goes through its whole lifecycle, and the provider stages a condition for every operation.
+func testLifecycle(t *testing.T, h *harness) {
+	record := h.record("lifecycle", "A", "127.0.0.1")
+	h.create(t, record)
+	h.expectMatch(t, record)
+
+	record.Spec.Targets = []string{"127.0.0.2"}
+	h.update(t, record)
+	h.expectMatch(t, record)
+
+	h.delete(t, record)
+	h.expectMissing(t, record)
+}
+
+// Creating a record that already exists (ie. Phonebook stopped before the status was saved) doesn't fail
+// and doesn't duplicate the record.
+func testCreateIsIdempotent(t *testing.T, h *harness) {
+	record := h.record("create-twice", "A", "127.0.0.1")
+	first := record.DeepCopy()
+
+	h.create(t, first)
+	h.create(t, record)
+	h.expectMatch(t, record)
+
+	h.delete(t, record)
+	h.expectMissing(t, first)
+}
+
+// Deleting a record that is already gone succeeds, the record is expected to be Terminated.
+func testDeleteIsIdempotent(t *testing.T, h *harness) {
+	record := h.record("delete-twice", "A", "127.0.0.1")
+	h.create(t, record)
+
+	h.delete(t, record)
+	h.delete(t, record)
+	h.expectMissing(t, record)
+
+	h.delete(t, h.record("never-created", "A", "127.0.0.1"))
+}
+
+// A record created by Phonebook is deleted even when its RemoteInfo was lost (ie. the status was reset).
+func testDeleteWithoutRemoteInfo(t *testing.T, h *harness) {
+	record := h.record("no-remote-info", "A", "127.0.0.1")
+	h.create(t, record)
+
+	withoutInfo := record.DeepCopy()
+	withoutInfo.Status.RemoteInfo = nil
+	h.delete(t, withoutInfo)
+
+	h.expectMissing(t, record)
+}
+
+// Every target of a record is created.
+func testMultipleTargets(t *testing.T, h *harness) {
+	if h.SingleTarget {
+		t.Skip("The provider only supports one target per record")
+	}
+
+	record := h.record("multiple-targets", "A", "127.0.0.1", "127.0.0.2")
+	h.create(t, record)
+	h.expectMatch(t, record)
+
+	record.Spec.Targets = []string{"127.0.0.2", "127.0.0.3", "127.0.0.4"}
+	h.update(t, record)
+	h.expectMatch(t, record)
+
+	record.Spec.Targets = []string{"127.0.0.4"}
+	h.update(t, record)
+	h.expectMatch(t, record)
+}
+
+// Records without a TTL use the provider's default, while the TTL of a record is used as is.
+func testTTL(t *testing.T, h *harness) {
+	record := h.record("default-ttl", "A", "127.0.0.1")
+	h.create(t, record)
+
+	if remote := h.expectMatch(t, record); remote != nil && remote.TTL != nil && *remote.TTL <= 0 {
+		t.Errorf("Expected a positive default TTL, got: %d", *remote.TTL)
+	}
+
+	ttl := int64(120)
+	record = h.record("ttl", "A", "127.0.0.1")
+	record.Spec.TTL = &ttl
+	h.create(t, record)
+	h.expectMatch(t, record)
+
+	ttl = int64(600)
+	h.update(t, record)
+	h.expectMatch(t, record)
+}
+
+// TXT targets are stored with their spaces, and targets that are already quoted aren't quoted twice.
+func testTXTQuoting(t *testing.T, h *harness) {
+	record := h.record("txt", "TXT", "hello world")
+	h.create(t, record)
+	h.expectMatch(t, record)
+
+	quoted := h.record("txt-quoted", "TXT", `"v=spf1 -all"`)
+	h.create(t, quoted)
+
+	if remote := h.expectMatch(t, quoted); remote != nil && len(remote.Targets) == 1 && remote.Targets[0] == `""v=spf1 -all""` {
+		t.Errorf("Expected the target to not be quoted twice, got: %s", remote.Targets[0])
+	}
+}
+
+// Record types the provider doesn't support return a permanent error without marking the record as Created.
+func testUnsupportedType(t *testing.T, h *harness) {
+	record := h.record("unsupported", h.UnsupportedType, "127.0.0.1")
+
+	updater := &mocks.Updater{}
+	err := h.provider.Create(context.TODO(), *record, updater)
+	if err == nil {
+		t.Fatalf("Expected %s records to be refused", h.UnsupportedType)
+	}
+
+	if providers.Retryable(err) {
+		t.Errorf("Expected a permanent error, got: %v", err)
+	}
+
+	if updater.Status != nil && *updater.Status == konditions.ConditionCreated {
+		t.Errorf("Expected the record to not be marked as Created")
+	}
+}
+
+// Reading a record that doesn't exist returns providers.ErrRecordNotFound.
+func testReadMissing(t *testing.T, h *harness) {
+	if h.reader == nil {
+		t.Skip("The provider can't read records")
+	}
+
+	h.expectMissing(t, h.record("missing", "A", "127.0.0.1"))
+}
+
+// The provider's check passes for the zone it was configured with.
+func testCheck(t *testing.T, h *harness) {
+	checker, ok := h.provider.(providers.Checker)
+	if !ok {
+		t.Skip("The provider doesn't implement providers.Checker")
+	}
+
+	if err := checker.Check(context.TODO(), h.Zone); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Constructor creates a provider. The provider reads its configuration from the environment, or from the
+// context when it runs inside the controller, see utils.RetrieveValue.
+type Constructor func(ctx context.Context) (Provider, error)
+
+var (
+	constructorsMu sync.RWMutex
+	constructors   = map[string]Constructor{}
+)
+
+// Register makes a provider available by its name, as set in the DNSIntegration's `provider.name`. The providers
+// that ship with Phonebook register themselves when their package is imported. Register panics if a provider is
+// already registered with the same name.
+func Register(name string, constructor Constructor) {
+	constructorsMu.Lock()
+	defer constructorsMu.Unlock()
+
+	if constructor == nil {
+		panic("providers: Register constructor is nil")
+	}
+
+	if _, ok := constructors[name]; ok {
+		panic(fmt.Sprintf("providers: Register called twice for %s", name))
+	}
+
+	constructors[name] = constructor
+}
+
+// New creates the provider registered with the name.
+func New(ctx context.Context, name string) (Provider, error) {
+	constructorsMu.RLock()
+	constructor, ok := constructors[name]
+	constructorsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("PB#0015: Unknown provider %q, the registered providers are: %v", name, Names())
+	}
+
+	return constructor(ctx)
+}
+
+// Constructors returns a copy of the registered providers.
+func Constructors() map[string]Constructor {
+	constructorsMu.RLock()
+	defer constructorsMu.RUnlock()
+
+	c := make(map[string]Constructor, len(constructors))
+	for name, constructor := range constructors {
+		c[name] = constructor
+	}
+
+	return c
+}
+
+// Names of the registered providers, sorted.
+func Names() []string {
+	constructorsMu.RLock()
+	defer constructorsMu.RUnlock()
+
+	names := make([]string, 0, len(constructors))
+	for name := range constructors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	expected := errors.New("constructed")
+	Register("test-register", func(ctx context.Context) (Provider, error) {
+		return nil, expected
+	})
+
+	if _, err := New(context.TODO(), "test-register"); !errors.Is(err, expected) {
+		t.Errorf("Expected the registered constructor to be called, got: %v", err)
+	}
+
+	if _, ok := Constructors()["test-register"]; !ok {
+		t.Error("Expected the provider to be part of the constructors")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic when a provider is registered twice")
+		}
+	}()
+
+	Register("test-register", func(ctx context.Context) (Provider, error) {
+		return nil, nil
+	})
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	if _, err := New(context.TODO(), "unknown"); err == nil || !strings.HasPrefix(err.Error(), "PB#0015") {
+		t.Errorf("Expected an unknown provider error, got: %v", err)
+	}
+}
@@ -0,0 +1,341 @@
+package coredns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	core "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	utils "github.com/pier-oliviert/phonebook/pkg/utils"
+)
+
+const (
+	kCoreDNSConfigMap  = "COREDNS_CONFIGMAP"
+	kCoreDNSNamespace  = "COREDNS_NAMESPACE"
+	kCoreDNSFormat     = "COREDNS_FORMAT"
+	kCoreDNSNameserver = "COREDNS_NAMESERVER"
+	kCoreDNSHostmaster = "COREDNS_HOSTMASTER"
+	defaultConfigMap   = "phonebook-zones"
+	defaultNamespace   = "kube-system"
+	defaultTTL         = int64(300) // Default TTL for DNS records in seconds if not specified
+)
+
+type coreDNS struct {
+	integration string
+	zones       []string
+
+	client    client.Client
+	configMap client.ObjectKey
+	format    format
+}
+
+func init() {
+	providers.Register("coredns", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
+}
+
+// NewClient creates a provider that stores its zones in a ConfigMap that CoreDNS reads, either as zone
+// files for the file plugin or as hosts files for the hosts plugin.
+func NewClient(ctx context.Context) (*coreDNS, error) {
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("PB-CDNS-#0001: Unable to create Kubernetes client -- %w", err)
+	}
+
+	c, err := client.New(config, client.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("PB-CDNS-#0001: Unable to create Kubernetes client -- %w", err)
+	}
+
+	return newCoreDNS(ctx, c)
+}
+
+func newCoreDNS(ctx context.Context, c client.Client) (*coreDNS, error) {
+	p := &coreDNS{
+		client:    c,
+		configMap: client.ObjectKey{Name: defaultConfigMap, Namespace: defaultNamespace},
+	}
+
+	if name, _ := utils.RetrieveValue(ctx, kCoreDNSConfigMap); strings.TrimSpace(name) != "" {
+		p.configMap.Name = strings.TrimSpace(name)
+	}
+
+	if namespace, _ := utils.RetrieveValue(ctx, kCoreDNSNamespace); strings.TrimSpace(namespace) != "" {
+		p.configMap.Namespace = strings.TrimSpace(namespace)
+	}
+
+	f, _ := utils.RetrieveValue(ctx, kCoreDNSFormat)
+	switch strings.ToLower(strings.TrimSpace(f)) {
+	case "", "file":
+		zf := zoneFile{}
+		if nameserver, _ := utils.RetrieveValue(ctx, kCoreDNSNameserver); strings.TrimSpace(nameserver) != "" {
+			zf.nameserver = dns.Fqdn(strings.TrimSpace(nameserver))
+		}
+
+		if hostmaster, _ := utils.RetrieveValue(ctx, kCoreDNSHostmaster); strings.TrimSpace(hostmaster) != "" {
+			zf.hostmaster = dns.Fqdn(strings.Replace(strings.TrimSpace(hostmaster), "@", ".", 1))
+		}
+
+		p.format = zf
+	case "hosts":
+		p.format = hostsFile{}
+	default:
+		return nil, fmt.Errorf("PB-CDNS-#0002: Format needs to be either file or hosts, got %s", f)
+	}
+
+	return p, nil
+}
+
+// Configure makes sure the ConfigMap exists and has a key for each zone, so CoreDNS can load
+// the zones before any record is created.
+func (p *coreDNS) Configure(ctx context.Context, integration string, zones []string) error {
+	p.integration = integration
+	p.zones = zones
+
+	var cm core.ConfigMap
+	err := p.client.Get(ctx, p.configMap, &cm)
+	if k8sErrors.IsNotFound(err) {
+		cm = core.ConfigMap{ObjectMeta: meta.ObjectMeta{Name: p.configMap.Name, Namespace: p.configMap.Namespace}}
+		err = p.client.Create(ctx, &cm)
+	}
+
+	if err != nil {
+		return fmt.Errorf("PB-CDNS-#0003: Unable to retrieve the ConfigMap %s -- %w", p.configMap, err)
+	}
+
+	for _, zone := range zones {
+		if err := p.modify(ctx, zone, func(rrs []dns.RR) []dns.RR { return rrs }, false); err != nil {
+			return fmt.Errorf("PB-CDNS-#0003: Unable to initialize the zone %s in the ConfigMap %s -- %w", zone, p.configMap, err)
+		}
+	}
+
+	log.FromContext(ctx).Info("[Provider] CoreDNS configured", "ConfigMap", p.configMap, "Zones", zones)
+	return nil
+}
+
+func (p *coreDNS) Zones() []string {
+	return p.zones
+}
+
+// Check that the ConfigMap can still be retrieved by the provider.
+func (p *coreDNS) Check(ctx context.Context, zone string) error {
+	var cm core.ConfigMap
+	if err := p.client.Get(ctx, p.configMap, &cm); err != nil {
+		return fmt.Errorf("PB-CDNS-#0003: Unable to retrieve the ConfigMap %s -- %w", p.configMap, err)
+	}
+
+	return nil
+}
+
+// Create the record's RRset in the zone. Any RRset with the same name and type is replaced so creating
+// a record that already exists doesn't fail.
+func (p *coreDNS) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := p.replace(ctx, &record); err != nil {
+		return classify(fmt.Errorf("PB-CDNS-#0004: Failed to create DNS record -- %w", err))
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "CoreDNS record created")
+	return nil
+}
+
+// Update replaces the record's RRset with the record's targets and TTL.
+func (p *coreDNS) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := p.replace(ctx, &record); err != nil {
+		return classify(fmt.Errorf("PB-CDNS-#0005: Failed to update DNS record -- %w", err))
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "CoreDNS record updated")
+	return nil
+}
+
+// Delete removes the record's RRset from the zone.
+func (p *coreDNS) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	rrtype, err := p.recordType(&record)
+	if err != nil {
+		return err
+	}
+
+	err = p.modify(ctx, record.Spec.Zone, func(rrs []dns.RR) []dns.RR {
+		return without(rrs, fqdn(&record), rrtype)
+	}, true)
+
+	if err != nil {
+		return classify(fmt.Errorf("PB-CDNS-#0006: Failed to delete DNS record -- %w", err))
+	}
+
+	su.StageCondition(konditions.ConditionTerminated, "CoreDNS record deleted")
+	return nil
+}
+
+// Read the record's RRset from the zone stored in the ConfigMap.
+func (p *coreDNS) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	rrtype, err := p.recordType(&record)
+	if err != nil {
+		return nil, err
+	}
+
+	var cm core.ConfigMap
+	if err := p.client.Get(ctx, p.configMap, &cm); err != nil {
+		return nil, classify(fmt.Errorf("PB-CDNS-#0007: Failed to read DNS record -- %w", err))
+	}
+
+	origin := dns.CanonicalName(record.Spec.Zone)
+	rrs, err := p.format.parse(origin, cm.Data[p.format.key(origin)])
+	if err != nil {
+		return nil, providers.Permanent(fmt.Errorf("PB-CDNS-#0007: Failed to read DNS record -- %w", err))
+	}
+
+	remote := &providers.RemoteRecord{}
+	for _, rr := range rrs {
+		if !strings.EqualFold(rr.Header().Name, fqdn(&record)) || rr.Header().Rrtype != rrtype {
+			continue
+		}
+
+		if _, ok := p.format.(zoneFile); ok {
+			// Hosts files don't store the TTL.
+			ttl := int64(rr.Header().Ttl)
+			remote.TTL = &ttl
+		}
+
+		remote.Targets = append(remote.Targets, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+
+	if len(remote.Targets) == 0 {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	return remote, nil
+}
+
+// Replace the record's RRset in the zone with the record's targets.
+func (p *coreDNS) replace(ctx context.Context, record *phonebook.DNSRecord) error {
+	rrs, err := p.resourceRecords(record)
+	if err != nil {
+		return err
+	}
+
+	rrtype, _ := p.recordType(record)
+	return p.modify(ctx, record.Spec.Zone, func(existing []dns.RR) []dns.RR {
+		return append(without(existing, fqdn(record), rrtype), rrs...)
+	}, true)
+}
+
+// Apply fn to the zone's records and write the zone back to the ConfigMap. The ConfigMap is updated with the
+// resource version it was read with, so a concurrent modification (ie. from another provider managing a zone in the
+// same ConfigMap) results in a conflict and the modification is applied again on the latest version.
+//
+// When force is false, the zone is only written if the ConfigMap doesn't have it yet.
+func (p *coreDNS) modify(ctx context.Context, zone string, fn func([]dns.RR) []dns.RR, force bool) error {
+	origin := dns.CanonicalName(zone)
+	key := p.format.key(origin)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var cm core.ConfigMap
+		if err := p.client.Get(ctx, p.configMap, &cm); err != nil {
+			return err
+		}
+
+		content, exists := cm.Data[key]
+		if exists && !force {
+			return nil
+		}
+
+		rrs, err := p.format.parse(origin, content)
+		if err != nil {
+			return providers.Permanent(fmt.Errorf("the zone %s in the ConfigMap couldn't be parsed -- %w", zone, err))
+		}
+
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+
+		cm.Data[key] = p.format.render(origin, fn(rrs))
+		return p.client.Update(ctx, &cm)
+	})
+}
+
+// Convert the record's targets to resource records. The targets are parsed the same way they would be in a zone
+// file, so names in a target (ie. CNAME) are fully qualified and TXT values are quoted.
+func (p *coreDNS) resourceRecords(record *phonebook.DNSRecord) ([]dns.RR, error) {
+	if _, err := p.recordType(record); err != nil {
+		return nil, err
+	}
+
+	ttl := defaultTTL
+	if record.Spec.TTL != nil {
+		ttl = *record.Spec.TTL
+	}
+
+	var rrs []dns.RR
+	for _, target := range record.Spec.Targets {
+		if strings.EqualFold(record.Spec.RecordType, "TXT") {
+			target = fmt.Sprintf("\"%s\"", strings.Trim(target, "\""))
+		}
+
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", fqdn(record), ttl, record.Spec.RecordType, target))
+		if err != nil || rr == nil {
+			return nil, providers.Permanent(fmt.Errorf("PB-CDNS-#0008: Invalid target (%s) for %s record -- %v", target, record.Spec.RecordType, err))
+		}
+
+		rrs = append(rrs, rr)
+	}
+
+	return rrs, nil
+}
+
+func (p *coreDNS) recordType(record *phonebook.DNSRecord) (uint16, error) {
+	rrtype, ok := dns.StringToType[strings.ToUpper(record.Spec.RecordType)]
+	if !ok || !p.format.supports(rrtype) {
+		return 0, providers.Permanent(fmt.Errorf("PB-CDNS-#0008: Record type %s is not supported", record.Spec.RecordType))
+	}
+
+	return rrtype, nil
+}
+
+// Return the resource records that don't have the given name and type.
+func without(rrs []dns.RR, name string, rrtype uint16) []dns.RR {
+	var kept []dns.RR
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == rrtype && strings.EqualFold(rr.Header().Name, name) {
+			continue
+		}
+
+		kept = append(kept, rr)
+	}
+
+	return kept
+}
+
+func fqdn(record *phonebook.DNSRecord) string {
+	if record.Spec.Name == "" || record.Spec.Name == "@" {
+		return dns.CanonicalName(record.Spec.Zone)
+	}
+
+	return dns.CanonicalName(fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone))
+}
+
+// classify wraps err with its kind. Errors from the Kubernetes API that are expected to go away (ie. the API
+// server is overloaded, or the ConfigMap kept changing while it was updated) are transient.
+func classify(err error) error {
+	switch {
+	case k8sErrors.IsConflict(err), k8sErrors.IsServerTimeout(err), k8sErrors.IsTimeout(err),
+		k8sErrors.IsTooManyRequests(err), k8sErrors.IsServiceUnavailable(err), k8sErrors.IsInternalError(err):
+		return providers.Transient(err)
+	case k8sErrors.IsForbidden(err), k8sErrors.IsNotFound(err), k8sErrors.IsInvalid(err):
+		return providers.Permanent(err)
+	}
+
+	return err
+}
@@ -0,0 +1,258 @@
+package coredns
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	core "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"github.com/pier-oliviert/phonebook/pkg/providers/conformance"
+)
+
+func newTestProvider(t *testing.T, format string, c client.Client) *coreDNS {
+	t.Setenv(kCoreDNSConfigMap, "")
+	t.Setenv(kCoreDNSNamespace, "")
+	t.Setenv(kCoreDNSFormat, format)
+	t.Setenv(kCoreDNSNameserver, "ns1.mydomain.com")
+	t.Setenv(kCoreDNSHostmaster, "")
+
+	p, err := newCoreDNS(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Configure(context.TODO(), "coredns-test", []string{"mydomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func zone(t *testing.T, c client.Client, key string) string {
+	var cm core.ConfigMap
+	if err := c.Get(context.TODO(), client.ObjectKey{Name: defaultConfigMap, Namespace: defaultNamespace}, &cm); err != nil {
+		t.Fatal(err)
+	}
+
+	return cm.Data[key]
+}
+
+func TestConfigure(t *testing.T) {
+	c := fake.NewClientBuilder().Build()
+	newTestProvider(t, "file", c)
+
+	content := zone(t, c, "db.mydomain.com")
+	if !strings.Contains(content, "SOA\tns1.mydomain.com. hostmaster.mydomain.com.") || !strings.Contains(content, "NS\tns1.mydomain.com.") {
+		t.Errorf("Expected the ConfigMap to be created with the zone's SOA and NS, got:\n%s", content)
+	}
+
+	// Configuring the provider again, ie. after a restart, doesn't modify existing zones.
+	newTestProvider(t, "file", c)
+	if zone(t, c, "db.mydomain.com") != content {
+		t.Errorf("Expected the zone to be left untouched, got:\n%s", zone(t, c, "db.mydomain.com"))
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	c := fake.NewClientBuilder().Build()
+	p := newTestProvider(t, "file", c)
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1", "127.0.0.2"},
+		},
+	}
+
+	updater := &mocks.Updater{}
+	if err := p.Create(context.TODO(), record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	if *updater.Status != konditions.ConditionCreated {
+		t.Errorf("Expected the record to be created, got: %s", *updater.Status)
+	}
+
+	if content := zone(t, c, "db.mydomain.com"); strings.Count(content, "www.mydomain.com.\t300\tIN\tA") != 2 {
+		t.Errorf("Expected both targets in the zone, got:\n%s", content)
+	}
+
+	ttl := int64(60)
+	record.Spec.TTL = &ttl
+	record.Spec.Targets = []string{"127.0.0.3"}
+	if err := p.Update(context.TODO(), record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	remote, err := p.Read(context.TODO(), record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !remote.Matches(record.Spec) {
+		t.Errorf("Expected the remote record to match the spec, got: %v", remote.Targets)
+	}
+
+	if err := p.Delete(context.TODO(), record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	if *updater.Status != konditions.ConditionTerminated {
+		t.Errorf("Expected the record to be deleted, got: %s", *updater.Status)
+	}
+
+	if _, err := p.Read(context.TODO(), record); !errors.Is(err, providers.ErrRecordNotFound) {
+		t.Errorf("Expected the record to not be found, got: %v", err)
+	}
+}
+
+func TestConflict(t *testing.T) {
+	conflicts := 0
+	c := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			if cm := obj.(*core.ConfigMap); strings.Contains(cm.Data["db.mydomain.com"], "www") && conflicts == 0 {
+				conflicts++
+
+				// Another writer modifies the ConfigMap before this update goes through.
+				var latest core.ConfigMap
+				c.Get(ctx, client.ObjectKeyFromObject(obj), &latest)
+				latest.Data["db.mydomain.com"] += "other.mydomain.com.\t300\tIN\tA\t127.0.0.9\n"
+				if err := c.Update(ctx, &latest); err != nil {
+					return err
+				}
+
+				return k8sErrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, obj.GetName(), errors.New("modified"))
+			}
+
+			return c.Update(ctx, obj, opts...)
+		},
+	}).Build()
+
+	p := newTestProvider(t, "file", c)
+
+	err := p.Create(context.TODO(), phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}, &mocks.Updater{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := zone(t, c, "db.mydomain.com")
+	if conflicts != 1 || !strings.Contains(content, "www.mydomain.com.") || !strings.Contains(content, "other.mydomain.com.") {
+		t.Errorf("Expected the update to be applied on top of the concurrent one, got:\n%s", content)
+	}
+}
+
+func TestHostsFormat(t *testing.T) {
+	c := fake.NewClientBuilder().Build()
+	p := newTestProvider(t, "hosts", c)
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "AAAA",
+			Targets:    []string{"::1"},
+		},
+	}
+
+	if err := p.Create(context.TODO(), record, &mocks.Updater{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if content := zone(t, c, "hosts.mydomain.com"); content != "::1 www.mydomain.com\n" {
+		t.Errorf("Expected a hosts file, got:\n%s", content)
+	}
+
+	remote, err := p.Read(context.TODO(), record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !remote.Matches(record.Spec) || remote.TTL != nil {
+		t.Errorf("Expected the remote record to match the spec without a TTL, got: %v", remote.Targets)
+	}
+
+	record.Spec.RecordType = "CNAME"
+	record.Spec.Targets = []string{"mydomain.com"}
+	err = p.Create(context.TODO(), record, &mocks.Updater{})
+	if err == nil || !strings.Contains(err.Error(), "PB-CDNS-#0008") || providers.Retryable(err) {
+		t.Errorf("Expected CNAME records to be refused by the hosts format, got: %v", err)
+	}
+}
+
+func TestNextSerial(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		current  uint32
+		expected uint32
+	}{
+		{current: 0, expected: 2026101700},
+		{current: 2026101700, expected: 2026101701},
+		{current: 2026101899, expected: 2026101900},
+	}
+
+	for _, tt := range tests {
+		if serial := nextSerial(tt.current, now); serial != tt.expected {
+			t.Errorf("Expected %d after %d, got %d", tt.expected, tt.current, serial)
+		}
+	}
+
+	f := zoneFile{}
+	rrs, err := f.parse("mydomain.com.", f.render("mydomain.com.", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := rrs[0].(*dns.SOA).Serial
+	rrs, _ = f.parse("mydomain.com.", f.render("mydomain.com.", rrs))
+	if rrs[0].(*dns.SOA).Serial != first+1 {
+		t.Errorf("Expected the serial to be bumped when the zone is rendered, got %d after %d", rrs[0].(*dns.SOA).Serial, first)
+	}
+}
+
+func TestInvalidFormat(t *testing.T) {
+	t.Setenv(kCoreDNSFormat, "bind")
+
+	if _, err := newCoreDNS(context.Background(), nil); err == nil || !strings.HasPrefix(err.Error(), "PB-CDNS-#0002") {
+		t.Errorf("Expected an invalid format to fail, got: %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, conformance.Suite{
+		Zone: "mydomain.com",
+		NewProvider: func(t *testing.T) providers.Provider {
+			t.Setenv(kCoreDNSFormat, "file")
+
+			p, err := newCoreDNS(context.Background(), fake.NewClientBuilder().Build())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return p
+		},
+	})
+}
@@ -0,0 +1,200 @@
+package coredns
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// Values of the SOA record created for new zones.
+	kSOATTL     = 3600
+	kSOARefresh = 300
+	kSOARetry   = 60
+	kSOAExpire  = 604800
+	kSOAMinTTL  = 60
+)
+
+// format converts the content of a zone, as stored in the ConfigMap, to resource records and back. Each format
+// is read by a different CoreDNS plugin.
+type format interface {
+	// Key of the ConfigMap that holds the zone.
+	key(origin string) string
+
+	parse(origin, content string) ([]dns.RR, error)
+	render(origin string, rrs []dns.RR) string
+
+	supports(rrtype uint16) bool
+}
+
+// zoneFile is the RFC 1035 format read by CoreDNS' file plugin. The plugin only reloads the zone when the SOA's
+// serial changes, so the serial is bumped every time the zone is rendered.
+type zoneFile struct {
+	nameserver string
+	hostmaster string
+}
+
+func (f zoneFile) key(origin string) string {
+	return "db." + strings.TrimSuffix(origin, ".")
+}
+
+func (f zoneFile) parse(origin, content string) ([]dns.RR, error) {
+	var rrs []dns.RR
+
+	zp := dns.NewZoneParser(strings.NewReader(content), origin, "")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+
+	return rrs, zp.Err()
+}
+
+// Render the zone with the SOA first, as required by the file plugin. A SOA and an NS record are created for
+// zones that don't have them yet.
+func (f zoneFile) render(origin string, rrs []dns.RR) string {
+	var soa *dns.SOA
+	var records []dns.RR
+	hasNS := false
+
+	for _, rr := range rrs {
+		switch {
+		case rr.Header().Rrtype == dns.TypeSOA:
+			soa = rr.(*dns.SOA)
+			continue
+		case rr.Header().Rrtype == dns.TypeNS && strings.EqualFold(rr.Header().Name, origin):
+			hasNS = true
+		}
+
+		records = append(records, rr)
+	}
+
+	nameserver := f.nameserver
+	if nameserver == "" {
+		nameserver = "ns.dns." + origin
+	}
+
+	if soa == nil {
+		hostmaster := f.hostmaster
+		if hostmaster == "" {
+			hostmaster = "hostmaster." + origin
+		}
+
+		soa = &dns.SOA{
+			Hdr:     dns.RR_Header{Name: origin, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: kSOATTL},
+			Ns:      nameserver,
+			Mbox:    hostmaster,
+			Refresh: kSOARefresh,
+			Retry:   kSOARetry,
+			Expire:  kSOAExpire,
+			Minttl:  kSOAMinTTL,
+		}
+	}
+
+	if !hasNS {
+		records = append(records, &dns.NS{
+			Hdr: dns.RR_Header{Name: origin, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: kSOATTL},
+			Ns:  nameserver,
+		})
+	}
+
+	soa.Serial = nextSerial(soa.Serial, time.Now())
+	sortRecords(records)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "$ORIGIN %s\n", origin)
+	fmt.Fprintln(&b, soa.String())
+	for _, rr := range records {
+		fmt.Fprintln(&b, rr.String())
+	}
+
+	return b.String()
+}
+
+func (f zoneFile) supports(rrtype uint16) bool {
+	return rrtype != dns.TypeSOA
+}
+
+// hostsFile is the /etc/hosts format read by CoreDNS' hosts plugin. It only supports A and AAAA records
+// and doesn't store TTLs, the plugin's `ttl` option is used instead.
+type hostsFile struct{}
+
+func (f hostsFile) key(origin string) string {
+	return "hosts." + strings.TrimSuffix(origin, ".")
+}
+
+func (f hostsFile) parse(origin, content string) ([]dns.RR, error) {
+	var rrs []dns.RR
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		if ip == nil {
+			return nil, fmt.Errorf("invalid address (%s) in hosts file", fields[0])
+		}
+
+		for _, name := range fields[1:] {
+			hdr := dns.RR_Header{Name: dns.CanonicalName(name), Class: dns.ClassINET}
+			if ip.To4() != nil {
+				hdr.Rrtype = dns.TypeA
+				rrs = append(rrs, &dns.A{Hdr: hdr, A: ip})
+			} else {
+				hdr.Rrtype = dns.TypeAAAA
+				rrs = append(rrs, &dns.AAAA{Hdr: hdr, AAAA: ip})
+			}
+		}
+	}
+
+	return rrs, scanner.Err()
+}
+
+func (f hostsFile) render(origin string, rrs []dns.RR) string {
+	sortRecords(rrs)
+
+	var b strings.Builder
+	for _, rr := range rrs {
+		name := strings.TrimSuffix(rr.Header().Name, ".")
+		switch rr := rr.(type) {
+		case *dns.A:
+			fmt.Fprintf(&b, "%s %s\n", rr.A, name)
+		case *dns.AAAA:
+			fmt.Fprintf(&b, "%s %s\n", rr.AAAA, name)
+		}
+	}
+
+	return b.String()
+}
+
+func (f hostsFile) supports(rrtype uint16) bool {
+	return rrtype == dns.TypeA || rrtype == dns.TypeAAAA
+}
+
+// Return the serial that follows the current one. Serials use the YYYYMMDDnn convention, so the serial jumps to
+// the current date when it's behind and is incremented otherwise.
+func nextSerial(current uint32, now time.Time) uint32 {
+	today, _ := strconv.ParseUint(now.UTC().Format("20060102")+"00", 10, 32)
+	return max(current+1, uint32(today))
+}
+
+// Sort records by name and type so the rendered zone is stable and easy to diff.
+func sortRecords(rrs []dns.RR) {
+	sort.SliceStable(rrs, func(i, j int) bool {
+		a, b := rrs[i].Header(), rrs[j].Header()
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+
+		return a.Rrtype < b.Rrtype
+	})
+}
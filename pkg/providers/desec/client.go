@@ -2,11 +2,14 @@ package desec
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/nrdcg/desec"
 	"github.com/pier-oliviert/konditionner/pkg/konditions"
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
 	utils "github.com/pier-oliviert/phonebook/pkg/utils"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -23,11 +26,17 @@ type deSEC struct {
 	zones       []string
 }
 
+func init() {
+	providers.Register("desec", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
+}
+
 // NewClient initializes a deSEC DNS client
 func NewClient(ctx context.Context) (*deSEC, error) {
 	logger := log.FromContext(ctx)
 
-	token, err := utils.RetrieveValueFromEnvOrFile(kDesecToken)
+	token, err := utils.RetrieveValue(ctx, kDesecToken)
 	if err != nil {
 		return nil, fmt.Errorf("PB-DESEC-#0001: deSEC Token not found -- %w", err)
 	}
@@ -56,6 +65,15 @@ func (d *deSEC) Zones() []string {
 	return d.zones
 }
 
+// Check that the domain exists in the account the token belongs to.
+func (d *deSEC) Check(ctx context.Context, zone string) error {
+	if _, err := d.client.Domains.Get(ctx, zone); err != nil {
+		return fmt.Errorf("PB-DESEC-#0006: Domain %s could not be retrieved -- %w", zone, err)
+	}
+
+	return nil
+}
+
 // Create DNS record in deSEC
 func (d *deSEC) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
 	logger := log.FromContext(ctx)
@@ -72,13 +90,13 @@ func (d *deSEC) Create(ctx context.Context, record phonebook.DNSRecord, su phone
 		SubName: record.Spec.Name,
 		Type:    record.Spec.RecordType,
 		TTL:     int(ttl),
-		Records: record.Spec.Targets,
+		Records: records(record),
 	}
 
 	// Create the RRSet
 	_, err := d.client.Records.Create(ctx, rrset)
 	if err != nil {
-		return fmt.Errorf("PB-DESEC-#0002: Unable to create record -- %w", err)
+		return classify(fmt.Errorf("PB-DESEC-#0002: Unable to create record -- %w", err))
 	}
 
 	logger.Info("[Provider] deSEC Record Created")
@@ -87,6 +105,50 @@ func (d *deSEC) Create(ctx context.Context, record phonebook.DNSRecord, su phone
 	return nil
 }
 
+// Update DNS record in deSEC by replacing the RRSet's records and TTL
+func (d *deSEC) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	logger := log.FromContext(ctx)
+
+	ttl := defaultTTL
+	if record.Spec.TTL != nil {
+		ttl = *record.Spec.TTL
+	}
+
+	rrset := desec.RRSet{
+		TTL:     int(ttl),
+		Records: records(record),
+	}
+
+	_, err := d.client.Records.Update(ctx, record.Spec.Zone, record.Spec.Name, record.Spec.RecordType, rrset)
+	if err != nil {
+		return classify(fmt.Errorf("PB-DESEC-#0004: Unable to update record -- %w", err))
+	}
+
+	logger.Info("[Provider] deSEC Record Updated")
+	su.StageCondition(konditions.ConditionCreated, "deSEC record updated")
+
+	return nil
+}
+
+// Read the RRSet from deSEC
+func (d *deSEC) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	rrset, err := d.client.Records.Get(ctx, record.Spec.Zone, record.Spec.Name, record.Spec.RecordType)
+	if err != nil {
+		var notFound *desec.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, providers.ErrRecordNotFound
+		}
+
+		return nil, classify(fmt.Errorf("PB-DESEC-#0005: Unable to read record -- %w", err))
+	}
+
+	ttl := int64(rrset.TTL)
+	return &providers.RemoteRecord{
+		Targets: rrset.Records,
+		TTL:     &ttl,
+	}, nil
+}
+
 // Delete DNS record in deSEC
 func (d *deSEC) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
 	logger := log.FromContext(ctx)
@@ -94,7 +156,7 @@ func (d *deSEC) Delete(ctx context.Context, record phonebook.DNSRecord, su phone
 	// Delete the RRSet
 	err := d.client.Records.Delete(ctx, record.Spec.Zone, record.Spec.Name, record.Spec.RecordType)
 	if err != nil {
-		return fmt.Errorf("PB-DESEC-#0003: Unable to delete record -- %w", err)
+		return classify(fmt.Errorf("PB-DESEC-#0003: Unable to delete record -- %w", err))
 	}
 
 	logger.Info("[Provider] deSEC Record Deleted")
@@ -103,3 +165,36 @@ func (d *deSEC) Delete(ctx context.Context, record phonebook.DNSRecord, su phone
 
 	return nil
 }
+
+// deSEC expects the records in the same format as a zone file, which means TXT records
+// need to be quoted. Targets that are already quoted are left as is.
+func records(record phonebook.DNSRecord) []string {
+	if record.Spec.RecordType != "TXT" {
+		return record.Spec.Targets
+	}
+
+	values := make([]string, len(record.Spec.Targets))
+	for i, target := range record.Spec.Targets {
+		if strings.HasPrefix(target, "\"") && strings.HasSuffix(target, "\"") {
+			values[i] = target
+		} else {
+			values[i] = fmt.Sprintf("\"%s\"", target)
+		}
+	}
+
+	return values
+}
+
+// classify wraps err with its kind based on the status code returned by deSEC. The client already
+// retries rate limited and failed requests, and only tells that it gave up once it runs out of retries.
+func classify(err error) error {
+	var apiErr *desec.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return providers.FromHTTPStatus(err, apiErr.StatusCode, "")
+	case strings.Contains(err.Error(), "giving up after"):
+		return providers.Transient(err)
+	}
+
+	return err
+}
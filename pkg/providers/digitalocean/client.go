@@ -0,0 +1,333 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	utils "github.com/pier-oliviert/phonebook/pkg/utils"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	kDigitalOceanToken = "DO_TOKEN"
+	defaultTTL         = int64(1800) // Default TTL for DNS records in seconds if not specified, same as DigitalOcean's
+)
+
+type digitalOcean struct {
+	integration string
+	zones       []string
+
+	domains godo.DomainsService
+}
+
+func init() {
+	providers.Register("digitalocean", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
+}
+
+// NewClient creates a provider for DigitalOcean's domains. The token needs to have read
+// and write access to the domains.
+func NewClient(ctx context.Context) (*digitalOcean, error) {
+	token, err := utils.RetrieveValue(ctx, kDigitalOceanToken)
+	if err != nil {
+		return nil, fmt.Errorf("PB-DO-#0001: API Token not found -- %w", err)
+	}
+
+	client := godo.NewFromToken(strings.TrimSpace(token))
+
+	return &digitalOcean{
+		domains: client.Domains,
+	}, nil
+}
+
+// Configure makes sure each of the zones exists as a domain in the account.
+func (d *digitalOcean) Configure(ctx context.Context, integration string, zones []string) error {
+	for _, zone := range zones {
+		if err := d.Check(ctx, zone); err != nil {
+			return err
+		}
+	}
+
+	d.integration = integration
+	d.zones = zones
+
+	log.FromContext(ctx).Info("[Provider] DigitalOcean configured", "Domains", zones)
+	return nil
+}
+
+func (d *digitalOcean) Zones() []string {
+	return d.zones
+}
+
+// Check that the domain can still be retrieved with the token.
+func (d *digitalOcean) Check(ctx context.Context, zone string) error {
+	if _, _, err := d.domains.Get(ctx, zone); err != nil {
+		return fmt.Errorf("PB-DO-#0002: Domain not found for %s -- %w", zone, classify(err))
+	}
+
+	return nil
+}
+
+// Create one DigitalOcean record for each of the record's targets. The IDs of the records are kept in the
+// RemoteInfo so they can be edited and deleted later. When a previous attempt created some of the records, they are
+// edited instead of being created again.
+func (d *digitalOcean) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	requests, err := editRequests(&record)
+	if err != nil {
+		return err
+	}
+
+	existing, err := d.existing(ctx, &record)
+	if err != nil {
+		return classify(fmt.Errorf("PB-DO-#0004: Failed to create DNS record -- %w", err))
+	}
+
+	if err := d.recordSet(ctx, &record).Sync(su, existing, requests); err != nil {
+		return classify(fmt.Errorf("PB-DO-#0004: Failed to create DNS record -- %w", err))
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "DigitalOcean records created")
+	return nil
+}
+
+// Update the records that were created for each target. Records are created or deleted when
+// targets were added or removed.
+func (d *digitalOcean) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	requests, err := editRequests(&record)
+	if err != nil {
+		return err
+	}
+
+	existing, err := d.existing(ctx, &record)
+	if err != nil {
+		return classify(fmt.Errorf("PB-DO-#0005: Failed to update DNS record -- %w", err))
+	}
+
+	if err := d.recordSet(ctx, &record).Sync(su, existing, requests); err != nil {
+		return classify(fmt.Errorf("PB-DO-#0005: Failed to update DNS record -- %w", err))
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "DigitalOcean records updated")
+	return nil
+}
+
+// Delete all the records that were created for the DNSRecord.
+func (d *digitalOcean) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	ids, err := d.existing(ctx, &record)
+	if err != nil {
+		return classify(fmt.Errorf("PB-DO-#0006: Failed to delete DNS record -- %w", err))
+	}
+
+	if err := d.recordSet(ctx, &record).DeleteAll(su, ids); err != nil {
+		return classify(fmt.Errorf("PB-DO-#0006: Failed to delete DNS record -- %w", err))
+	}
+
+	su.StageCondition(konditions.ConditionTerminated, "DigitalOcean records deleted")
+	return nil
+}
+
+// Read the records that have the same name and type as the DNSRecord. Each of them is converted back
+// to the target it was created from.
+func (d *digitalOcean) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	records, _, err := d.domains.RecordsByTypeAndName(ctx, record.Spec.Zone, record.Spec.RecordType, fqdn(&record), &godo.ListOptions{PerPage: 200})
+	if err != nil {
+		return nil, classify(fmt.Errorf("PB-DO-#0007: Failed to read DNS record -- %w", err))
+	}
+
+	if len(records) == 0 {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	remote := &providers.RemoteRecord{}
+	for _, r := range records {
+		ttl := int64(r.TTL)
+		remote.TTL = &ttl
+		remote.Targets = append(remote.Targets, target(r))
+	}
+
+	return remote, nil
+}
+
+// recordSet creates, edits and deletes the records of the DNSRecord's domain. DigitalOcean's record IDs are integers.
+func (d *digitalOcean) recordSet(ctx context.Context, record *phonebook.DNSRecord) providers.RecordSet[*godo.DomainRecordEditRequest] {
+	return providers.RecordSet[*godo.DomainRecordEditRequest]{
+		Create: func(req *godo.DomainRecordEditRequest) (string, error) {
+			created, _, err := d.domains.CreateRecord(ctx, record.Spec.Zone, req)
+			if err != nil {
+				return "", err
+			}
+
+			return strconv.Itoa(created.ID), nil
+		},
+		Update: func(id string, req *godo.DomainRecordEditRequest) error {
+			recordID, err := strconv.Atoi(id)
+			if err != nil {
+				return err
+			}
+
+			_, _, err = d.domains.EditRecord(ctx, record.Spec.Zone, recordID, req)
+			return err
+		},
+		Delete: func(id string) error {
+			recordID, err := strconv.Atoi(id)
+			if err != nil {
+				return err
+			}
+
+			resp, err := d.domains.DeleteRecord(ctx, record.Spec.Zone, recordID)
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				// The record was already deleted.
+				return nil
+			}
+
+			return err
+		},
+	}
+}
+
+// existing returns the IDs of the records created for the DNSRecord. If none were stored in the RemoteInfo,
+// the records with the same name and type are looked up and only the ones whose data is one of the targets are
+// used, the others weren't created by Phonebook.
+func (d *digitalOcean) existing(ctx context.Context, record *phonebook.DNSRecord) ([]string, error) {
+	if ids := providers.RecordIDs(record, d.integration); len(ids) != 0 {
+		return ids, nil
+	}
+
+	records, _, err := d.domains.RecordsByTypeAndName(ctx, record.Spec.Zone, record.Spec.RecordType, fqdn(record), &godo.ListOptions{PerPage: 200})
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, r := range records {
+		if providers.MatchesTarget(record.Spec, target(r)) {
+			ids = append(ids, strconv.Itoa(r.ID))
+		}
+	}
+
+	return ids, nil
+}
+
+// Convert each of the record's targets to a DigitalOcean record. Targets are written the way they would
+// be in a zone file, so the fields of MX, SRV and CAA targets are parsed into the record's fields.
+func editRequests(record *phonebook.DNSRecord) ([]*godo.DomainRecordEditRequest, error) {
+	ttl := defaultTTL
+	if record.Spec.TTL != nil {
+		ttl = *record.Spec.TTL
+	}
+
+	name := record.Spec.Name
+	if name == "" {
+		name = "@"
+	}
+
+	var requests []*godo.DomainRecordEditRequest
+	for _, target := range record.Spec.Targets {
+		req := &godo.DomainRecordEditRequest{
+			Type: record.Spec.RecordType,
+			Name: name,
+			Data: target,
+			TTL:  int(ttl),
+		}
+
+		fields := strings.Fields(target)
+		invalid := providers.Permanent(fmt.Errorf("PB-DO-#0003: Invalid target (%s) for %s record", target, record.Spec.RecordType))
+
+		var err error
+		switch record.Spec.RecordType {
+		case "CNAME", "NS":
+			req.Data = hostname(target)
+
+		case "MX":
+			// priority host
+			if len(fields) != 2 {
+				return nil, invalid
+			}
+
+			req.Priority, err = strconv.Atoi(fields[0])
+			req.Data = hostname(fields[1])
+
+		case "SRV":
+			// priority weight port host
+			if len(fields) != 4 {
+				return nil, invalid
+			}
+
+			var errs [3]error
+			req.Priority, errs[0] = strconv.Atoi(fields[0])
+			req.Weight, errs[1] = strconv.Atoi(fields[1])
+			req.Port, errs[2] = strconv.Atoi(fields[2])
+			req.Data = hostname(fields[3])
+			err = errors.Join(errs[:]...)
+
+		case "CAA":
+			// flags tag "value"
+			if len(fields) < 3 {
+				return nil, invalid
+			}
+
+			req.Flags, err = strconv.Atoi(fields[0])
+			req.Tag = fields[1]
+			req.Data = strings.Trim(strings.Join(fields[2:], " "), "\"")
+		}
+
+		if err != nil {
+			return nil, invalid
+		}
+
+		requests = append(requests, req)
+	}
+
+	return requests, nil
+}
+
+// Convert a DigitalOcean record back to a target.
+func target(r godo.DomainRecord) string {
+	switch r.Type {
+	case "MX":
+		return fmt.Sprintf("%d %s", r.Priority, r.Data)
+	case "SRV":
+		return fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, r.Data)
+	case "CAA":
+		return fmt.Sprintf("%d %s \"%s\"", r.Flags, r.Tag, r.Data)
+	}
+
+	return r.Data
+}
+
+// Name of the record as expected by DigitalOcean when filtering records, without the trailing dot.
+func fqdn(record *phonebook.DNSRecord) string {
+	if record.Spec.Name == "" || record.Spec.Name == "@" {
+		return record.Spec.Zone
+	}
+
+	return fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone)
+}
+
+// DigitalOcean expects hostnames in the data of a record to be fully qualified.
+func hostname(name string) string {
+	if name == "@" || strings.HasSuffix(name, ".") {
+		return name
+	}
+
+	return name + "."
+}
+
+// classify wraps err with its kind based on the status code returned by DigitalOcean's API.
+func classify(err error) error {
+	var respErr *godo.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil {
+		return providers.FromHTTPStatus(err, respErr.Response.StatusCode, respErr.Response.Header.Get("Retry-After"))
+	}
+
+	return err
+}
@@ -0,0 +1,308 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"github.com/pier-oliviert/phonebook/pkg/providers/conformance"
+)
+
+// fakeDigitalOcean mimics the subset of DigitalOcean's domains API used by the provider.
+type fakeDigitalOcean struct {
+	mu      sync.Mutex
+	domains map[string]map[int]godo.DomainRecord
+	nextID  int
+	status  int
+
+	// When set, the API fails with a 503 once that many records were created.
+	quota int
+}
+
+var supportedTypes = map[string]bool{"A": true, "AAAA": true, "CAA": true, "CNAME": true, "MX": true, "NS": true, "SRV": true, "TXT": true}
+
+func (f *fakeDigitalOcean) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fail := func(status int, message string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"id": strings.ToLower(http.StatusText(status)), "message": message})
+	}
+
+	if f.status != 0 {
+		fail(f.status, http.StatusText(f.status))
+		return
+	}
+
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/v2/domains/"), "/")
+	records, ok := f.domains[segments[0]]
+	if !ok {
+		fail(http.StatusNotFound, "The resource you were accessing could not be found.")
+		return
+	}
+
+	switch {
+	case len(segments) == 1:
+		json.NewEncoder(w).Encode(map[string]any{"domain": godo.Domain{Name: segments[0]}})
+
+	case len(segments) == 2 && r.Method == http.MethodGet:
+		found := []godo.DomainRecord{}
+		for _, record := range records {
+			name := record.Name + "." + segments[0]
+			if record.Name == "@" {
+				name = segments[0]
+			}
+
+			if record.Type == r.URL.Query().Get("type") && name == r.URL.Query().Get("name") {
+				found = append(found, record)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"domain_records": found, "meta": map[string]int{"total": len(found)}})
+
+	case len(segments) == 2 && r.Method == http.MethodPost:
+		if f.quota != 0 && f.nextID >= f.quota {
+			fail(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+			return
+		}
+
+		var record godo.DomainRecord
+		json.NewDecoder(r.Body).Decode(&record)
+
+		if !supportedTypes[record.Type] {
+			fail(http.StatusUnprocessableEntity, "Record type is invalid.")
+			return
+		}
+
+		f.nextID++
+		record.ID = f.nextID
+		records[record.ID] = record
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"domain_record": record})
+
+	case len(segments) == 3:
+		id, _ := strconv.Atoi(segments[2])
+		if _, ok := records[id]; !ok {
+			fail(http.StatusNotFound, "The resource you were accessing could not be found.")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			var record godo.DomainRecord
+			json.NewDecoder(r.Body).Decode(&record)
+
+			record.ID = id
+			records[id] = record
+			json.NewEncoder(w).Encode(map[string]any{"domain_record": record})
+
+		case http.MethodDelete:
+			delete(records, id)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+func newTestClient(t *testing.T, fake *fakeDigitalOcean) *digitalOcean {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client, err := godo.New(server.Client(), godo.SetBaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &digitalOcean{domains: client.Domains}
+	if err := d.Configure(context.TODO(), "digitalocean-test", []string{"mydomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func TestNewClient(t *testing.T) {
+	t.Setenv(kDigitalOceanToken, "")
+	if _, err := NewClient(context.TODO()); err == nil || !strings.HasPrefix(err.Error(), "PB-DO-#0001") {
+		t.Errorf("Expected a missing token to fail, got: %v", err)
+	}
+
+	t.Setenv(kDigitalOceanToken, "token")
+	if _, err := NewClient(context.TODO()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	fake := &fakeDigitalOcean{domains: map[string]map[int]godo.DomainRecord{"mydomain.com": {}}}
+	d := newTestClient(t, fake)
+
+	err := d.Configure(context.TODO(), "digitalocean-test", []string{"unknown.com"})
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-DO-#0002") {
+		t.Errorf("Expected an error for a domain that doesn't exist, got: %v", err)
+	}
+
+	delete(fake.domains, "mydomain.com")
+	if err := d.Check(context.TODO(), "mydomain.com"); err == nil || !strings.HasPrefix(err.Error(), "PB-DO-#0002") {
+		t.Errorf("Expected the check to fail for a domain that was deleted, got: %v", err)
+	}
+}
+
+func TestDeleteWithoutRecordIDs(t *testing.T) {
+	fake := &fakeDigitalOcean{domains: map[string]map[int]godo.DomainRecord{"mydomain.com": {
+		7: {ID: 7, Type: "TXT", Name: "@", Data: "some value"},
+		8: {ID: 8, Type: "TXT", Name: "other", Data: "other value"},
+		9: {ID: 9, Type: "TXT", Name: "@", Data: "created by hand"},
+	}}}
+	d := newTestClient(t, fake)
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			RecordType: "TXT",
+			Targets:    []string{"some value"},
+		},
+	}
+
+	if err := d.Delete(context.TODO(), record, &mocks.Updater{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fake.domains["mydomain.com"][7]; ok {
+		t.Error("Expected the record to be found by name and deleted")
+	}
+
+	if _, ok := fake.domains["mydomain.com"][8]; !ok {
+		t.Error("Expected records with a different name to be kept")
+	}
+
+	if _, ok := fake.domains["mydomain.com"][9]; !ok {
+		t.Error("Expected records with a value that isn't one of the targets to be kept")
+	}
+}
+
+func TestEditRequests(t *testing.T) {
+	tests := []struct {
+		recordType string
+		target     string
+		expected   godo.DomainRecordEditRequest
+	}{
+		{recordType: "CNAME", target: "mydomain.com", expected: godo.DomainRecordEditRequest{Data: "mydomain.com."}},
+		{recordType: "MX", target: "10 mail.mydomain.com", expected: godo.DomainRecordEditRequest{Priority: 10, Data: "mail.mydomain.com."}},
+		{recordType: "SRV", target: "10 5 5060 sip.mydomain.com.", expected: godo.DomainRecordEditRequest{Priority: 10, Weight: 5, Port: 5060, Data: "sip.mydomain.com."}},
+		{recordType: "CAA", target: "0 issue \"letsencrypt.org\"", expected: godo.DomainRecordEditRequest{Flags: 0, Tag: "issue", Data: "letsencrypt.org"}},
+		{recordType: "TXT", target: "some value", expected: godo.DomainRecordEditRequest{Data: "some value"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.recordType, func(t *testing.T) {
+			record := &phonebook.DNSRecord{
+				Spec: phonebook.DNSRecordSpec{
+					Zone:       "mydomain.com",
+					Name:       "record",
+					RecordType: tt.recordType,
+					Targets:    []string{tt.target},
+				},
+			}
+
+			requests, err := editRequests(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.expected.Type = tt.recordType
+			tt.expected.Name = "record"
+			tt.expected.TTL = int(defaultTTL)
+			if *requests[0] != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, *requests[0])
+			}
+
+		})
+	}
+
+	for _, invalid := range []struct{ recordType, target string }{{"MX", "mail.mydomain.com"}, {"SRV", "10 x 5060 sip.mydomain.com"}, {"CAA", "issue"}} {
+		_, err := editRequests(&phonebook.DNSRecord{Spec: phonebook.DNSRecordSpec{RecordType: invalid.recordType, Targets: []string{invalid.target}}})
+		if err == nil || !strings.HasPrefix(err.Error(), "PB-DO-#0003") || providers.Retryable(err) {
+			t.Errorf("Expected %s to be an invalid %s target, got: %v", invalid.target, invalid.recordType, err)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	fake := &fakeDigitalOcean{domains: map[string]map[int]godo.DomainRecord{"mydomain.com": {}}}
+	d := newTestClient(t, fake)
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+
+	fake.status = http.StatusTooManyRequests
+	err := d.Create(context.TODO(), record, &mocks.Updater{})
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-DO-#0004") || providers.Kind(err) != providers.ErrorRateLimited {
+		t.Errorf("Expected a rate limited error, got: %v", err)
+	}
+
+	fake.status = http.StatusUnprocessableEntity
+	err = d.Create(context.TODO(), record, &mocks.Updater{})
+	if err == nil || providers.Retryable(err) {
+		t.Errorf("Expected a permanent error, got: %v", err)
+	}
+}
+
+func TestCreatePartialFailure(t *testing.T) {
+	fake := &fakeDigitalOcean{domains: map[string]map[int]godo.DomainRecord{"mydomain.com": {}}, quota: 1}
+	d := newTestClient(t, fake)
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1", "127.0.0.2"},
+		},
+	}
+
+	updater := &mocks.Updater{}
+	if err := d.Create(context.TODO(), record, updater); err == nil || !providers.Retryable(err) {
+		t.Fatalf("Expected a retryable error, got: %v", err)
+	}
+
+	if updater.Info["recordIDs"] != "1" {
+		t.Fatalf("Expected the record created before the failure to be staged, got: %v", updater.Info)
+	}
+
+	// The retry starts from the records that were created by the failed attempt.
+	fake.quota = 0
+	record.Status.RemoteInfo = map[string]phonebook.IntegrationInfo{"digitalocean-test": updater.Info}
+	if err := d.Create(context.TODO(), record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	if updater.Info["recordIDs"] != "1,2" || len(fake.domains["mydomain.com"]) != 2 {
+		t.Errorf("Expected one record per target, got: %v, %v", updater.Info, fake.domains["mydomain.com"])
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, conformance.Suite{
+		Zone: "mydomain.com",
+		NewProvider: func(t *testing.T) providers.Provider {
+			return newTestClient(t, &fakeDigitalOcean{domains: map[string]map[int]godo.DomainRecord{"mydomain.com": {}}})
+		},
+	})
+}
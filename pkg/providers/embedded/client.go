@@ -0,0 +1,391 @@
+package embedded
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	utils "github.com/pier-oliviert/phonebook/pkg/utils"
+)
+
+const (
+	kEmbeddedListenAddress = "EMBEDDED_LISTEN_ADDRESS"
+	kEmbeddedNameservers   = "EMBEDDED_NAMESERVERS"
+	kEmbeddedHostmaster    = "EMBEDDED_HOSTMASTER"
+	kEmbeddedTransferAllow = "EMBEDDED_TRANSFER_ALLOW"
+	kEmbeddedRefresh       = "EMBEDDED_REFRESH_INTERVAL"
+	defaultListenAddress   = ":1053"
+	defaultRefresh         = 10 * time.Second
+	defaultTTL             = int64(300) // Default TTL for DNS records in seconds if not specified
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(phonebook.AddToScheme(scheme))
+}
+
+// embedded is a provider that serves its zones itself. Records are kept in memory and answered
+// authoritatively over UDP and TCP. The memory is rebuilt from the DNSRecords stored in Kubernetes when
+// the provider is configured, so nothing is lost when the provider restarts.
+//
+// Only the leader reconciles DNSRecords, but every replica answers queries. The DNSRecords are listed again at every
+// refresh interval so the replicas that aren't the leader serve the records the leader created.
+type embedded struct {
+	integration string
+	names       []string
+
+	// Zones indexed by their canonical name (ie. mydomain.com.)
+	mu    sync.RWMutex
+	zones map[string]*zone
+
+	// Kubernetes client used to list the DNSRecords when the provider starts, and at every refresh.
+	reader  client.Reader
+	refresh time.Duration
+
+	address     string
+	nameservers []string
+	hostmaster  string
+
+	// Networks allowed to transfer zones (AXFR). Transfers are refused when it's empty.
+	transferAllow []*net.IPNet
+
+	servers []*dns.Server
+}
+
+func init() {
+	providers.Register("embedded", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
+}
+
+// NewClient creates a provider that runs an authoritative nameserver for its zones. The nameserver
+// listens on EMBEDDED_LISTEN_ADDRESS, for both UDP and TCP.
+func NewClient(ctx context.Context) (*embedded, error) {
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("PB-EMB-#0001: Unable to create Kubernetes client -- %w", err)
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("PB-EMB-#0001: Unable to create Kubernetes client -- %w", err)
+	}
+
+	return newEmbedded(ctx, c)
+}
+
+func newEmbedded(ctx context.Context, reader client.Reader) (*embedded, error) {
+	e := &embedded{
+		reader:  reader,
+		refresh: defaultRefresh,
+		zones:   map[string]*zone{},
+		address: defaultListenAddress,
+	}
+
+	if address, _ := utils.RetrieveValue(ctx, kEmbeddedListenAddress); strings.TrimSpace(address) != "" {
+		e.address = strings.TrimSpace(address)
+	}
+
+	nameservers, _ := utils.RetrieveValue(ctx, kEmbeddedNameservers)
+	for _, ns := range strings.Split(nameservers, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			e.nameservers = append(e.nameservers, dns.Fqdn(ns))
+		}
+	}
+
+	if hostmaster, _ := utils.RetrieveValue(ctx, kEmbeddedHostmaster); strings.TrimSpace(hostmaster) != "" {
+		// The mailbox is written as a name in the SOA record, ie. hostmaster@mydomain.com is hostmaster.mydomain.com.
+		e.hostmaster = dns.Fqdn(strings.Replace(strings.TrimSpace(hostmaster), "@", ".", 1))
+	}
+
+	if refresh, _ := utils.RetrieveValue(ctx, kEmbeddedRefresh); strings.TrimSpace(refresh) != "" {
+		interval, err := time.ParseDuration(strings.TrimSpace(refresh))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("PB-EMB-#0002: Invalid duration (%s) in %s", refresh, kEmbeddedRefresh)
+		}
+
+		e.refresh = interval
+	}
+
+	allow, _ := utils.RetrieveValue(ctx, kEmbeddedTransferAllow)
+	for _, cidr := range strings.Split(allow, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("PB-EMB-#0002: Invalid network (%s) in %s -- %w", cidr, kEmbeddedTransferAllow, err)
+		}
+
+		e.transferAllow = append(e.transferAllow, network)
+	}
+
+	return e, nil
+}
+
+// Configure loads the records of each zone from Kubernetes and starts the nameserver. Only the records
+// this provider already created are loaded, the others are added as they get reconciled, or when the records
+// are loaded again at the next refresh.
+func (e *embedded) Configure(ctx context.Context, integration string, zones []string) error {
+	e.integration = integration
+	e.names = zones
+
+	for _, name := range zones {
+		origin := dns.CanonicalName(name)
+
+		nameservers := e.nameservers
+		if len(nameservers) == 0 {
+			nameservers = []string{"ns1." + origin}
+		}
+
+		hostmaster := e.hostmaster
+		if hostmaster == "" {
+			hostmaster = "hostmaster." + origin
+		}
+
+		e.zones[origin] = newZone(origin, nameservers, hostmaster)
+	}
+
+	if err := e.load(ctx); err != nil {
+		return err
+	}
+
+	if err := e.listen(); err != nil {
+		return err
+	}
+
+	go e.reload(ctx)
+
+	log.FromContext(ctx).Info("[Provider] Embedded nameserver configured", "Address", e.address, "Zones", zones, "Transfers", len(e.transferAllow) > 0)
+	return nil
+}
+
+func (e *embedded) Zones() []string {
+	return e.names
+}
+
+// Create adds the record to its zone. The zone's serial is bumped so secondaries transfer the zone again.
+func (e *embedded) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := e.apply(&record); err != nil {
+		return err
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "Record served by the embedded nameserver")
+	return nil
+}
+
+// Update replaces the record's resource records in its zone.
+func (e *embedded) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := e.apply(&record); err != nil {
+		return err
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "Record updated on the embedded nameserver")
+	return nil
+}
+
+// Delete removes the record from its zone.
+func (e *embedded) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	e.remove(&record)
+
+	su.StageCondition(konditions.ConditionTerminated, "Record removed from the embedded nameserver")
+	return nil
+}
+
+// Read returns the resource records the nameserver answers with for the record's name and type.
+func (e *embedded) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	rrtype, err := recordType(&record)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	z, ok := e.zones[dns.CanonicalName(record.Spec.Zone)]
+	if !ok {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	remote := &providers.RemoteRecord{}
+	for _, rr := range z.names[fqdn(&record)] {
+		if rr.Header().Rrtype != rrtype {
+			continue
+		}
+
+		ttl := int64(rr.Header().Ttl)
+		remote.TTL = &ttl
+		remote.Targets = append(remote.Targets, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+
+	if len(remote.Targets) == 0 {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	return remote, nil
+}
+
+func (e *embedded) remove(record *phonebook.DNSRecord) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if z, ok := e.zones[dns.CanonicalName(record.Spec.Zone)]; ok {
+		z.remove(key(record))
+	}
+}
+
+func (e *embedded) apply(record *phonebook.DNSRecord) error {
+	rrs, err := resourceRecords(record)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	z, ok := e.zones[dns.CanonicalName(record.Spec.Zone)]
+	if !ok {
+		return providers.Permanent(fmt.Errorf("PB-EMB-#0006: Zone %s is not served by this provider", record.Spec.Zone))
+	}
+
+	z.set(key(record), rrs)
+	return nil
+}
+
+// Load the DNSRecords this provider created. A record is loaded when its provider condition is Created, which
+// means it was created by this provider, on this replica or on the leader. Records are removed once their
+// condition is Terminated or once they're deleted. The records whose condition has any other status are being
+// reconciled by the leader and are left as they are.
+func (e *embedded) load(ctx context.Context) error {
+	var list phonebook.DNSRecordList
+	if err := e.reader.List(ctx, &list); err != nil {
+		return fmt.Errorf("PB-EMB-#0004: Unable to list the DNSRecords -- %w", err)
+	}
+
+	conditionType := konditions.ConditionType(fmt.Sprintf("provider.%s", e.integration))
+
+	found := map[string]bool{}
+	for i := range list.Items {
+		record := list.Items[i].Resolved()
+		found[key(&record)] = true
+
+		condition := record.Status.Conditions.FindType(conditionType)
+		if condition == nil {
+			continue
+		}
+
+		if _, ok := e.zones[dns.CanonicalName(record.Spec.Zone)]; !ok {
+			continue
+		}
+
+		switch {
+		case condition.Status == konditions.ConditionTerminated:
+			e.remove(&record)
+
+		case condition.Status == konditions.ConditionCreated && record.DeletionTimestamp.IsZero():
+			if err := e.apply(&record); err != nil {
+				// A record that can't be parsed would have failed when it was created, it's skipped so
+				// it doesn't prevent the other records from being served.
+				log.FromContext(ctx).Error(err, "Skipping record", "Record", key(&record))
+			}
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, z := range e.zones {
+		for k := range z.records {
+			if !found[k] {
+				z.remove(k)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Load the DNSRecords at every refresh interval until the context is done.
+func (e *embedded) reload(ctx context.Context) {
+	ticker := time.NewTicker(e.refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := e.load(ctx); err != nil {
+			log.FromContext(ctx).Error(err, "[Provider] Could not refresh the records")
+		}
+	}
+}
+
+// Convert the record's targets to resource records. The targets are parsed the same way they would be in a zone
+// file, so names in a target (ie. CNAME) are fully qualified and TXT values are quoted.
+func resourceRecords(record *phonebook.DNSRecord) ([]dns.RR, error) {
+	if _, err := recordType(record); err != nil {
+		return nil, err
+	}
+
+	ttl := defaultTTL
+	if record.Spec.TTL != nil {
+		ttl = *record.Spec.TTL
+	}
+
+	var rrs []dns.RR
+	for _, target := range record.Spec.Targets {
+		if strings.EqualFold(record.Spec.RecordType, "TXT") {
+			target = fmt.Sprintf("\"%s\"", strings.Trim(target, "\""))
+		}
+
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", fqdn(record), ttl, record.Spec.RecordType, target))
+		if err != nil || rr == nil {
+			return nil, providers.Permanent(fmt.Errorf("PB-EMB-#0005: Invalid target (%s) for %s record -- %v", target, record.Spec.RecordType, err))
+		}
+
+		rrs = append(rrs, rr)
+	}
+
+	return rrs, nil
+}
+
+func recordType(record *phonebook.DNSRecord) (uint16, error) {
+	rrtype, ok := dns.StringToType[strings.ToUpper(record.Spec.RecordType)]
+	if !ok || rrtype == dns.TypeSOA {
+		return 0, providers.Permanent(fmt.Errorf("PB-EMB-#0005: Unsupported record type %s", record.Spec.RecordType))
+	}
+
+	return rrtype, nil
+}
+
+// Records are stored by the DNSRecord they come from so updating one DNSRecord doesn't affect the
+// others with the same name.
+func key(record *phonebook.DNSRecord) string {
+	return fmt.Sprintf("%s/%s", record.Namespace, record.Name)
+}
+
+func fqdn(record *phonebook.DNSRecord) string {
+	if record.Spec.Name == "" || record.Spec.Name == "@" {
+		return dns.CanonicalName(record.Spec.Zone)
+	}
+
+	return dns.CanonicalName(fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone))
+}
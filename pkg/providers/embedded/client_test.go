@@ -0,0 +1,273 @@
+package embedded
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"github.com/pier-oliviert/phonebook/pkg/providers/conformance"
+)
+
+func newRecord(name, recordName, recordType string, targets ...string) *phonebook.DNSRecord {
+	return &phonebook.DNSRecord{
+		ObjectMeta: meta.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       recordName,
+			RecordType: recordType,
+			Targets:    targets,
+		},
+	}
+}
+
+// Create a provider for mydomain.com that listens on a random port. The records are stored in a fake
+// Kubernetes API and loaded when the provider is configured.
+func newTestProvider(t *testing.T, records ...client.Object) *embedded {
+	t.Setenv(kEmbeddedListenAddress, "127.0.0.1:0")
+	t.Setenv(kEmbeddedNameservers, "ns1.mydomain.com,ns2.mydomain.com")
+	t.Setenv(kEmbeddedHostmaster, "")
+	t.Setenv(kEmbeddedTransferAllow, "127.0.0.0/8")
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(records...).WithStatusSubresource(records...).Build()
+
+	e, err := newEmbedded(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Configure(context.TODO(), "embedded-test", []string{"mydomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		for _, server := range e.servers {
+			server.Shutdown()
+		}
+	})
+
+	return e
+}
+
+func TestLoadRecords(t *testing.T) {
+	created := newRecord("created", "www", "A", "127.0.0.1")
+	created.Status.Conditions.SetCondition(konditions.Condition{Type: "provider.embedded-test", Status: konditions.ConditionCreated})
+
+	pending := newRecord("pending", "pending", "A", "127.0.0.2")
+	pending.Status.Conditions.SetCondition(konditions.Condition{Type: "provider.embedded-test", Status: konditions.ConditionInitialized})
+
+	other := newRecord("other", "other", "A", "127.0.0.3")
+	other.Status.Conditions.SetCondition(konditions.Condition{Type: "provider.other", Status: konditions.ConditionCreated})
+
+	resolved := newRecord("resolved", "api.mydomain.com", "A", "127.0.0.4")
+	resolved.Spec.Zone = ""
+	resolved.Status.Zone = "mydomain.com"
+	resolved.Status.Name = "api"
+	resolved.Status.Conditions.SetCondition(konditions.Condition{Type: "provider.embedded-test", Status: konditions.ConditionCreated})
+
+	e := newTestProvider(t, created, pending, other, resolved)
+
+	if _, err := e.Read(context.TODO(), *created); err != nil {
+		t.Errorf("Expected the created record to be loaded, got: %v", err)
+	}
+
+	if _, err := e.Read(context.TODO(), resolved.Resolved()); err != nil {
+		t.Errorf("Expected the record to be loaded with its resolved name, got: %v", err)
+	}
+
+	for _, r := range []*phonebook.DNSRecord{pending, other} {
+		if _, err := e.Read(context.TODO(), *r); !errors.Is(err, providers.ErrRecordNotFound) {
+			t.Errorf("Expected %s to not be loaded, got: %v", r.Name, err)
+		}
+	}
+}
+
+func TestRefreshRecords(t *testing.T) {
+	// The provider runs on a replica that isn't the leader, the records are only created by the leader.
+	e := newTestProvider(t)
+	c := e.reader.(client.Client)
+
+	record := newRecord("www", "www", "A", "127.0.0.1")
+	record.Status.Conditions.SetCondition(konditions.Condition{Type: "provider.embedded-test", Status: konditions.ConditionCreated})
+	if err := c.Create(context.TODO(), record); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.load(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.Read(context.TODO(), *record); err != nil {
+		t.Fatalf("Expected the record created by the leader to be served, got: %v", err)
+	}
+
+	// Loading records that didn't change leaves the zone as is.
+	serial := e.zones["mydomain.com."].serial
+	if err := e.load(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	if e.zones["mydomain.com."].serial != serial {
+		t.Error("Expected the serial to stay the same when the records didn't change")
+	}
+
+	// A record being reconciled by the leader keeps being served.
+	record.Status.Conditions.SetCondition(konditions.Condition{Type: "provider.embedded-test", Status: konditions.ConditionLocked})
+	if err := c.Update(context.TODO(), record); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.load(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.Read(context.TODO(), *record); err != nil {
+		t.Errorf("Expected the record to still be served while it's reconciled, got: %v", err)
+	}
+
+	record.Status.Conditions.SetCondition(konditions.Condition{Type: "provider.embedded-test", Status: konditions.ConditionTerminated})
+	if err := c.Update(context.TODO(), record); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.load(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.Read(context.TODO(), *record); !errors.Is(err, providers.ErrRecordNotFound) {
+		t.Errorf("Expected the terminated record to be removed, got: %v", err)
+	}
+
+	// A record that was deleted is removed as well.
+	deleted := newRecord("api", "api", "A", "127.0.0.2")
+	deleted.Status.Conditions.SetCondition(konditions.Condition{Type: "provider.embedded-test", Status: konditions.ConditionCreated})
+	if err := c.Create(context.TODO(), deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.load(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Delete(context.TODO(), deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.load(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.Read(context.TODO(), *deleted); !errors.Is(err, providers.ErrRecordNotFound) {
+		t.Errorf("Expected the deleted record to be removed, got: %v", err)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	e := newTestProvider(t)
+	record := newRecord("www", "www", "A", "127.0.0.1", "127.0.0.2")
+
+	serial := e.zones["mydomain.com."].serial
+
+	updater := &mocks.Updater{}
+	if err := e.Create(context.TODO(), *record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	if *updater.Status != konditions.ConditionCreated {
+		t.Errorf("Expected the record to be created, got: %s", *updater.Status)
+	}
+
+	if e.zones["mydomain.com."].serial <= serial {
+		t.Errorf("Expected the serial to be bumped, got: %d", e.zones["mydomain.com."].serial)
+	}
+
+	record.Spec.Targets = []string{"127.0.0.3"}
+	if err := e.Update(context.TODO(), *record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	remote, err := e.Read(context.TODO(), *record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !remote.Matches(record.Spec) {
+		t.Errorf("Expected the remote record to match the spec, got: %v", remote.Targets)
+	}
+
+	if err := e.Delete(context.TODO(), *record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	if *updater.Status != konditions.ConditionTerminated {
+		t.Errorf("Expected the record to be deleted, got: %s", *updater.Status)
+	}
+
+	if _, err := e.Read(context.TODO(), *record); !errors.Is(err, providers.ErrRecordNotFound) {
+		t.Errorf("Expected the record to not be found, got: %v", err)
+	}
+}
+
+func TestInvalidRecord(t *testing.T) {
+	e := newTestProvider(t)
+
+	for _, record := range []*phonebook.DNSRecord{
+		newRecord("mx", "mail", "MX", "mail.mydomain.com"),
+		newRecord("soa", "", "SOA", "ns1.mydomain.com. hostmaster.mydomain.com. 1 2 3 4 5"),
+	} {
+		err := e.Create(context.TODO(), *record, &mocks.Updater{})
+		if err == nil || !strings.HasPrefix(err.Error(), "PB-EMB-#0005") || providers.Retryable(err) {
+			t.Errorf("Expected %s to be a permanent error, got: %v", record.Name, err)
+		}
+	}
+}
+
+func TestTransferAllowConfiguration(t *testing.T) {
+	t.Setenv(kEmbeddedTransferAllow, "10.0.0.0/8, not-a-network")
+
+	if _, err := newEmbedded(context.Background(), nil); err == nil || !strings.HasPrefix(err.Error(), "PB-EMB-#0002") {
+		t.Errorf("Expected an invalid network to fail, got: %v", err)
+	}
+}
+
+func TestRefreshConfiguration(t *testing.T) {
+	t.Setenv(kEmbeddedTransferAllow, "")
+
+	for _, value := range []string{"soon", "0s"} {
+		t.Setenv(kEmbeddedRefresh, value)
+
+		if _, err := newEmbedded(context.Background(), nil); err == nil || !strings.HasPrefix(err.Error(), "PB-EMB-#0002") {
+			t.Errorf("Expected %s to be an invalid refresh interval, got: %v", value, err)
+		}
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, conformance.Suite{
+		Zone: "mydomain.com",
+		NewProvider: func(t *testing.T) providers.Provider {
+			t.Setenv(kEmbeddedListenAddress, "127.0.0.1:0")
+
+			e, err := newEmbedded(context.Background(), fake.NewClientBuilder().WithScheme(scheme).Build())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Cleanup(func() {
+				for _, server := range e.servers {
+					server.Shutdown()
+				}
+			})
+
+			return e
+		},
+	})
+}
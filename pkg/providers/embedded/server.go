@@ -0,0 +1,134 @@
+package embedded
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Number of resource records sent in each message of a zone transfer.
+const kTransferBatchSize = 100
+
+// Start the nameserver on both UDP and TCP. The sockets are opened here so an address that's
+// already in use fails the configuration instead of failing in the background.
+func (e *embedded) listen() error {
+	pc, err := net.ListenPacket("udp", e.address)
+	if err != nil {
+		return fmt.Errorf("PB-EMB-#0003: Unable to listen on %s/udp -- %w", e.address, err)
+	}
+
+	l, err := net.Listen("tcp", e.address)
+	if err != nil {
+		pc.Close()
+		return fmt.Errorf("PB-EMB-#0003: Unable to listen on %s/tcp -- %w", e.address, err)
+	}
+
+	e.servers = []*dns.Server{
+		{PacketConn: pc, Handler: e},
+		{Listener: l, Handler: e},
+	}
+
+	for _, server := range e.servers {
+		go server.ActivateAndServe()
+	}
+
+	return nil
+}
+
+// ServeDNS answers queries for the zones served by the provider. Queries for any other zone are
+// refused since the server isn't a resolver.
+func (e *embedded) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	msg := new(dns.Msg)
+	msg.SetReply(req)
+
+	if req.Opcode != dns.OpcodeQuery || len(req.Question) != 1 {
+		msg.SetRcode(req, dns.RcodeNotImplemented)
+		w.WriteMsg(msg)
+		return
+	}
+
+	q := req.Question[0]
+	if q.Qtype == dns.TypeAXFR || q.Qtype == dns.TypeIXFR {
+		e.transfer(w, req)
+		return
+	}
+
+	e.mu.RLock()
+	z := e.zoneFor(q.Name)
+	if z != nil {
+		msg.Authoritative = true
+		z.answer(msg, q)
+	}
+	e.mu.RUnlock()
+
+	if z == nil {
+		msg.SetRcode(req, dns.RcodeRefused)
+	}
+
+	w.WriteMsg(msg)
+}
+
+// Send the whole zone to a secondary. Transfers are only allowed over TCP and from the networks set in
+// EMBEDDED_TRANSFER_ALLOW. IXFR requests get the whole zone as well, which RFC 1995 allows.
+func (e *embedded) transfer(w dns.ResponseWriter, req *dns.Msg) {
+	e.mu.RLock()
+	z := e.zoneFor(req.Question[0].Name)
+
+	var rrs []dns.RR
+	if z != nil && z.origin == strings.ToLower(req.Question[0].Name) {
+		rrs = z.transfer()
+	}
+	e.mu.RUnlock()
+
+	if rrs == nil || !e.transferAllowed(w) {
+		msg := new(dns.Msg)
+		msg.SetRcode(req, dns.RcodeRefused)
+		w.WriteMsg(msg)
+		return
+	}
+
+	ch := make(chan *dns.Envelope)
+	done := make(chan error)
+	go func() {
+		done <- new(dns.Transfer).Out(w, req, ch)
+	}()
+
+	for i := 0; i < len(rrs); i += kTransferBatchSize {
+		ch <- &dns.Envelope{RR: rrs[i:min(i+kTransferBatchSize, len(rrs))]}
+	}
+
+	close(ch)
+	<-done
+}
+
+func (e *embedded) transferAllowed(w dns.ResponseWriter) bool {
+	addr, ok := w.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+
+	for _, network := range e.transferAllow {
+		if network.Contains(addr.IP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Return the zone the name belongs to. When zones are nested (ie. mydomain.com and sub.mydomain.com), the
+// longest one wins.
+func (e *embedded) zoneFor(name string) *zone {
+	name = strings.ToLower(name)
+
+	var found *zone
+	for origin, z := range e.zones {
+		if (name == origin || strings.HasSuffix(name, "."+origin)) && (found == nil || len(origin) > len(found.origin)) {
+			found = z
+		}
+	}
+
+	return found
+}
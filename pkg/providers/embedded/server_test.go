@@ -0,0 +1,149 @@
+package embedded
+
+import (
+	"context"
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+)
+
+func query(t *testing.T, e *embedded, name string, qtype uint16) *dns.Msg {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+
+	response, _, err := new(dns.Client).Exchange(msg, e.servers[0].PacketConn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return response
+}
+
+func TestQueries(t *testing.T) {
+	e := newTestProvider(t)
+
+	for _, record := range []struct{ name, recordName, recordType, target string }{
+		{"www", "www", "A", "127.0.0.1"},
+		{"alias", "alias", "CNAME", "www.mydomain.com"},
+		{"deep", "a.b", "TXT", "deep value"},
+		{"wildcard", "*.apps", "A", "127.0.0.2"},
+		{"apex", "", "MX", "10 mail.mydomain.com"},
+	} {
+		if err := e.Create(context.TODO(), *newRecord(record.name, record.recordName, record.recordType, record.target), &mocks.Updater{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		qname    string
+		qtype    uint16
+		rcode    int
+		answer   string
+		negative bool
+	}{
+		{name: "Exact", qname: "www.mydomain.com.", qtype: dns.TypeA, answer: "127.0.0.1"},
+		{name: "Case insensitive", qname: "WWW.MyDomain.com.", qtype: dns.TypeA, answer: "127.0.0.1"},
+		{name: "CNAME", qname: "alias.mydomain.com.", qtype: dns.TypeA, answer: "www.mydomain.com."},
+		{name: "Wildcard", qname: "myapp.apps.mydomain.com.", qtype: dns.TypeA, answer: "127.0.0.2"},
+		{name: "Apex", qname: "mydomain.com.", qtype: dns.TypeMX, answer: "mail.mydomain.com."},
+		{name: "SOA", qname: "mydomain.com.", qtype: dns.TypeSOA, answer: "ns1.mydomain.com."},
+		{name: "NS", qname: "mydomain.com.", qtype: dns.TypeNS, answer: "ns1.mydomain.com."},
+		{name: "NODATA", qname: "www.mydomain.com.", qtype: dns.TypeAAAA, negative: true},
+		{name: "Empty non-terminal", qname: "b.mydomain.com.", qtype: dns.TypeA, negative: true},
+		{name: "NXDOMAIN", qname: "missing.mydomain.com.", qtype: dns.TypeA, rcode: dns.RcodeNameError, negative: true},
+		{name: "Other zone", qname: "otherdomain.com.", qtype: dns.TypeA, rcode: dns.RcodeRefused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := query(t, e, tt.qname, tt.qtype)
+
+			if response.Rcode != tt.rcode {
+				t.Fatalf("Expected rcode %s, got %s", dns.RcodeToString[tt.rcode], dns.RcodeToString[response.Rcode])
+			}
+
+			if tt.rcode != dns.RcodeRefused && !response.Authoritative {
+				t.Error("Expected an authoritative answer")
+			}
+
+			if tt.answer != "" {
+				if len(response.Answer) == 0 {
+					t.Fatal("Expected an answer")
+				}
+
+				rr := response.Answer[0]
+				if rr.Header().Name != tt.qname {
+					t.Errorf("Expected the answer's name to be %s, got %s", tt.qname, rr.Header().Name)
+				}
+
+				var value string
+				switch rr := rr.(type) {
+				case *dns.A:
+					value = rr.A.String()
+				case *dns.CNAME:
+					value = rr.Target
+				case *dns.MX:
+					value = rr.Mx
+				case *dns.SOA:
+					value = rr.Ns
+				case *dns.NS:
+					value = rr.Ns
+				}
+
+				if value != tt.answer {
+					t.Errorf("Expected %s, got %s", tt.answer, rr)
+				}
+			}
+
+			if tt.negative && (len(response.Answer) != 0 || len(response.Ns) != 1 || response.Ns[0].Header().Rrtype != dns.TypeSOA) {
+				t.Errorf("Expected a negative answer with the SOA, got: %v", response)
+			}
+		})
+	}
+}
+
+func TestZoneTransfer(t *testing.T) {
+	e := newTestProvider(t)
+	if err := e.Create(context.TODO(), *newRecord("www", "www", "A", "127.0.0.1"), &mocks.Updater{}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := new(dns.Msg)
+	msg.SetAxfr("mydomain.com.")
+
+	envelopes, err := new(dns.Transfer).In(msg, e.servers[1].Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rrs []dns.RR
+	for envelope := range envelopes {
+		if envelope.Error != nil {
+			t.Fatal(envelope.Error)
+		}
+		rrs = append(rrs, envelope.RR...)
+	}
+
+	// SOA, 2 NS, A, SOA
+	if len(rrs) != 5 || rrs[0].Header().Rrtype != dns.TypeSOA || rrs[len(rrs)-1].Header().Rrtype != dns.TypeSOA {
+		t.Errorf("Expected the zone to be transferred, got: %v", rrs)
+	}
+
+	// Transfers are refused over UDP and from networks that aren't allowed.
+	if response := query(t, e, "mydomain.com.", dns.TypeAXFR); response.Rcode != dns.RcodeRefused {
+		t.Errorf("Expected the transfer to be refused over UDP, got: %s", dns.RcodeToString[response.Rcode])
+	}
+
+	e.transferAllow = nil
+	envelopes, err = new(dns.Transfer).In(msg, e.servers[1].Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if envelope := <-envelopes; envelope.Error == nil {
+		t.Errorf("Expected the transfer to be refused, got: %v", envelope.RR)
+	}
+}
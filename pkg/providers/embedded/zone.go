@@ -0,0 +1,215 @@
+package embedded
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// Values of the synthesized SOA record. The refresh is short so secondaries pick up
+	// changes quickly even if they don't poll the serial often.
+	kSOATTL     = 3600
+	kSOARefresh = 300
+	kSOARetry   = 60
+	kSOAExpire  = 604800
+	kSOAMinTTL  = 60
+)
+
+// zone holds the records served for a single origin. It isn't safe for concurrent use, the
+// provider guards all the zones with its own lock.
+type zone struct {
+	origin      string
+	nameservers []string
+	hostmaster  string
+	serial      uint32
+
+	// Resource records of each DNSRecord, indexed by the DNSRecord's namespace/name.
+	records map[string][]dns.RR
+
+	// All the resource records of the zone indexed by their owner name, including the synthesized
+	// SOA and NS records. The index is rebuilt every time the zone changes.
+	names map[string][]dns.RR
+}
+
+func newZone(origin string, nameservers []string, hostmaster string) *zone {
+	z := &zone{
+		origin:      origin,
+		nameservers: nameservers,
+		hostmaster:  hostmaster,
+		records:     map[string][]dns.RR{},
+	}
+
+	z.bump()
+	return z
+}
+
+// set the resource records of the key. The zone is left untouched when they didn't change so loading the
+// records again doesn't bump the serial.
+func (z *zone) set(key string, rrs []dns.RR) {
+	if existing, ok := z.records[key]; ok && slices.EqualFunc(existing, rrs, func(a, b dns.RR) bool { return a.String() == b.String() }) {
+		return
+	}
+
+	z.records[key] = rrs
+	z.bump()
+}
+
+func (z *zone) remove(key string) {
+	if _, ok := z.records[key]; !ok {
+		return
+	}
+
+	delete(z.records, key)
+	z.bump()
+}
+
+// Increment the serial and rebuild the index. The serial is based on the current time so it keeps increasing
+// when the provider restarts, otherwise secondaries would ignore the zone until the serial catches up.
+func (z *zone) bump() {
+	z.serial = max(z.serial+1, uint32(time.Now().Unix()))
+
+	z.names = map[string][]dns.RR{
+		z.origin: {z.soa()},
+	}
+
+	for _, ns := range z.nameservers {
+		z.names[z.origin] = append(z.names[z.origin], &dns.NS{
+			Hdr: dns.RR_Header{Name: z.origin, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: kSOATTL},
+			Ns:  ns,
+		})
+	}
+
+	for _, rrs := range z.records {
+		for _, rr := range rrs {
+			name := strings.ToLower(rr.Header().Name)
+			z.names[name] = append(z.names[name], rr)
+		}
+	}
+}
+
+func (z *zone) soa() *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: z.origin, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: kSOATTL},
+		Ns:      z.nameservers[0],
+		Mbox:    z.hostmaster,
+		Serial:  z.serial,
+		Refresh: kSOARefresh,
+		Retry:   kSOARetry,
+		Expire:  kSOAExpire,
+		Minttl:  kSOAMinTTL,
+	}
+}
+
+// answer fills the message with the answer to the question, as an authoritative server would. Names that exist
+// without records of the requested type return an empty answer (NODATA) while names that don't exist return NXDOMAIN.
+// In both cases, the SOA is added to the authority section so resolvers can cache the negative answer.
+func (z *zone) answer(msg *dns.Msg, q dns.Question) {
+	name := strings.ToLower(q.Name)
+
+	rrs, found := z.names[name]
+	if !found {
+		rrs, found = z.wildcard(name)
+	}
+
+	if !found {
+		if !z.exists(name) {
+			msg.Rcode = dns.RcodeNameError
+		}
+
+		msg.Ns = []dns.RR{z.soa()}
+		return
+	}
+
+	var cname dns.RR
+	for _, rr := range rrs {
+		switch {
+		case rr.Header().Rrtype == q.Qtype, q.Qtype == dns.TypeANY:
+			msg.Answer = append(msg.Answer, synthesize(rr, q.Name))
+		case rr.Header().Rrtype == dns.TypeCNAME:
+			cname = rr
+		}
+	}
+
+	if len(msg.Answer) == 0 && cname != nil {
+		msg.Answer = append(msg.Answer, synthesize(cname, q.Name))
+	}
+
+	if len(msg.Answer) == 0 {
+		msg.Ns = []dns.RR{z.soa()}
+	}
+}
+
+// Look for a wildcard (ie. *.mydomain.com) that covers the name. As defined in RFC 4592, only the wildcard
+// directly below the closest existing ancestor of the name can match.
+func (z *zone) wildcard(name string) ([]dns.RR, bool) {
+	for ancestor := name; ancestor != z.origin; {
+		i := strings.Index(ancestor, ".")
+		if i < 0 || i == len(ancestor)-1 {
+			return nil, false
+		}
+
+		ancestor = ancestor[i+1:]
+		if rrs, ok := z.names["*."+ancestor]; ok {
+			return rrs, true
+		}
+
+		if z.exists(ancestor) {
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
+
+// A name exists when it has records, or when a name below it has records (empty non-terminal).
+func (z *zone) exists(name string) bool {
+	if _, ok := z.names[name]; ok {
+		return true
+	}
+
+	for n := range z.names {
+		if strings.HasSuffix(n, "."+name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Return all the resource records of the zone as expected by a zone transfer: the SOA first, followed by
+// the other records, and the SOA again to signal the end of the transfer.
+func (z *zone) transfer() []dns.RR {
+	soa := z.soa()
+
+	names := make([]string, 0, len(z.names))
+	for name := range z.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rrs := []dns.RR{soa}
+	for _, name := range names {
+		for _, rr := range z.names[name] {
+			if rr.Header().Rrtype != dns.TypeSOA {
+				rrs = append(rrs, rr)
+			}
+		}
+	}
+
+	return append(rrs, soa)
+}
+
+// Copy the resource record with the name from the question, which is only different for wildcards.
+func synthesize(rr dns.RR, name string) dns.RR {
+	if rr.Header().Name == name {
+		return rr
+	}
+
+	rr = dns.Copy(rr)
+	rr.Header().Name = name
+	return rr
+}
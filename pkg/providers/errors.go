@@ -0,0 +1,150 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ErrorKind classifies an error returned by a Provider so the reconciler knows whether
+// the operation can be retried.
+type ErrorKind string
+
+const (
+	// Transient errors are expected to go away on their own (ie. network failure, 5xx from the provider's API).
+	ErrorTransient ErrorKind = "Transient"
+
+	// RateLimited errors are transient errors where the provider asked to slow down. The provider
+	// might also tell how long to wait before retrying.
+	ErrorRateLimited ErrorKind = "RateLimited"
+
+	// Permanent errors won't go away by retrying (ie. invalid credentials, invalid record). The record
+	// stays in error until a user intervenes.
+	ErrorPermanent ErrorKind = "Permanent"
+)
+
+// Error wraps an error returned by a provider's SDK with its kind. The message of the wrapped error
+// is kept as is so the error code set by the provider is what users see.
+type Error struct {
+	Kind ErrorKind
+
+	// RetryAfter is the delay the provider asked to wait for before retrying. It's only set
+	// for rate limited errors, and only when the provider returned it.
+	RetryAfter time.Duration
+
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Transient wraps err as a transient error. A nil err returns nil.
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Kind: ErrorTransient, Err: err}
+}
+
+// RateLimited wraps err as a rate limited error. retryAfter can be zero when the provider
+// didn't say how long to wait. A nil err returns nil.
+func RateLimited(err error, retryAfter time.Duration) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Kind: ErrorRateLimited, RetryAfter: retryAfter, Err: err}
+}
+
+// Permanent wraps err as a permanent error. A nil err returns nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Kind: ErrorPermanent, Err: err}
+}
+
+// FromHTTPStatus wraps err according to the HTTP status code returned by a provider's API. A 429 is
+// rate limited and uses the Retry-After header when it's set, 408 and 5xx are transient and all other
+// codes are permanent.
+func FromHTTPStatus(err error, status int, retryAfter string) error {
+	switch {
+	case err == nil:
+		return nil
+	case status == http.StatusTooManyRequests:
+		return RateLimited(err, ParseRetryAfter(retryAfter))
+	case status == http.StatusRequestTimeout || status >= http.StatusInternalServerError:
+		return Transient(err)
+	case status == 0:
+		// The request never got a response, the error is classified by its cause.
+		return err
+	default:
+		return Permanent(err)
+	}
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, which is either a number of seconds
+// or an HTTP date. Zero is returned when the value is empty or invalid.
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
+
+// Kind returns the kind of err. Errors that weren't wrapped by a provider are classified
+// by their cause: timeouts and connection failures are transient, everything else is
+// permanent.
+func Kind(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTransient
+	}
+
+	return ErrorPermanent
+}
+
+// Retryable returns true if the operation that returned err can be retried.
+func Retryable(err error) bool {
+	kind := Kind(err)
+	return kind == ErrorTransient || kind == ErrorRateLimited
+}
+
+// RetryAfter returns the delay a provider asked to wait for before retrying, if any.
+func RetryAfter(err error) time.Duration {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.RetryAfter
+	}
+
+	return 0
+}
@@ -0,0 +1,116 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestErrorKind(t *testing.T) {
+	base := errors.New("PB-CF-#0003: Could not create the record")
+
+	tests := []struct {
+		name      string
+		err       error
+		kind      ErrorKind
+		retryable bool
+	}{
+		{
+			name:      "Transient",
+			err:       Transient(base),
+			kind:      ErrorTransient,
+			retryable: true,
+		},
+		{
+			name:      "Rate limited wrapped by another error",
+			err:       fmt.Errorf("context -- %w", RateLimited(base, time.Minute)),
+			kind:      ErrorRateLimited,
+			retryable: true,
+		},
+		{
+			name: "Permanent",
+			err:  Permanent(base),
+			kind: ErrorPermanent,
+		},
+		{
+			name:      "Unclassified deadline",
+			err:       fmt.Errorf("PB-CF-#0003: -- %w", context.DeadlineExceeded),
+			kind:      ErrorTransient,
+			retryable: true,
+		},
+		{
+			name: "Unclassified",
+			err:  base,
+			kind: ErrorPermanent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kind := Kind(tt.err); kind != tt.kind {
+				t.Errorf("Expected kind %s, got %s", tt.kind, kind)
+			}
+
+			if retryable := Retryable(tt.err); retryable != tt.retryable {
+				t.Errorf("Expected Retryable() to return %t, got %t", tt.retryable, retryable)
+			}
+		})
+	}
+}
+
+func TestFromHTTPStatus(t *testing.T) {
+	base := errors.New("PB-CF-#0003: Could not create the record")
+
+	tests := []struct {
+		status     int
+		retryAfter string
+		kind       ErrorKind
+		wait       time.Duration
+	}{
+		{status: http.StatusTooManyRequests, retryAfter: "30", kind: ErrorRateLimited, wait: 30 * time.Second},
+		{status: http.StatusTooManyRequests, kind: ErrorRateLimited},
+		{status: http.StatusServiceUnavailable, kind: ErrorTransient},
+		{status: http.StatusRequestTimeout, kind: ErrorTransient},
+		{status: http.StatusForbidden, kind: ErrorPermanent},
+		{status: http.StatusBadRequest, kind: ErrorPermanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			err := FromHTTPStatus(base, tt.status, tt.retryAfter)
+			if !errors.Is(err, base) {
+				t.Errorf("Expected the error to wrap %v", base)
+			}
+
+			if kind := Kind(err); kind != tt.kind {
+				t.Errorf("Expected kind %s, got %s", tt.kind, kind)
+			}
+
+			if wait := RetryAfter(err); wait != tt.wait {
+				t.Errorf("Expected to wait %s, got %s", tt.wait, wait)
+			}
+		})
+	}
+
+	if FromHTTPStatus(nil, http.StatusTooManyRequests, "") != nil {
+		t.Error("Expected a nil error to stay nil")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if wait := ParseRetryAfter("120"); wait != 2*time.Minute {
+		t.Errorf("Expected 2m, got %s", wait)
+	}
+
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if wait := ParseRetryAfter(date); wait <= 59*time.Minute || wait > time.Hour {
+		t.Errorf("Expected about an hour, got %s", wait)
+	}
+
+	if wait := ParseRetryAfter("soon"); wait != 0 {
+		t.Errorf("Expected an invalid value to return 0, got %s", wait)
+	}
+}
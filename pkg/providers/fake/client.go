@@ -0,0 +1,181 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	defaultTTL = int64(300) // Default TTL for DNS records in seconds if not specified
+	kRecordID  = "recordID"
+)
+
+// fake is a provider that stores records in memory. It behaves like a provider backed by a real DNS
+// service, which makes it useful for demos, e2e tests and as the reference for the conformance suite.
+// Records are lost when the provider restarts.
+type fake struct {
+	integration string
+	zones       []string
+
+	mu      sync.Mutex
+	records map[string]entry
+}
+
+type entry struct {
+	targets []string
+	ttl     int64
+}
+
+func init() {
+	providers.Register("fake", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
+}
+
+func NewClient(ctx context.Context) (*fake, error) {
+	return &fake{records: map[string]entry{}}, nil
+}
+
+func (f *fake) Configure(ctx context.Context, integration string, zones []string) error {
+	f.integration = integration
+	f.zones = zones
+
+	log.FromContext(ctx).Info("[Provider] Fake configured", "Zones", zones)
+	return nil
+}
+
+func (f *fake) Zones() []string {
+	return f.zones
+}
+
+// Check that the zone is one of the zones the provider was configured with.
+func (f *fake) Check(ctx context.Context, zone string) error {
+	for _, z := range f.zones {
+		if strings.EqualFold(strings.TrimSuffix(z, "."), strings.TrimSuffix(zone, ".")) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("PB-FAKE-#0004: Zone %s is not managed by the provider", zone)
+}
+
+func (f *fake) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := f.store(&record); err != nil {
+		return fmt.Errorf("PB-FAKE-#0002: Failed to create DNS record -- %w", err)
+	}
+
+	su.StageRemoteInfo(phonebook.IntegrationInfo{kRecordID: key(&record)})
+	su.StageCondition(konditions.ConditionCreated, "Record created")
+	return nil
+}
+
+func (f *fake) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := f.store(&record); err != nil {
+		return fmt.Errorf("PB-FAKE-#0003: Failed to update DNS record -- %w", err)
+	}
+
+	su.StageRemoteInfo(phonebook.IntegrationInfo{kRecordID: key(&record)})
+	su.StageCondition(konditions.ConditionCreated, "Record updated")
+	return nil
+}
+
+// Delete the record. Records that don't exist are considered deleted.
+func (f *fake) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	f.mu.Lock()
+	delete(f.records, key(&record))
+	f.mu.Unlock()
+
+	su.StageCondition(konditions.ConditionTerminated, "Record deleted")
+	return nil
+}
+
+func (f *fake) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e, ok := f.records[key(&record)]
+	if !ok {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	ttl := e.ttl
+	return &providers.RemoteRecord{Targets: append([]string{}, e.targets...), TTL: &ttl}, nil
+}
+
+// Validate the record and replace the values stored for its name and type.
+func (f *fake) store(record *phonebook.DNSRecord) error {
+	targets, err := validate(record)
+	if err != nil {
+		return err
+	}
+
+	ttl := defaultTTL
+	if record.Spec.TTL != nil {
+		ttl = *record.Spec.TTL
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records[key(record)] = entry{targets: targets, ttl: ttl}
+	return nil
+}
+
+// Validate the targets for the record's type and return them as they're stored. TXT targets
+// are stored without their quotes, the same way a DNS service would.
+func validate(record *phonebook.DNSRecord) ([]string, error) {
+	if len(record.Spec.Targets) == 0 {
+		return nil, providers.Permanent(fmt.Errorf("PB-FAKE-#0001: Record %s has no targets", record.Spec.Name))
+	}
+
+	targets := make([]string, len(record.Spec.Targets))
+	for i, target := range record.Spec.Targets {
+		switch record.Spec.RecordType {
+		case "A":
+			if ip := net.ParseIP(target); ip == nil || ip.To4() == nil {
+				return nil, invalid(record, target)
+			}
+		case "AAAA":
+			if ip := net.ParseIP(target); ip == nil || ip.To4() != nil {
+				return nil, invalid(record, target)
+			}
+		case "CNAME":
+			if len(record.Spec.Targets) > 1 {
+				return nil, providers.Permanent(fmt.Errorf("PB-FAKE-#0001: CNAME records can only have one target"))
+			}
+		case "TXT":
+			target = strings.Trim(target, "\"")
+		case "MX", "NS", "SRV", "CAA":
+		default:
+			return nil, providers.Permanent(fmt.Errorf("PB-FAKE-#0001: Unsupported record type %s", record.Spec.RecordType))
+		}
+
+		targets[i] = target
+	}
+
+	return targets, nil
+}
+
+func invalid(record *phonebook.DNSRecord, target string) error {
+	return providers.Permanent(fmt.Errorf("PB-FAKE-#0001: Invalid target (%s) for %s record", target, record.Spec.RecordType))
+}
+
+// Records are stored by their fully qualified name and type, which means a record created twice is stored once.
+func key(record *phonebook.DNSRecord) string {
+	name := strings.ToLower(strings.TrimSuffix(record.Spec.Name, "."))
+	zone := strings.ToLower(strings.TrimSuffix(record.Spec.Zone, "."))
+
+	if name == "" || name == "@" {
+		return fmt.Sprintf("%s/%s", zone, record.Spec.RecordType)
+	}
+
+	return fmt.Sprintf("%s.%s/%s", name, zone, record.Spec.RecordType)
+}
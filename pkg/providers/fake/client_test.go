@@ -0,0 +1,23 @@
+package fake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"github.com/pier-oliviert/phonebook/pkg/providers/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, conformance.Suite{
+		Zone: "mydomain.com",
+		NewProvider: func(t *testing.T) providers.Provider {
+			f, err := NewClient(context.TODO())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return f
+		},
+	})
+}
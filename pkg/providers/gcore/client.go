@@ -2,11 +2,14 @@ package gcore
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 
 	gdns "github.com/G-Core/gcore-dns-sdk-go"
 	"github.com/pier-oliviert/konditionner/pkg/konditions"
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
 	"github.com/pier-oliviert/phonebook/pkg/utils"
 )
 
@@ -19,7 +22,10 @@ const (
 // this needs to exists here in order for phonebook to have proper testing
 type api interface {
 	AddZoneRRSet(context.Context, string, string, string, []gdns.ResourceRecord, int, ...gdns.AddZoneOpt) error
+	UpdateRRSet(context.Context, string, string, string, gdns.RRSet) error
+	RRSet(context.Context, string, string, string) (gdns.RRSet, error)
 	DeleteRRSet(context.Context, string, string, string) error
+	Zone(context.Context, string) (gdns.Zone, error)
 }
 
 type gcore struct {
@@ -29,10 +35,16 @@ type gcore struct {
 	api         api
 }
 
+func init() {
+	providers.Register("gcore", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
+}
+
 func NewClient(ctx context.Context) (*gcore, error) {
 	var err error
 
-	token, err := utils.RetrieveValueFromEnvOrFile(EnvAPIToken)
+	token, err := utils.RetrieveValue(ctx, EnvAPIToken)
 	if err != nil {
 		return nil, err
 	}
@@ -54,7 +66,73 @@ func (c *gcore) Zones() []string {
 	return c.zones
 }
 
+// Check that the zone exists in the account the token belongs to.
+func (c *gcore) Check(ctx context.Context, zone string) error {
+	if _, err := c.api.Zone(ctx, zone); err != nil {
+		return fmt.Errorf("PB-GCORE-#0001: Zone %s could not be retrieved -- %w", zone, classify(err))
+	}
+
+	return nil
+}
+
 func (c *gcore) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	values, ttl := c.resourceRecords(record)
+
+	err := c.api.AddZoneRRSet(ctx, record.Spec.Zone, fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone), record.Spec.RecordType, values, ttl)
+	if err != nil {
+		return classify(err)
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "G-Core record created")
+	return nil
+}
+
+// Update replaces the whole RRSet with the values from the record. AddZoneRRSet can't be used here
+// as it would append the new values to the ones that already exist.
+func (c *gcore) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	values, ttl := c.resourceRecords(record)
+
+	err := c.api.UpdateRRSet(ctx, record.Spec.Zone, fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone), record.Spec.RecordType, gdns.RRSet{
+		TTL:     ttl,
+		Records: values,
+	})
+	if err != nil {
+		return classify(err)
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "G-Core record updated")
+	return nil
+}
+
+// Read the RRSet from G-Core. Each content value is converted back to a string so it can
+// be compared to the record's targets.
+func (c *gcore) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	rrset, err := c.api.RRSet(ctx, record.Spec.Zone, fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone), record.Spec.RecordType)
+	if err != nil {
+		var apiErr gdns.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, providers.ErrRecordNotFound
+		}
+
+		return nil, classify(err)
+	}
+
+	ttl := int64(rrset.TTL)
+	remote := &providers.RemoteRecord{
+		TTL: &ttl,
+	}
+
+	for _, rr := range rrset.Records {
+		for _, content := range rr.Content {
+			remote.Targets = append(remote.Targets, fmt.Sprint(content))
+		}
+	}
+
+	return remote, nil
+}
+
+// Convert the record's targets and TTL to the values expected by G-Core
+func (c *gcore) resourceRecords(record phonebook.DNSRecord) ([]gdns.ResourceRecord, int) {
 	values := []gdns.ResourceRecord{{
 		Enabled: true,
 	}}
@@ -71,21 +149,26 @@ func (c *gcore) Create(ctx context.Context, record phonebook.DNSRecord, su phone
 		*ttl = DefaultTTL
 	}
 
-	err := c.api.AddZoneRRSet(ctx, record.Spec.Zone, fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone), record.Spec.RecordType, values, int(*ttl))
-	if err != nil {
-		return err
-	}
-
-	su.StageCondition(konditions.ConditionCreated, "G-Core record created")
-	return nil
+	return values, int(*ttl)
 }
 
 func (c *gcore) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
 	err := c.api.DeleteRRSet(ctx, record.Spec.Zone, fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone), record.Spec.RecordType)
 	if err != nil {
-		return err
+		return classify(err)
 	}
 
 	su.StageCondition(konditions.ConditionTerminated, "G-Core record deleted")
 	return nil
 }
+
+// classify wraps err with its kind based on the status code returned by G-Core so rate limited
+// and failed requests are retried.
+func classify(err error) error {
+	var apiErr gdns.APIError
+	if errors.As(err, &apiErr) {
+		return providers.FromHTTPStatus(err, apiErr.StatusCode, "")
+	}
+
+	return err
+}
@@ -2,20 +2,26 @@ package gcore
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"testing"
 
 	gdns "github.com/G-Core/gcore-dns-sdk-go"
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
 	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
 	"github.com/stretchr/testify/mock"
 )
 
 type MockRecordSetsClient struct {
 	mock.Mock
 	recordCreated rCreated
+	recordUpdated rCreated
 	recordDeleted rDeleted
+	remote        *gdns.RRSet
+	err           error
 }
 
 type rCreated struct {
@@ -39,9 +45,32 @@ func (m *MockRecordSetsClient) AddZoneRRSet(_ context.Context, zone, name, rType
 		ttl:        ttl,
 	}
 
+	return m.err
+}
+
+func (m *MockRecordSetsClient) Zone(_ context.Context, name string) (gdns.Zone, error) {
+	return gdns.Zone{Name: name}, m.err
+}
+
+func (m *MockRecordSetsClient) UpdateRRSet(_ context.Context, zone, name, rType string, set gdns.RRSet) error {
+	m.recordUpdated = rCreated{
+		zone:       zone,
+		name:       name,
+		recordType: rType,
+		ttl:        set.TTL,
+	}
+
 	return nil
 }
 
+func (m *MockRecordSetsClient) RRSet(_ context.Context, zone, name, rType string) (gdns.RRSet, error) {
+	if m.remote == nil {
+		return gdns.RRSet{}, gdns.APIError{StatusCode: http.StatusNotFound}
+	}
+
+	return *m.remote, nil
+}
+
 func (m *MockRecordSetsClient) DeleteRRSet(_ context.Context, zone, name, rType string) error {
 	m.recordDeleted = rDeleted{
 		zone:       zone,
@@ -135,3 +164,105 @@ func TestCreationTTLSet(t *testing.T) {
 		t.Errorf("Record did not match default TTL %d: %d", mock.recordCreated.ttl, 900)
 	}
 }
+
+func TestUpdate(t *testing.T) {
+	os.Setenv("GCORE_API_TOKEN", "Mytoken")
+	client, err := NewClient(context.Background())
+	if err != nil {
+		t.Error(err)
+	}
+
+	mock := &MockRecordSetsClient{}
+	client.api = mock
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "subdomain",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.2"},
+			TTL:        new(int64),
+		},
+		Status: phonebook.DNSRecordStatus{},
+	}
+	*record.Spec.TTL = 300
+
+	err = client.Update(context.Background(), record, &mocks.Updater{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if mock.recordUpdated.name != fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone) {
+		t.Errorf("Record did not match name %s: %s.%s", mock.recordUpdated.name, record.Spec.Name, record.Spec.Zone)
+	}
+
+	if mock.recordUpdated.ttl != 300 {
+		t.Errorf("Record did not match TTL %d: %d", mock.recordUpdated.ttl, 300)
+	}
+}
+
+func TestRead(t *testing.T) {
+	os.Setenv("GCORE_API_TOKEN", "Mytoken")
+	client, err := NewClient(context.Background())
+	if err != nil {
+		t.Error(err)
+	}
+
+	mock := &MockRecordSetsClient{}
+	client.api = mock
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "subdomain",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+
+	_, err = client.Read(context.Background(), record)
+	if !errors.Is(err, providers.ErrRecordNotFound) {
+		t.Errorf("Expected the record to not be found, got: %v", err)
+	}
+
+	mock.remote = &gdns.RRSet{
+		TTL: 120,
+		Records: []gdns.ResourceRecord{{
+			Content: []any{"127.0.0.2"},
+			Enabled: true,
+		}},
+	}
+
+	remote, err := client.Read(context.Background(), record)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if remote.Matches(record.Spec) {
+		t.Errorf("Expected the remote record to have drifted: %v", remote.Targets)
+	}
+}
+
+func TestCreationRateLimited(t *testing.T) {
+	os.Setenv("GCORE_API_TOKEN", "Mytoken")
+	client, err := NewClient(context.Background())
+	if err != nil {
+		t.Error(err)
+	}
+
+	client.api = &MockRecordSetsClient{err: gdns.APIError{StatusCode: http.StatusTooManyRequests}}
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "subdomain",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+
+	err = client.Create(context.Background(), record, &mocks.Updater{})
+	if !providers.Retryable(err) {
+		t.Errorf("Expected a retryable error, got: %v", err)
+	}
+}
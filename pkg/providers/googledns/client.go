@@ -0,0 +1,325 @@
+package googledns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	utils "github.com/pier-oliviert/phonebook/pkg/utils"
+	gdns "google.golang.org/api/dns/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	kGoogleProjectID   = "GOOGLE_PROJECT_ID"
+	kGoogleCredentials = "GOOGLE_CREDENTIALS"
+	kGoogleManagedZone = "GOOGLE_MANAGED_ZONE"
+	defaultTTL         = int64(60) // Default TTL for DNS records in seconds if not specified
+)
+
+type googleDNS struct {
+	integration string
+	project     string
+	zones       []string
+
+	// Name of the managed zone for each of the zones, resolved when the provider is configured. Managed
+	// zones can be set explicitly with GOOGLE_MANAGED_ZONE, otherwise they're looked up by DNS name.
+	overrides    string
+	managedZones map[string]string
+
+	service *gdns.Service
+}
+
+func init() {
+	providers.Register("googledns", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
+}
+
+// NewClient creates a Cloud DNS provider for the project set with GOOGLE_PROJECT_ID.
+//
+// The provider authenticates with the service account key stored in GOOGLE_CREDENTIALS (the content
+// of the JSON file, not its path). When GOOGLE_CREDENTIALS isn't set, the Application Default Credentials
+// are used, which is how Workload Identity is configured on GKE.
+func NewClient(ctx context.Context) (*googleDNS, error) {
+	project, err := utils.RetrieveValue(ctx, kGoogleProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("PB-GCP-#0001: Google Project ID not found -- %w", err)
+	}
+
+	overrides, _ := utils.RetrieveValue(ctx, kGoogleManagedZone)
+
+	opts := []option.ClientOption{option.WithScopes(gdns.NdevClouddnsReadwriteScope)}
+	if credentials, err := utils.RetrieveValue(ctx, kGoogleCredentials); err == nil {
+		opts = append(opts, option.WithCredentialsJSON([]byte(credentials)))
+	}
+
+	service, err := gdns.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("PB-GCP-#0002: Unable to create Cloud DNS client -- %w", err)
+	}
+
+	return &googleDNS{
+		project:   strings.TrimSpace(project),
+		overrides: overrides,
+		service:   service,
+	}, nil
+}
+
+// Configure resolves the managed zone for each of the zones. Zones that don't have a managed zone
+// set explicitly through GOOGLE_MANAGED_ZONE are looked up by their DNS name.
+func (g *googleDNS) Configure(ctx context.Context, integration string, zones []string) error {
+	g.integration = integration
+	g.zones = zones
+
+	managedZones, err := providers.ParseZoneOverrides(g.overrides, zones)
+	if err != nil {
+		return err
+	}
+
+	for _, zone := range zones {
+		if _, ok := managedZones[zone]; ok {
+			continue
+		}
+
+		name, err := g.lookupManagedZone(ctx, zone)
+		if err != nil {
+			return err
+		}
+
+		managedZones[zone] = name
+	}
+
+	log.FromContext(ctx).Info("[Provider] Cloud DNS Managed Zones resolved", "Managed Zones", managedZones)
+	g.managedZones = managedZones
+
+	return nil
+}
+
+// Find the managed zone for the zone. A project can have a public and a private managed zone
+// for the same DNS name, in which case the user needs to pick one.
+func (g *googleDNS) lookupManagedZone(ctx context.Context, zone string) (string, error) {
+	response, err := g.service.ManagedZones.List(g.project).DnsName(fqdn(zone)).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("PB-GCP-#0009: Failed to list managed zones -- %w", err)
+	}
+
+	switch len(response.ManagedZones) {
+	case 0:
+		return "", fmt.Errorf("PB-GCP-#0003: Managed zone not found for %s", zone)
+	case 1:
+		return response.ManagedZones[0].Name, nil
+	}
+
+	var names []string
+	for _, mz := range response.ManagedZones {
+		names = append(names, mz.Name)
+	}
+
+	return "", fmt.Errorf("PB-GCP-#0004: Multiple managed zones found for %s (%s), set the managed zone with %s", zone, strings.Join(names, ", "), kGoogleManagedZone)
+}
+
+// Return the name of the managed zone the record belongs to.
+func (g *googleDNS) managedZone(record *phonebook.DNSRecord) (string, error) {
+	name, ok := g.managedZones[record.Spec.Zone]
+	if !ok {
+		return "", fmt.Errorf("PB-GCP-#0003: Managed zone not found for %s", record.Spec.Zone)
+	}
+
+	return name, nil
+}
+
+func (g *googleDNS) Zones() []string {
+	return g.zones
+}
+
+// Check that the managed zone can be retrieved with the credentials.
+func (g *googleDNS) Check(ctx context.Context, zone string) error {
+	name, ok := g.managedZones[zone]
+	if !ok {
+		return fmt.Errorf("PB-GCP-#0003: Managed zone not found for %s", zone)
+	}
+
+	if _, err := g.service.ManagedZones.Get(g.project, name).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("PB-GCP-#0010: Failed to retrieve the managed zone %s -- %w", name, classify(err))
+	}
+
+	return nil
+}
+
+// Create the record set. If it already exists (ie. Phonebook stopped before the status was saved), it's
+// replaced instead.
+func (g *googleDNS) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	change := &gdns.Change{
+		Additions: []*gdns.ResourceRecordSet{g.resourceRecordSet(&record)},
+	}
+
+	response, err := g.change(ctx, &record, change)
+	if isConflict(err) {
+		response, err = g.replace(ctx, &record)
+	}
+
+	if err != nil {
+		return classify(fmt.Errorf("PB-GCP-#0005: Failed to create DNS record -- %w", err))
+	}
+
+	su.StageRemoteInfo(phonebook.IntegrationInfo{
+		"changeID": response.Id,
+	})
+	su.StageCondition(konditions.ConditionCreated, "Cloud DNS record created")
+
+	return nil
+}
+
+// Update replaces the record set with the values from the record.
+func (g *googleDNS) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	response, err := g.replace(ctx, &record)
+	if err != nil {
+		return classify(fmt.Errorf("PB-GCP-#0006: Failed to update DNS record -- %w", err))
+	}
+
+	su.StageRemoteInfo(phonebook.IntegrationInfo{
+		"changeID": response.Id,
+	})
+	su.StageCondition(konditions.ConditionCreated, "Cloud DNS record updated")
+
+	return nil
+}
+
+// Delete the record set. A record set that doesn't exist anymore is considered deleted.
+func (g *googleDNS) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	existing, err := g.get(ctx, &record)
+	if err != nil && !isNotFound(err) {
+		return classify(fmt.Errorf("PB-GCP-#0007: Failed to delete DNS record -- %w", err))
+	}
+
+	if existing != nil {
+		_, err = g.change(ctx, &record, &gdns.Change{
+			Deletions: []*gdns.ResourceRecordSet{existing},
+		})
+		if err != nil {
+			return classify(fmt.Errorf("PB-GCP-#0007: Failed to delete DNS record -- %w", err))
+		}
+	}
+
+	su.StageCondition(konditions.ConditionTerminated, "Cloud DNS record deleted")
+	return nil
+}
+
+// Read the record set from Cloud DNS
+func (g *googleDNS) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	rrset, err := g.get(ctx, &record)
+	if isNotFound(err) {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	if err != nil {
+		return nil, classify(fmt.Errorf("PB-GCP-#0008: Failed to read DNS record -- %w", err))
+	}
+
+	return &providers.RemoteRecord{
+		Targets: rrset.Rrdatas,
+		TTL:     &rrset.Ttl,
+	}, nil
+}
+
+func (g *googleDNS) change(ctx context.Context, record *phonebook.DNSRecord, change *gdns.Change) (*gdns.Change, error) {
+	managedZone, err := g.managedZone(record)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.service.Changes.Create(g.project, managedZone, change).Context(ctx).Do()
+}
+
+// replace the record set with the values from the record. Cloud DNS requires the existing record set to be
+// deleted in the same change, so it's read first. If the record set doesn't exist anymore, it's created.
+func (g *googleDNS) replace(ctx context.Context, record *phonebook.DNSRecord) (*gdns.Change, error) {
+	change := &gdns.Change{
+		Additions: []*gdns.ResourceRecordSet{g.resourceRecordSet(record)},
+	}
+
+	existing, err := g.get(ctx, record)
+	if err != nil && !isNotFound(err) {
+		return nil, err
+	}
+
+	if existing != nil {
+		change.Deletions = []*gdns.ResourceRecordSet{existing}
+	}
+
+	return g.change(ctx, record, change)
+}
+
+func (g *googleDNS) get(ctx context.Context, record *phonebook.DNSRecord) (*gdns.ResourceRecordSet, error) {
+	managedZone, err := g.managedZone(record)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.service.ResourceRecordSets.Get(g.project, managedZone, fqdn(record.Spec.Name, record.Spec.Zone), record.Spec.RecordType).Context(ctx).Do()
+}
+
+// Convert a DNSRecord to a record set. Cloud DNS expects names to be fully qualified, including the ones
+// that are part of a target (ie. CNAME, MX), and TXT values to be quoted.
+func (g *googleDNS) resourceRecordSet(record *phonebook.DNSRecord) *gdns.ResourceRecordSet {
+	ttl := defaultTTL
+	if record.Spec.TTL != nil {
+		ttl = *record.Spec.TTL
+	}
+
+	rrset := &gdns.ResourceRecordSet{
+		Name: fqdn(record.Spec.Name, record.Spec.Zone),
+		Type: record.Spec.RecordType,
+		Ttl:  ttl,
+	}
+
+	for _, target := range record.Spec.Targets {
+		switch record.Spec.RecordType {
+		case "TXT":
+			target = fmt.Sprintf("\"%s\"", strings.Trim(target, "\""))
+		case "CNAME", "NS", "PTR", "MX", "SRV":
+			// The hostname is always the last field (ie. `10 mail.mydomain.com` for MX)
+			fields := strings.Fields(target)
+			fields[len(fields)-1] = fqdn(fields[len(fields)-1])
+			target = strings.Join(fields, " ")
+		}
+
+		rrset.Rrdatas = append(rrset.Rrdatas, target)
+	}
+
+	return rrset
+}
+
+// Join the labels into a fully qualified domain name, with the trailing dot.
+func fqdn(labels ...string) string {
+	return strings.TrimSuffix(strings.Join(labels, "."), ".") + "."
+}
+
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+func isConflict(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict
+}
+
+// classify wraps err with its kind based on the status code returned by Cloud DNS so rate limited
+// and failed requests are retried.
+func classify(err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return providers.FromHTTPStatus(err, apiErr.Code, apiErr.Header.Get("Retry-After"))
+	}
+
+	return err
+}
@@ -0,0 +1,239 @@
+package googledns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	gdns "google.golang.org/api/dns/v1"
+	"google.golang.org/api/option"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"github.com/pier-oliviert/phonebook/pkg/providers/conformance"
+)
+
+// fakeCloudDNS implements the subset of the Cloud DNS API used by the provider. Changes
+// are applied to the record sets as soon as they're received.
+type fakeCloudDNS struct {
+	mu           sync.Mutex
+	managedZones []*gdns.ManagedZone
+	rrsets       map[string]*gdns.ResourceRecordSet
+	changes      []*gdns.Change
+	status       int
+}
+
+var supportedTypes = map[string]bool{"A": true, "AAAA": true, "CAA": true, "CNAME": true, "MX": true, "NS": true, "PTR": true, "SRV": true, "TXT": true}
+
+func (f *fakeCloudDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if f.status != 0 {
+		writeError(w, f.status)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/dns/v1/projects/my-project/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "managedZones":
+		response := &gdns.ManagedZonesListResponse{}
+		for _, mz := range f.managedZones {
+			if mz.DnsName == r.URL.Query().Get("dnsName") {
+				response.ManagedZones = append(response.ManagedZones, mz)
+			}
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case len(parts) == 2 && parts[0] == "managedZones":
+		for _, mz := range f.managedZones {
+			if mz.Name == parts[1] {
+				json.NewEncoder(w).Encode(mz)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound)
+
+	case len(parts) == 3 && parts[2] == "changes" && r.Method == http.MethodPost:
+		var change gdns.Change
+		json.NewDecoder(r.Body).Decode(&change)
+
+		for _, rrset := range change.Deletions {
+			delete(f.rrsets, rrset.Name+rrset.Type)
+		}
+
+		for _, rrset := range change.Additions {
+			if !supportedTypes[rrset.Type] {
+				writeError(w, http.StatusBadRequest)
+				return
+			}
+
+			if _, ok := f.rrsets[rrset.Name+rrset.Type]; ok {
+				writeError(w, http.StatusConflict)
+				return
+			}
+			f.rrsets[rrset.Name+rrset.Type] = rrset
+		}
+
+		change.Id = fmt.Sprint(len(f.changes) + 1)
+		f.changes = append(f.changes, &change)
+		json.NewEncoder(w).Encode(change)
+
+	case len(parts) == 5 && parts[2] == "rrsets":
+		rrset, ok := f.rrsets[parts[3]+parts[4]]
+		if !ok {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(rrset)
+
+	default:
+		writeError(w, http.StatusNotImplemented)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"error": {"code": %d, "message": "%s"}}`, status, http.StatusText(status))
+}
+
+func newTestClient(t *testing.T, fake *fakeCloudDNS) *googleDNS {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	if fake.rrsets == nil {
+		fake.rrsets = map[string]*gdns.ResourceRecordSet{}
+	}
+
+	service, err := gdns.NewService(context.TODO(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &googleDNS{
+		integration:  "google-test",
+		project:      "my-project",
+		zones:        []string{"mydomain.com"},
+		managedZones: map[string]string{"mydomain.com": "my-zone"},
+		service:      service,
+	}
+}
+
+func TestConfigureManagedZones(t *testing.T) {
+	fake := &fakeCloudDNS{
+		managedZones: []*gdns.ManagedZone{
+			{Name: "my-zone", DnsName: "mydomain.com."},
+			{Name: "other-zone-public", DnsName: "otherdomain.com."},
+			{Name: "other-zone-private", DnsName: "otherdomain.com."},
+		},
+	}
+
+	g := newTestClient(t, fake)
+	if err := g.Configure(context.TODO(), "google-test", []string{"mydomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.managedZones["mydomain.com"] != "my-zone" {
+		t.Errorf("Expected the managed zone to be looked up, got: %v", g.managedZones)
+	}
+
+	err := g.Configure(context.TODO(), "google-test", []string{"otherdomain.com"})
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-GCP-#0004") {
+		t.Errorf("Expected an error when multiple managed zones match, got: %v", err)
+	}
+
+	g.overrides = "otherdomain.com=other-zone-private"
+	if err := g.Configure(context.TODO(), "google-test", []string{"otherdomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.managedZones["otherdomain.com"] != "other-zone-private" {
+		t.Errorf("Expected the managed zone to be overridden, got: %v", g.managedZones)
+	}
+
+	g.overrides = ""
+	err = g.Configure(context.TODO(), "google-test", []string{"unknown.com"})
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-GCP-#0003") {
+		t.Errorf("Expected an error when the managed zone doesn't exist, got: %v", err)
+	}
+}
+
+func TestResourceRecordSet(t *testing.T) {
+	g := &googleDNS{}
+
+	tests := []struct {
+		recordType string
+		targets    []string
+		expected   []string
+	}{
+		{recordType: "TXT", targets: []string{"some value", "\"quoted\""}, expected: []string{"\"some value\"", "\"quoted\""}},
+		{recordType: "CNAME", targets: []string{"alias.mydomain.com"}, expected: []string{"alias.mydomain.com."}},
+		{recordType: "MX", targets: []string{"10 mail.mydomain.com"}, expected: []string{"10 mail.mydomain.com."}},
+		{recordType: "SRV", targets: []string{"10 5 5060 sip.mydomain.com."}, expected: []string{"10 5 5060 sip.mydomain.com."}},
+		{recordType: "AAAA", targets: []string{"::1"}, expected: []string{"::1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.recordType, func(t *testing.T) {
+			rrset := g.resourceRecordSet(&phonebook.DNSRecord{
+				Spec: phonebook.DNSRecordSpec{
+					Zone:       "mydomain.com",
+					Name:       "record",
+					RecordType: tt.recordType,
+					Targets:    tt.targets,
+				},
+			})
+
+			if rrset.Name != "record.mydomain.com." {
+				t.Errorf("Expected a fully qualified name, got %s", rrset.Name)
+			}
+
+			if strings.Join(rrset.Rrdatas, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, rrset.Rrdatas)
+			}
+		})
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	fake := &fakeCloudDNS{status: http.StatusTooManyRequests}
+	g := newTestClient(t, fake)
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+
+	err := g.Create(context.TODO(), record, &mocks.Updater{})
+	if providers.Kind(err) != providers.ErrorRateLimited || !strings.HasPrefix(err.Error(), "PB-GCP-#0005") {
+		t.Errorf("Expected a rate limited error, got: %v", err)
+	}
+
+	fake.status = http.StatusForbidden
+	err = g.Create(context.TODO(), record, &mocks.Updater{})
+	if providers.Kind(err) != providers.ErrorPermanent {
+		t.Errorf("Expected a permanent error, got: %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, conformance.Suite{
+		Zone: "mydomain.com",
+		NewProvider: func(t *testing.T) providers.Provider {
+			return newTestClient(t, &fakeCloudDNS{
+				managedZones: []*gdns.ManagedZone{{Name: "my-zone", DnsName: "mydomain.com."}},
+			})
+		},
+	})
+}
@@ -0,0 +1,447 @@
+package hetzner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	utils "github.com/pier-oliviert/phonebook/pkg/utils"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	kHetznerAPIToken = "HETZNER_API_TOKEN"
+	kHetznerAPIURL   = "HETZNER_API_URL"
+	defaultAPIURL    = "https://dns.hetzner.com/api/v1"
+	kPageSize        = 100
+)
+
+type hetzner struct {
+	integration string
+	zones       []string
+
+	// Zone IDs indexed by the zone's name, resolved when the provider is configured.
+	zoneIDs map[string]string
+
+	baseURL  string
+	apiToken string
+	client   *http.Client
+}
+
+// Record as represented by Hetzner's API. Names are relative to the zone, with `@` for the apex.
+type record struct {
+	ID     string `json:"id,omitempty"`
+	ZoneID string `json:"zone_id"`
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	TTL    *int64 `json:"ttl,omitempty"`
+}
+
+type zone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type pagination struct {
+	Meta struct {
+		Pagination struct {
+			LastPage int `json:"last_page"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+func init() {
+	providers.Register("hetzner", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
+}
+
+// NewClient creates a provider for Hetzner DNS. The token is an API token created in the
+// DNS console, the Hetzner Cloud tokens don't have access to DNS.
+func NewClient(ctx context.Context) (*hetzner, error) {
+	token, err := utils.RetrieveValue(ctx, kHetznerAPIToken)
+	if err != nil {
+		return nil, fmt.Errorf("PB-HZ-#0001: API Token not found -- %w", err)
+	}
+
+	baseURL, _ := utils.RetrieveValue(ctx, kHetznerAPIURL)
+	if baseURL = strings.TrimSpace(baseURL); baseURL == "" {
+		baseURL = defaultAPIURL
+	}
+
+	return &hetzner{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		apiToken: strings.TrimSpace(token),
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Configure looks up the ID of each zone by its name.
+func (h *hetzner) Configure(ctx context.Context, integration string, zones []string) error {
+	zoneIDs := map[string]string{}
+	for _, name := range zones {
+		var response struct {
+			Zones []zone `json:"zones"`
+		}
+
+		if err := h.do(ctx, http.MethodGet, "zones", url.Values{"name": {name}}, nil, &response); err != nil && statusCode(err) != http.StatusNotFound {
+			return fmt.Errorf("PB-HZ-#0002: Could not look up the zone %s -- %w", name, err)
+		}
+
+		for _, z := range response.Zones {
+			if strings.EqualFold(z.Name, name) {
+				zoneIDs[name] = z.ID
+			}
+		}
+
+		if _, ok := zoneIDs[name]; !ok {
+			return fmt.Errorf("PB-HZ-#0002: Zone %s not found", name)
+		}
+	}
+
+	h.integration = integration
+	h.zones = zones
+	h.zoneIDs = zoneIDs
+
+	log.FromContext(ctx).Info("[Provider] Hetzner configured", "Zones", zones)
+	return nil
+}
+
+func (h *hetzner) Zones() []string {
+	return h.zones
+}
+
+// Check that the zone can still be retrieved with the API token.
+func (h *hetzner) Check(ctx context.Context, zone string) error {
+	id, ok := h.zoneIDs[zone]
+	if !ok {
+		return fmt.Errorf("PB-HZ-#0002: Zone %s not found", zone)
+	}
+
+	if err := h.do(ctx, http.MethodGet, "zones/"+id, nil, nil, nil); err != nil {
+		return fmt.Errorf("PB-HZ-#0002: Could not look up the zone %s -- %w", zone, err)
+	}
+
+	return nil
+}
+
+// Create one Hetzner record for each of the record's targets. The IDs of the records are stored in
+// the RemoteInfo so the records can be updated and deleted later. Records created by a previous attempt that failed
+// part way are updated instead of being created again.
+func (h *hetzner) Create(ctx context.Context, r phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	records, err := h.records(&r)
+	if err != nil {
+		return err
+	}
+
+	existing, err := h.existing(ctx, &r)
+	if err != nil {
+		return classify(fmt.Errorf("PB-HZ-#0003: Failed to create DNS record -- %w", err))
+	}
+
+	if err := h.recordSet(ctx).Sync(su, existing, records); err != nil {
+		return classify(fmt.Errorf("PB-HZ-#0003: Failed to create DNS record -- %w", err))
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "Hetzner records created")
+	return nil
+}
+
+// Update the records that were created for each target.
+func (h *hetzner) Update(ctx context.Context, r phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	records, err := h.records(&r)
+	if err != nil {
+		return err
+	}
+
+	existing, err := h.existing(ctx, &r)
+	if err != nil {
+		return classify(fmt.Errorf("PB-HZ-#0004: Failed to update DNS record -- %w", err))
+	}
+
+	if err := h.recordSet(ctx).Sync(su, existing, records); err != nil {
+		return classify(fmt.Errorf("PB-HZ-#0004: Failed to update DNS record -- %w", err))
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "Hetzner records updated")
+	return nil
+}
+
+// Delete the records whose IDs were stored when they were created. Records that were
+// already deleted on Hetzner are ignored.
+func (h *hetzner) Delete(ctx context.Context, r phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	ids, err := h.existing(ctx, &r)
+	if err != nil {
+		return classify(fmt.Errorf("PB-HZ-#0005: Failed to delete DNS record -- %w", err))
+	}
+
+	if err := h.recordSet(ctx).DeleteAll(su, ids); err != nil {
+		return classify(fmt.Errorf("PB-HZ-#0005: Failed to delete DNS record -- %w", err))
+	}
+
+	su.StageCondition(konditions.ConditionTerminated, "Hetzner records deleted")
+	return nil
+}
+
+// Read the records of the zone that have the same name and type as the DNSRecord.
+func (h *hetzner) Read(ctx context.Context, r phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	records, err := h.list(ctx, &r)
+	if err != nil {
+		return nil, classify(fmt.Errorf("PB-HZ-#0006: Failed to read DNS record -- %w", err))
+	}
+
+	if len(records) == 0 {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	remote := &providers.RemoteRecord{}
+	for _, rec := range records {
+		remote.TTL = rec.TTL
+		remote.Targets = append(remote.Targets, rec.Value)
+	}
+
+	return remote, nil
+}
+
+// list the records of the zone that have the same name and type as the DNSRecord. Hetzner's API
+// can't filter records by name, so all the records of the zone are listed.
+func (h *hetzner) list(ctx context.Context, r *phonebook.DNSRecord) ([]record, error) {
+	name := recordName(r)
+
+	var records []record
+	for page, lastPage := 1, 1; page <= lastPage; page++ {
+		var response struct {
+			pagination
+			Records []record `json:"records"`
+		}
+
+		query := url.Values{
+			"zone_id":  {h.zoneIDs[r.Spec.Zone]},
+			"page":     {strconv.Itoa(page)},
+			"per_page": {strconv.Itoa(kPageSize)},
+		}
+
+		if err := h.do(ctx, http.MethodGet, "records", query, nil, &response); err != nil {
+			return nil, err
+		}
+
+		for _, rec := range response.Records {
+			if rec.Name == name && rec.Type == r.Spec.RecordType {
+				records = append(records, rec)
+			}
+		}
+
+		lastPage = response.Meta.Pagination.LastPage
+	}
+
+	return records, nil
+}
+
+// recordSet creates, updates and deletes Hetzner records by their ID.
+func (h *hetzner) recordSet(ctx context.Context) providers.RecordSet[record] {
+	return providers.RecordSet[record]{
+		Create: func(rec record) (string, error) {
+			var response struct {
+				Record record `json:"record"`
+			}
+
+			if err := h.do(ctx, http.MethodPost, "records", nil, rec, &response); err != nil {
+				return "", err
+			}
+
+			return response.Record.ID, nil
+		},
+		Update: func(id string, rec record) error {
+			return h.do(ctx, http.MethodPut, "records/"+url.PathEscape(id), nil, rec, nil)
+		},
+		Delete: func(id string) error {
+			if err := h.do(ctx, http.MethodDelete, "records/"+url.PathEscape(id), nil, nil, nil); err != nil && statusCode(err) != http.StatusNotFound {
+				return err
+			}
+
+			return nil
+		},
+	}
+}
+
+// existing returns the IDs of the records created for the DNSRecord. If none were stored in the RemoteInfo,
+// the records of the zone with the same name and type are only used when their value is one of the targets.
+// Records with other values were created outside of Phonebook and are left untouched.
+func (h *hetzner) existing(ctx context.Context, r *phonebook.DNSRecord) ([]string, error) {
+	if ids := providers.RecordIDs(r, h.integration); len(ids) != 0 {
+		return ids, nil
+	}
+
+	records, err := h.list(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, rec := range records {
+		if providers.MatchesTarget(r.Spec, rec.Value) {
+			ids = append(ids, rec.ID)
+		}
+	}
+
+	return ids, nil
+}
+
+// Convert each of the record's targets to a Hetzner record. Values are written as they would be in a zone
+// file: hostnames in a target (ie. CNAME, MX) are fully qualified so Hetzner doesn't append the zone to them,
+// and TXT values are quoted.
+func (h *hetzner) records(r *phonebook.DNSRecord) ([]record, error) {
+	var records []record
+	for _, target := range r.Spec.Targets {
+		switch r.Spec.RecordType {
+		case "TXT":
+			target = fmt.Sprintf("\"%s\"", strings.Trim(target, "\""))
+		case "CNAME", "NS", "PTR", "MX", "SRV":
+			// The hostname is always the last field (ie. `10 mail.mydomain.com` for MX)
+			fields := strings.Fields(target)
+			if len(fields) == 0 {
+				return nil, providers.Permanent(fmt.Errorf("PB-HZ-#0007: Invalid target (%s) for %s record", target, r.Spec.RecordType))
+			}
+
+			if last := fields[len(fields)-1]; !strings.HasSuffix(last, ".") {
+				fields[len(fields)-1] = last + "."
+			}
+			target = strings.Join(fields, " ")
+		}
+
+		records = append(records, record{
+			ZoneID: h.zoneIDs[r.Spec.Zone],
+			Type:   r.Spec.RecordType,
+			Name:   recordName(r),
+			Value:  target,
+			TTL:    r.Spec.TTL,
+		})
+	}
+
+	return records, nil
+}
+
+// Send a request to the API. The body, if any, is encoded as JSON and the response is
+// decoded into out when it's set.
+func (h *hetzner) do(ctx context.Context, method, path string, query url.Values, body any, out any) error {
+	endpoint := h.baseURL + "/" + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Auth-API-Token", h.apiToken)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return newAPIError(resp)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// apiError is the error Hetzner returns in the body of failed requests.
+type apiError struct {
+	StatusCode int
+	RetryAfter string
+	Message    string
+}
+
+func newAPIError(resp *http.Response) *apiError {
+	apiErr := &apiError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: resp.Header.Get("Retry-After"),
+	}
+
+	// The message is either nested in an error object or at the root of the body, depending on the endpoint.
+	var body struct {
+		Message string `json:"message"`
+		Error   struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil {
+		apiErr.Message = body.Error.Message
+		if apiErr.Message == "" {
+			apiErr.Message = body.Message
+		}
+	}
+
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return apiErr
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+// Return the status code of the API's response, or zero if the request failed before a response was received.
+func statusCode(err error) int {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+
+	return 0
+}
+
+// classify wraps err with its kind based on the status code returned by Hetzner's API.
+func classify(err error) error {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return providers.FromHTTPStatus(err, apiErr.StatusCode, apiErr.RetryAfter)
+	}
+
+	return err
+}
+
+// Name of the record relative to the zone, as expected by Hetzner.
+func recordName(r *phonebook.DNSRecord) string {
+	if r.Spec.Name == "" {
+		return "@"
+	}
+
+	return r.Spec.Name
+}
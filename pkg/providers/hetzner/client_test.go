@@ -0,0 +1,346 @@
+package hetzner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"github.com/pier-oliviert/phonebook/pkg/providers/conformance"
+)
+
+// fakeHetzner mimics the subset of Hetzner DNS' API used by the provider. Records are
+// listed one per page to exercise the pagination.
+type fakeHetzner struct {
+	mu      sync.Mutex
+	zones   map[string]string
+	records map[string]record
+	nextID  int
+	status  int
+
+	// When set, the API fails with a 503 once that many records were created.
+	quota int
+}
+
+var supportedTypes = map[string]bool{"A": true, "AAAA": true, "CAA": true, "CNAME": true, "MX": true, "NS": true, "PTR": true, "SRV": true, "TXT": true}
+
+func (f *fakeHetzner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fail := func(status int, message string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": message, "code": status}})
+	}
+
+	if r.Header.Get("Auth-API-Token") != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid authentication credentials"})
+		return
+	}
+
+	if f.status != 0 {
+		w.Header().Set("Retry-After", "3")
+		fail(f.status, http.StatusText(f.status))
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/")
+	switch {
+	case path == "zones":
+		id, ok := f.zones[r.URL.Query().Get("name")]
+		if !ok {
+			fail(http.StatusNotFound, "zone not found")
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"zones": []zone{{ID: id, Name: r.URL.Query().Get("name")}}})
+
+	case strings.HasPrefix(path, "zones/"):
+		for name, id := range f.zones {
+			if id == strings.TrimPrefix(path, "zones/") {
+				json.NewEncoder(w).Encode(map[string]any{"zone": zone{ID: id, Name: name}})
+				return
+			}
+		}
+		fail(http.StatusNotFound, "zone not found")
+
+	case path == "records" && r.Method == http.MethodGet:
+		var records []record
+		for _, rec := range f.records {
+			if rec.ZoneID == r.URL.Query().Get("zone_id") {
+				records = append(records, rec)
+			}
+		}
+		// Records are paginated, so they need to be listed in the same order for every page.
+		sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		response := map[string]any{"records": []record{}, "meta": map[string]any{"pagination": map[string]int{"last_page": len(records)}}}
+		if page > 0 && page <= len(records) {
+			response["records"] = records[page-1 : page]
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case path == "records" && r.Method == http.MethodPost:
+		if f.quota != 0 && f.nextID >= f.quota {
+			fail(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+			return
+		}
+
+		var rec record
+		json.NewDecoder(r.Body).Decode(&rec)
+
+		if !supportedTypes[rec.Type] {
+			fail(http.StatusUnprocessableEntity, "invalid record type")
+			return
+		}
+
+		f.nextID++
+		rec.ID = fmt.Sprintf("rec%d", f.nextID)
+		f.records[rec.ID] = rec
+		json.NewEncoder(w).Encode(map[string]any{"record": rec})
+
+	case strings.HasPrefix(path, "records/"):
+		id := strings.TrimPrefix(path, "records/")
+		if _, ok := f.records[id]; !ok {
+			fail(http.StatusNotFound, "record not found")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			var rec record
+			json.NewDecoder(r.Body).Decode(&rec)
+
+			rec.ID = id
+			f.records[id] = rec
+			json.NewEncoder(w).Encode(map[string]any{"record": rec})
+
+		case http.MethodDelete:
+			delete(f.records, id)
+		}
+
+	default:
+		fail(http.StatusNotFound, "not found")
+	}
+}
+
+func newTestClient(t *testing.T, fake *fakeHetzner) *hetzner {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	t.Setenv(kHetznerAPIToken, "secret")
+	t.Setenv(kHetznerAPIURL, server.URL+"/api/v1")
+
+	h, err := NewClient(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+func TestZoneIDs(t *testing.T) {
+	fake := &fakeHetzner{zones: map[string]string{"mydomain.com": "zone1", "otherdomain.com": "zone2"}}
+	h := newTestClient(t, fake)
+
+	if err := h.Configure(context.TODO(), "hetzner-test", []string{"mydomain.com", "otherdomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if h.zoneIDs["mydomain.com"] != "zone1" || h.zoneIDs["otherdomain.com"] != "zone2" {
+		t.Errorf("Expected the zone IDs to be resolved, got: %v", h.zoneIDs)
+	}
+
+	delete(fake.zones, "otherdomain.com")
+	if err := h.Check(context.TODO(), "otherdomain.com"); err == nil || !strings.HasPrefix(err.Error(), "PB-HZ-#0002") {
+		t.Errorf("Expected the check to fail for a zone that was deleted, got: %v", err)
+	}
+
+	err := h.Configure(context.TODO(), "hetzner-test", []string{"unknown.com"})
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-HZ-#0002") {
+		t.Errorf("Expected an error for a zone that doesn't exist, got: %v", err)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	h := &hetzner{zoneIDs: map[string]string{"mydomain.com": "zone1"}}
+
+	tests := []struct {
+		name       string
+		recordType string
+		target     string
+		expected   record
+	}{
+		{name: "", recordType: "TXT", target: "some value", expected: record{Name: "@", Value: "\"some value\""}},
+		{name: "www", recordType: "CNAME", target: "mydomain.com", expected: record{Name: "www", Value: "mydomain.com."}},
+		{name: "mail", recordType: "MX", target: "10 mail.mydomain.com", expected: record{Name: "mail", Value: "10 mail.mydomain.com."}},
+		{name: "_sip._tcp", recordType: "SRV", target: "10 5 5060 sip.mydomain.com.", expected: record{Name: "_sip._tcp", Value: "10 5 5060 sip.mydomain.com."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.recordType, func(t *testing.T) {
+			records, err := h.records(&phonebook.DNSRecord{
+				Spec: phonebook.DNSRecordSpec{
+					Zone:       "mydomain.com",
+					Name:       tt.name,
+					RecordType: tt.recordType,
+					Targets:    []string{tt.target},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.expected.ZoneID = "zone1"
+			tt.expected.Type = tt.recordType
+			if records[0] != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, records[0])
+			}
+		})
+	}
+}
+
+func TestRecordsInvalidTarget(t *testing.T) {
+	h := &hetzner{zoneIDs: map[string]string{"mydomain.com": "zone1"}}
+
+	for _, target := range []string{"", "  "} {
+		_, err := h.records(&phonebook.DNSRecord{
+			Spec: phonebook.DNSRecordSpec{
+				Zone:       "mydomain.com",
+				Name:       "mail",
+				RecordType: "MX",
+				Targets:    []string{target},
+			},
+		})
+
+		if err == nil || !strings.HasPrefix(err.Error(), "PB-HZ-#0007") || providers.Retryable(err) {
+			t.Errorf("Expected a permanent error for the target %q, got: %v", target, err)
+		}
+	}
+}
+
+func TestDeleteWithoutRecordIDs(t *testing.T) {
+	fake := &fakeHetzner{zones: map[string]string{"mydomain.com": "zone1"}, records: map[string]record{
+		"rec1": {ID: "rec1", ZoneID: "zone1", Type: "A", Name: "www", Value: "127.0.0.1"},
+		"rec2": {ID: "rec2", ZoneID: "zone1", Type: "A", Name: "www", Value: "10.0.0.1"},
+		"rec3": {ID: "rec3", ZoneID: "zone1", Type: "A", Name: "other", Value: "127.0.0.1"},
+	}}
+	h := newTestClient(t, fake)
+	if err := h.Configure(context.TODO(), "hetzner-test", []string{"mydomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+
+	if err := h.Delete(context.TODO(), record, &mocks.Updater{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fake.records["rec1"]; ok {
+		t.Error("Expected the record matching the target to be deleted")
+	}
+
+	if _, ok := fake.records["rec2"]; !ok {
+		t.Error("Expected the record with a value that isn't one of the targets to be kept")
+	}
+
+	if _, ok := fake.records["rec3"]; !ok {
+		t.Error("Expected records with a different name to be kept")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	fake := &fakeHetzner{zones: map[string]string{"mydomain.com": "zone1"}, records: map[string]record{}}
+	h := newTestClient(t, fake)
+	if err := h.Configure(context.TODO(), "hetzner-test", []string{"mydomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+
+	fake.status = http.StatusTooManyRequests
+	err := h.Create(context.TODO(), record, &mocks.Updater{})
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-HZ-#0003") || providers.Kind(err) != providers.ErrorRateLimited || providers.RetryAfter(err).Seconds() != 3 {
+		t.Errorf("Expected a rate limited error, got: %v", err)
+	}
+
+	fake.status = 0
+	h.apiToken = "wrong"
+	err = h.Create(context.TODO(), record, &mocks.Updater{})
+	if err == nil || !strings.Contains(err.Error(), "401: Invalid authentication credentials") || providers.Retryable(err) {
+		t.Errorf("Expected a permanent error with the API's message, got: %v", err)
+	}
+}
+
+func TestCreatePartialFailure(t *testing.T) {
+	fake := &fakeHetzner{zones: map[string]string{"mydomain.com": "zone1"}, records: map[string]record{}, quota: 1}
+	h := newTestClient(t, fake)
+	if err := h.Configure(context.TODO(), "hetzner-test", []string{"mydomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1", "127.0.0.2"},
+		},
+	}
+
+	updater := &mocks.Updater{}
+	if err := h.Create(context.TODO(), record, updater); err == nil || !providers.Retryable(err) {
+		t.Fatalf("Expected a retryable error, got: %v", err)
+	}
+
+	if updater.Info["recordIDs"] != "rec1" {
+		t.Fatalf("Expected the record created before the failure to be staged, got: %v", updater.Info)
+	}
+
+	// The retry starts from the records that were created by the failed attempt.
+	fake.quota = 0
+	record.Status.RemoteInfo = map[string]phonebook.IntegrationInfo{"hetzner-test": updater.Info}
+	if err := h.Create(context.TODO(), record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	if updater.Info["recordIDs"] != "rec1,rec2" || len(fake.records) != 2 {
+		t.Errorf("Expected one record per target, got: %v, %v", updater.Info, fake.records)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, conformance.Suite{
+		Zone: "mydomain.com",
+		NewProvider: func(t *testing.T) providers.Provider {
+			return newTestClient(t, &fakeHetzner{
+				zones:   map[string]string{"mydomain.com": "zone1"},
+				records: map[string]record{},
+			})
+		},
+	})
+}
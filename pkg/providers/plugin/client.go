@@ -0,0 +1,342 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"github.com/pier-oliviert/phonebook/pkg/providers/plugin/proto"
+	utils "github.com/pier-oliviert/phonebook/pkg/utils"
+)
+
+const (
+	kPluginPath           = "PLUGIN_PATH"
+	kPluginHealthInterval = "PLUGIN_HEALTH_INTERVAL"
+	defaultHealthInterval = 10 * time.Second
+)
+
+// Plugin is a provider running as a separate process that Phonebook talks to with gRPC over a Unix socket. The
+// process is started by NewClient and inherits the provider's environment, which includes the integration's secrets.
+//
+// The plugin is health checked in the background once it's configured. When it crashes or stops answering health
+// checks, it's restarted and configured again with the same integration and zones. Operations that happen while the
+// plugin is down fail with a transient error so the record is retried.
+type Plugin struct {
+	path     string
+	interval time.Duration
+	logger   hclog.Logger
+
+	mu        sync.Mutex
+	process   *goplugin.Client
+	protocol  goplugin.ClientProtocol
+	provider  proto.ProviderClient
+	restarts  int
+	configure *proto.ConfigureRequest
+	zones     []string
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// pluginReader is returned for plugins that can read records back.
+type pluginReader struct {
+	*Plugin
+}
+
+func init() {
+	providers.Register("plugin", NewClient)
+}
+
+// NewClient starts the plugin located at PLUGIN_PATH. The Provider returned implements providers.Reader when
+// the plugin supports reading records.
+func NewClient(ctx context.Context) (providers.Provider, error) {
+	path, err := utils.RetrieveValue(ctx, kPluginPath)
+	if err != nil {
+		return nil, fmt.Errorf("PB-PLG-#0001: Plugin path not found -- %w", err)
+	}
+
+	interval := defaultHealthInterval
+	if value, _ := utils.RetrieveValue(ctx, kPluginHealthInterval); strings.TrimSpace(value) != "" {
+		if interval, err = time.ParseDuration(strings.TrimSpace(value)); err != nil || interval <= 0 {
+			return nil, fmt.Errorf("PB-PLG-#0001: Invalid value for %s -- %v", kPluginHealthInterval, err)
+		}
+	}
+
+	p := &Plugin{
+		path:     strings.TrimSpace(path),
+		interval: interval,
+		logger:   hclog.New(&hclog.LoggerOptions{Name: "plugin", Level: hclog.Info}),
+		stop:     make(chan struct{}),
+	}
+
+	if err := p.start(); err != nil {
+		return nil, fmt.Errorf("PB-PLG-#0002: Unable to start plugin %s -- %w", p.path, err)
+	}
+
+	capabilities, err := p.provider.Capabilities(ctx, &proto.CapabilitiesRequest{})
+	if err != nil {
+		p.Close()
+		return nil, fmt.Errorf("PB-PLG-#0002: Unable to start plugin %s -- %w", p.path, fromStatus(err))
+	}
+
+	if capabilities.GetReader() {
+		return &pluginReader{p}, nil
+	}
+
+	return p, nil
+}
+
+func (p *Plugin) Configure(ctx context.Context, integration string, zones []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	req := &proto.ConfigureRequest{Integration: integration, Zones: zones}
+	resp, err := p.provider.Configure(ctx, req)
+	if err != nil {
+		return fmt.Errorf("PB-PLG-#0003: Plugin refused the configuration -- %w", fromStatus(err))
+	}
+
+	if p.configure == nil {
+		go p.supervise(log.FromContext(ctx))
+	}
+
+	p.configure = req
+	p.zones = resp.GetZones()
+
+	log.FromContext(ctx).Info("[Provider] Plugin configured", "Path", p.path, "Zones", p.zones)
+	return nil
+}
+
+func (p *Plugin) Zones() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.zones
+}
+
+func (p *Plugin) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	c, err := p.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("PB-PLG-#0004: Failed to create DNS record -- %w", err)
+	}
+
+	resp, err := c.Create(ctx, &proto.RecordRequest{Record: toRecord(record)})
+	if err != nil {
+		return fmt.Errorf("PB-PLG-#0004: Failed to create DNS record -- %w", fromStatus(err))
+	}
+
+	stage(resp.GetStaging(), su)
+	return nil
+}
+
+func (p *Plugin) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	c, err := p.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("PB-PLG-#0005: Failed to update DNS record -- %w", err)
+	}
+
+	resp, err := c.Update(ctx, &proto.RecordRequest{Record: toRecord(record)})
+	if err != nil {
+		return fmt.Errorf("PB-PLG-#0005: Failed to update DNS record -- %w", fromStatus(err))
+	}
+
+	stage(resp.GetStaging(), su)
+	return nil
+}
+
+func (p *Plugin) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	c, err := p.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("PB-PLG-#0006: Failed to delete DNS record -- %w", err)
+	}
+
+	resp, err := c.Delete(ctx, &proto.RecordRequest{Record: toRecord(record)})
+	if err != nil {
+		return fmt.Errorf("PB-PLG-#0006: Failed to delete DNS record -- %w", fromStatus(err))
+	}
+
+	stage(resp.GetStaging(), su)
+	return nil
+}
+
+func (r *pluginReader) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	c, err := r.connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("PB-PLG-#0007: Failed to read DNS record -- %w", err)
+	}
+
+	resp, err := c.Read(ctx, &proto.RecordRequest{Record: toRecord(record)})
+	if status.Code(err) == codes.NotFound {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("PB-PLG-#0007: Failed to read DNS record -- %w", fromStatus(err))
+	}
+
+	return &providers.RemoteRecord{Targets: resp.GetTargets(), TTL: resp.Ttl}, nil
+}
+
+// Close stops the health checks and the plugin's process.
+func (p *Plugin) Close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.process.Kill()
+}
+
+// Cleanup stops the processes of every plugin. It's meant to be deferred in the main function of a provider so
+// plugins don't outlive it.
+func Cleanup() {
+	goplugin.CleanupClients()
+}
+
+// Restarts returns how many times the plugin was restarted since it was started.
+func (p *Plugin) Restarts() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.restarts
+}
+
+// Return the client of the plugin, restarting it first if its process exited.
+func (p *Plugin) connect(ctx context.Context) (proto.ProviderClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.process.Exited() {
+		if err := p.restart(ctx); err != nil {
+			return nil, providers.Transient(err)
+		}
+	}
+
+	return p.provider, nil
+}
+
+// Health check the plugin at every interval and restart it when it's not healthy.
+func (p *Plugin) supervise(logger logr.Logger) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		process, protocol := p.process, p.protocol
+		p.mu.Unlock()
+
+		if !process.Exited() && p.healthy(protocol) {
+			continue
+		}
+
+		p.mu.Lock()
+		// The plugin might have been restarted by an operation while it was checked.
+		if p.process == process {
+			if err := p.restart(context.Background()); err != nil {
+				logger.Error(err, "[Provider] Plugin is unhealthy and couldn't be restarted", "Path", p.path)
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// Check the health service that go-plugin registers in the plugin. A plugin that doesn't
+// answer within the interval is unhealthy.
+func (p *Plugin) healthy(protocol goplugin.ClientProtocol) bool {
+	client, ok := protocol.(*goplugin.GRPCClient)
+	if !ok {
+		return protocol.Ping() == nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.interval)
+	defer cancel()
+
+	resp, err := healthpb.NewHealthClient(client.Conn).Check(ctx, &healthpb.HealthCheckRequest{Service: goplugin.GRPCServiceName})
+	return err == nil && resp.GetStatus() == healthpb.HealthCheckResponse_SERVING
+}
+
+// Start the plugin's process and connect to it. The caller needs to hold the lock unless the plugin
+// isn't shared yet.
+func (p *Plugin) start() error {
+	process := goplugin.NewClient(&goplugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		Plugins:          goplugin.PluginSet{pluginName: &providerPlugin{}},
+		Cmd:              exec.Command(p.path),
+		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+		Logger:           p.logger,
+		Stderr:           os.Stderr,
+		Managed:          true,
+	})
+
+	protocol, err := process.Client()
+	if err != nil {
+		process.Kill()
+		return err
+	}
+
+	raw, err := protocol.Dispense(pluginName)
+	if err != nil {
+		process.Kill()
+		return err
+	}
+
+	provider, ok := raw.(proto.ProviderClient)
+	if !ok {
+		process.Kill()
+		return errors.New("the plugin doesn't implement the provider service")
+	}
+
+	p.process = process
+	p.protocol = protocol
+	p.provider = provider
+	return nil
+}
+
+// Restart the plugin and configure it again. The caller needs to hold the lock.
+func (p *Plugin) restart(ctx context.Context) error {
+	select {
+	case <-p.stop:
+		return errors.New("the plugin was closed")
+	default:
+	}
+
+	p.process.Kill()
+	p.restarts++
+
+	if err := p.start(); err != nil {
+		return fmt.Errorf("PB-PLG-#0002: Unable to restart plugin %s -- %w", p.path, err)
+	}
+
+	if p.configure != nil {
+		resp, err := p.provider.Configure(ctx, p.configure)
+		if err != nil {
+			return fmt.Errorf("PB-PLG-#0003: Plugin refused the configuration -- %w", fromStatus(err))
+		}
+
+		p.zones = resp.GetZones()
+	}
+
+	log.FromContext(ctx).Info("[Provider] Plugin restarted", "Path", p.path, "Restarts", p.restarts)
+	return nil
+}
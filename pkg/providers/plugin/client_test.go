@@ -0,0 +1,271 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+)
+
+const kTestPlugin = "PHONEBOOK_TEST_PLUGIN"
+
+// The test binary is also the plugin: when it's started by the tests with kTestPlugin set,
+// it serves the fake provider instead of running the tests.
+func TestMain(m *testing.M) {
+	if os.Getenv(kTestPlugin) != "" {
+		Serve(&fakeProvider{records: map[string]phonebook.DNSRecordSpec{}})
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// fakeProvider stores records in memory. The name of a record can be used to make the provider
+// fail (ie. "crash" exits the plugin's process).
+type fakeProvider struct {
+	mu      sync.Mutex
+	zones   []string
+	records map[string]phonebook.DNSRecordSpec
+}
+
+func (f *fakeProvider) Configure(ctx context.Context, integration string, zones []string) error {
+	if integration == "" {
+		return errors.New("PB-FAKE-#0001: integration is required")
+	}
+
+	f.zones = zones
+	return nil
+}
+
+func (f *fakeProvider) Zones() []string {
+	return f.zones
+}
+
+func (f *fakeProvider) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch record.Spec.Name {
+	case "crash":
+		os.Exit(1)
+	case "invalid":
+		return providers.Permanent(errors.New("PB-FAKE-#0002: invalid record"))
+	case "limited":
+		return providers.RateLimited(errors.New("PB-FAKE-#0003: slow down"), 2*time.Second)
+	case "unavailable":
+		return providers.Transient(errors.New("PB-FAKE-#0004: unavailable"))
+	}
+
+	f.records[record.Namespace+"/"+record.Name] = record.Spec
+	su.StageRemoteInfo(phonebook.IntegrationInfo{"id": string(record.UID)})
+	su.StageCondition(konditions.ConditionCreated, "Record created")
+	return nil
+}
+
+func (f *fakeProvider) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// The RemoteInfo staged on creation is sent back to the plugin.
+	if record.Status.RemoteInfo["plugin-test"]["id"] != string(record.UID) {
+		return providers.Permanent(errors.New("PB-FAKE-#0005: missing remote info"))
+	}
+
+	f.records[record.Namespace+"/"+record.Name] = record.Spec
+	su.StageCondition(konditions.ConditionCreated, "Record updated")
+	return nil
+}
+
+func (f *fakeProvider) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.records, record.Namespace+"/"+record.Name)
+	su.StageCondition(konditions.ConditionTerminated, "Record deleted")
+	return nil
+}
+
+func (f *fakeProvider) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	spec, ok := f.records[record.Namespace+"/"+record.Name]
+	if !ok {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	return &providers.RemoteRecord{Targets: spec.Targets, TTL: spec.TTL}, nil
+}
+
+func newTestPlugin(t *testing.T) *pluginReader {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(kTestPlugin, "1")
+	t.Setenv(kPluginPath, executable)
+	t.Setenv(kPluginHealthInterval, "100ms")
+
+	p, err := NewClient(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, ok := p.(*pluginReader)
+	if !ok {
+		t.Fatalf("Expected the plugin to support reading records, got: %T", p)
+	}
+	t.Cleanup(reader.Close)
+
+	if err := reader.Configure(context.TODO(), "plugin-test", []string{"mydomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	return reader
+}
+
+func testRecord(name string) phonebook.DNSRecord {
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       name,
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+	record.Namespace = "default"
+	record.Name = name
+	record.UID = "1234"
+
+	return record
+}
+
+func TestLifecycle(t *testing.T) {
+	p := newTestPlugin(t)
+
+	if zones := p.Zones(); len(zones) != 1 || zones[0] != "mydomain.com" {
+		t.Errorf("Expected the zones returned by the plugin, got: %v", zones)
+	}
+
+	record := testRecord("www")
+	updater := &mocks.Updater{}
+	if err := p.Create(context.TODO(), record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	if *updater.Status != konditions.ConditionCreated || updater.Info["id"] != "1234" {
+		t.Fatalf("Expected the values staged by the plugin, got: %s, %v", *updater.Status, updater.Info)
+	}
+
+	ttl := int64(60)
+	record.Status.RemoteInfo = map[string]phonebook.IntegrationInfo{"plugin-test": updater.Info}
+	record.Spec.TTL = &ttl
+	record.Spec.Targets = []string{"127.0.0.2"}
+
+	if err := p.Update(context.TODO(), record, &mocks.Updater{}); err != nil {
+		t.Fatal(err)
+	}
+
+	remote, err := p.Read(context.TODO(), record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !remote.Matches(record.Spec) {
+		t.Errorf("Expected the remote record to match the spec, got: %v", remote.Targets)
+	}
+
+	updater = &mocks.Updater{}
+	if err := p.Delete(context.TODO(), record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	if *updater.Status != konditions.ConditionTerminated {
+		t.Errorf("Expected the record to be deleted, got: %s", *updater.Status)
+	}
+
+	if _, err := p.Read(context.TODO(), record); !errors.Is(err, providers.ErrRecordNotFound) {
+		t.Errorf("Expected the record to not be found, got: %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	p := newTestPlugin(t)
+
+	err := p.Create(context.TODO(), testRecord("invalid"), &mocks.Updater{})
+	if err == nil || !strings.Contains(err.Error(), "PB-FAKE-#0002") || providers.Retryable(err) {
+		t.Errorf("Expected a permanent error with the plugin's code, got: %v", err)
+	}
+
+	err = p.Create(context.TODO(), testRecord("limited"), &mocks.Updater{})
+	if providers.Kind(err) != providers.ErrorRateLimited || providers.RetryAfter(err) != 2*time.Second {
+		t.Errorf("Expected a rate limited error with its delay, got: %v", err)
+	}
+
+	err = p.Create(context.TODO(), testRecord("unavailable"), &mocks.Updater{})
+	if providers.Kind(err) != providers.ErrorTransient {
+		t.Errorf("Expected a transient error, got: %v", err)
+	}
+
+	err = p.Configure(context.TODO(), "", nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-PLG-#0003") {
+		t.Errorf("Expected the configuration to be refused, got: %v", err)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	p := newTestPlugin(t)
+
+	err := p.Create(context.TODO(), testRecord("crash"), &mocks.Updater{})
+	if !providers.Retryable(err) {
+		t.Fatalf("Expected a crash to be retryable, got: %v", err)
+	}
+
+	// The plugin is restarted by the next operation, or by the health check. Operations that happen
+	// before the crash is noticed fail with a transient error and are retried by the reconciler.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err = p.Create(context.TODO(), testRecord("www"), &mocks.Updater{})
+		if err == nil {
+			break
+		}
+
+		if !providers.Retryable(err) || time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if p.Restarts() != 1 {
+		t.Errorf("Expected the plugin to be restarted once, got: %d", p.Restarts())
+	}
+
+	if zones := p.Zones(); len(zones) != 1 || zones[0] != "mydomain.com" {
+		t.Errorf("Expected the plugin to be configured again, got: %v", zones)
+	}
+
+	// Stopping the plugin's process out of band is caught by the health check.
+	p.mu.Lock()
+	p.process.Kill()
+	p.mu.Unlock()
+
+	deadline = time.Now().Add(5 * time.Second)
+	for p.Restarts() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the health check to restart the plugin")
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
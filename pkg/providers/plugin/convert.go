@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/types"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"github.com/pier-oliviert/phonebook/pkg/providers/plugin/proto"
+)
+
+func toRecord(record phonebook.DNSRecord) *proto.Record {
+	r := &proto.Record{
+		Namespace:  record.Namespace,
+		Name:       record.Name,
+		Uid:        string(record.UID),
+		Generation: record.Generation,
+		Spec: &proto.RecordSpec{
+			Zone:        record.Spec.Zone,
+			RecordType:  record.Spec.RecordType,
+			Name:        record.Spec.Name,
+			Targets:     record.Spec.Targets,
+			Properties:  record.Spec.Properties,
+			Ttl:         record.Spec.TTL,
+			Integration: record.Spec.Integration,
+		},
+	}
+
+	if len(record.Status.RemoteInfo) != 0 {
+		r.RemoteInfo = map[string]*proto.RemoteInfo{}
+		for integration, info := range record.Status.RemoteInfo {
+			r.RemoteInfo[integration] = &proto.RemoteInfo{Values: info}
+		}
+	}
+
+	return r
+}
+
+func fromRecord(r *proto.Record) phonebook.DNSRecord {
+	var record phonebook.DNSRecord
+	record.Namespace = r.GetNamespace()
+	record.Name = r.GetName()
+	record.UID = types.UID(r.GetUid())
+	record.Generation = r.GetGeneration()
+
+	if spec := r.GetSpec(); spec != nil {
+		record.Spec = phonebook.DNSRecordSpec{
+			Zone:        spec.GetZone(),
+			RecordType:  spec.GetRecordType(),
+			Name:        spec.GetName(),
+			Targets:     spec.GetTargets(),
+			Properties:  spec.GetProperties(),
+			TTL:         spec.Ttl,
+			Integration: spec.Integration,
+		}
+	}
+
+	if len(r.GetRemoteInfo()) != 0 {
+		record.Status.RemoteInfo = map[string]phonebook.IntegrationInfo{}
+		for integration, info := range r.GetRemoteInfo() {
+			record.Status.RemoteInfo[integration] = info.GetValues()
+		}
+	}
+
+	return record
+}
+
+// staging is the StagingUpdater given to the plugin's provider. The values it stages are sent back to
+// Phonebook in the response.
+type staging struct {
+	proto.Staging
+}
+
+func (s *staging) StageCondition(status konditions.ConditionStatus, reason string) {
+	s.Condition = &proto.Condition{Status: string(status), Reason: reason}
+}
+
+func (s *staging) StageRemoteInfo(info phonebook.IntegrationInfo) {
+	s.RemoteInfo = &proto.RemoteInfo{Values: info}
+}
+
+// Apply the values staged by the plugin to the StagingUpdater of the reconciler.
+func stage(s *proto.Staging, su phonebook.StagingUpdater) {
+	if s == nil {
+		return
+	}
+
+	if info := s.GetRemoteInfo(); info != nil {
+		su.StageRemoteInfo(info.GetValues())
+	}
+
+	if condition := s.GetCondition(); condition != nil {
+		su.StageCondition(konditions.ConditionStatus(condition.GetStatus()), condition.GetReason())
+	}
+}
+
+// Convert an error returned by the plugin's provider to a gRPC status so its kind survives the trip
+// back to Phonebook.
+func toStatus(err error) error {
+	if errors.Is(err, providers.ErrRecordNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+
+	switch providers.Kind(err) {
+	case providers.ErrorTransient:
+		return status.Error(codes.Unavailable, err.Error())
+	case providers.ErrorRateLimited:
+		s := status.New(codes.ResourceExhausted, err.Error())
+		if retryAfter := providers.RetryAfter(err); retryAfter > 0 {
+			if detailed, err := s.WithDetails(&proto.ErrorDetail{RetryAfterMs: retryAfter.Milliseconds()}); err == nil {
+				s = detailed
+			}
+		}
+		return s.Err()
+	default:
+		return status.Error(codes.Unknown, err.Error())
+	}
+}
+
+// Convert a gRPC status returned by the plugin to an error classified the same way the plugin's
+// provider classified it. Failures to reach the plugin (ie. the plugin crashed) are transient.
+func fromStatus(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	cause := errors.New(s.Message())
+
+	switch s.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.Canceled:
+		return providers.Transient(cause)
+	case codes.ResourceExhausted:
+		var retryAfter time.Duration
+		for _, detail := range s.Details() {
+			if d, ok := detail.(*proto.ErrorDetail); ok {
+				retryAfter = time.Duration(d.GetRetryAfterMs()) * time.Millisecond
+			}
+		}
+		return providers.RateLimited(cause, retryAfter)
+	default:
+		return providers.Permanent(cause)
+	}
+}
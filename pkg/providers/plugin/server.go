@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"github.com/pier-oliviert/phonebook/pkg/providers/plugin/proto"
+)
+
+// Handshake is shared between Phonebook and its plugins. A plugin started with a different
+// protocol version is refused.
+var Handshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "PHONEBOOK_PLUGIN",
+	MagicCookieValue: "provider",
+}
+
+const pluginName = "provider"
+
+// Serve the provider as a plugin. It's meant to be called from the main function of the plugin's binary
+// and blocks until Phonebook stops the plugin.
+//
+//	func main() {
+//		plugin.Serve(acme.NewClient())
+//	}
+func Serve(p providers.Provider) {
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         goplugin.PluginSet{pluginName: &providerPlugin{provider: p}},
+		GRPCServer:      goplugin.DefaultGRPCServer,
+		Logger:          hclog.New(&hclog.LoggerOptions{Name: "plugin", Level: hclog.Info, JSONFormat: true}),
+	})
+}
+
+// providerPlugin is the go-plugin definition of a provider. Only gRPC is supported.
+type providerPlugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+
+	// Only set in the plugin's process.
+	provider providers.Provider
+}
+
+func (p *providerPlugin) GRPCServer(broker *goplugin.GRPCBroker, s *grpc.Server) error {
+	proto.RegisterProviderServer(s, &server{provider: p.provider})
+	return nil
+}
+
+func (p *providerPlugin) GRPCClient(ctx context.Context, broker *goplugin.GRPCBroker, conn *grpc.ClientConn) (interface{}, error) {
+	return proto.NewProviderClient(conn), nil
+}
+
+// server runs in the plugin's process and calls the provider for each request it receives from Phonebook.
+type server struct {
+	proto.UnimplementedProviderServer
+
+	provider providers.Provider
+}
+
+func (s *server) Capabilities(ctx context.Context, req *proto.CapabilitiesRequest) (*proto.CapabilitiesResponse, error) {
+	_, reader := s.provider.(providers.Reader)
+	return &proto.CapabilitiesResponse{Reader: reader}, nil
+}
+
+func (s *server) Configure(ctx context.Context, req *proto.ConfigureRequest) (*proto.ConfigureResponse, error) {
+	if err := s.provider.Configure(ctx, req.GetIntegration(), req.GetZones()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &proto.ConfigureResponse{Zones: s.provider.Zones()}, nil
+}
+
+func (s *server) Create(ctx context.Context, req *proto.RecordRequest) (*proto.RecordResponse, error) {
+	su := &staging{}
+	if err := s.provider.Create(ctx, fromRecord(req.GetRecord()), su); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &proto.RecordResponse{Staging: &su.Staging}, nil
+}
+
+func (s *server) Update(ctx context.Context, req *proto.RecordRequest) (*proto.RecordResponse, error) {
+	su := &staging{}
+	if err := s.provider.Update(ctx, fromRecord(req.GetRecord()), su); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &proto.RecordResponse{Staging: &su.Staging}, nil
+}
+
+func (s *server) Delete(ctx context.Context, req *proto.RecordRequest) (*proto.RecordResponse, error) {
+	su := &staging{}
+	if err := s.provider.Delete(ctx, fromRecord(req.GetRecord()), su); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &proto.RecordResponse{Staging: &su.Staging}, nil
+}
+
+func (s *server) Read(ctx context.Context, req *proto.RecordRequest) (*proto.ReadResponse, error) {
+	reader, ok := s.provider.(providers.Reader)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the provider doesn't support reading records")
+	}
+
+	remote, err := reader.Read(ctx, fromRecord(req.GetRecord()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	if remote == nil {
+		return nil, toStatus(providers.ErrRecordNotFound)
+	}
+
+	return &proto.ReadResponse{Targets: remote.Targets, Ttl: remote.TTL}, nil
+}
@@ -0,0 +1,330 @@
+package powerdns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	utils "github.com/pier-oliviert/phonebook/pkg/utils"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	kPowerDNSAPIURL   = "PDNS_API_URL"
+	kPowerDNSAPIKey   = "PDNS_API_KEY"
+	kPowerDNSServerID = "PDNS_SERVER_ID"
+	defaultServerID   = "localhost"
+	defaultTTL        = int64(60) // Default TTL for DNS records in seconds if not specified
+)
+
+type powerDNS struct {
+	integration string
+	zones       []string
+
+	// Base URL of the API (ie. http://pdns:8081) and the server the zones belong to. PowerDNS
+	// only has one server, named localhost, unless it's behind a proxy that aggregates multiple servers.
+	baseURL  *url.URL
+	serverID string
+	apiKey   string
+
+	client *http.Client
+}
+
+// rrset as represented by PowerDNS' API. Names are always canonical (with the trailing dot).
+type rrset struct {
+	Name       string           `json:"name"`
+	Type       string           `json:"type"`
+	TTL        int64            `json:"ttl,omitempty"`
+	ChangeType string           `json:"changetype,omitempty"`
+	Records    []resourceRecord `json:"records"`
+}
+
+type resourceRecord struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+type zone struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	RRSets []rrset `json:"rrsets"`
+}
+
+func init() {
+	providers.Register("powerdns", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
+}
+
+// NewClient creates a provider for PowerDNS Authoritative's HTTP API located at PDNS_API_URL. The API key
+// is the value of `api-key` in PowerDNS' configuration.
+func NewClient(ctx context.Context) (*powerDNS, error) {
+	rawURL, err := utils.RetrieveValue(ctx, kPowerDNSAPIURL)
+	if err != nil {
+		return nil, fmt.Errorf("PB-PDNS-#0001: API URL not found -- %w", err)
+	}
+
+	baseURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || baseURL.Host == "" {
+		return nil, fmt.Errorf("PB-PDNS-#0001: API URL (%s) is not a valid URL -- %v", rawURL, err)
+	}
+
+	apiKey, err := utils.RetrieveValue(ctx, kPowerDNSAPIKey)
+	if err != nil {
+		return nil, fmt.Errorf("PB-PDNS-#0002: API Key not found -- %w", err)
+	}
+
+	serverID, _ := utils.RetrieveValue(ctx, kPowerDNSServerID)
+	if serverID = strings.TrimSpace(serverID); serverID == "" {
+		serverID = defaultServerID
+	}
+
+	return &powerDNS{
+		baseURL:  baseURL,
+		serverID: serverID,
+		apiKey:   strings.TrimSpace(apiKey),
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Configure makes sure each of the zones exists on the server.
+func (p *powerDNS) Configure(ctx context.Context, integration string, zones []string) error {
+	for _, z := range zones {
+		if err := p.Check(ctx, z); err != nil {
+			return err
+		}
+	}
+
+	p.integration = integration
+	p.zones = zones
+
+	log.FromContext(ctx).Info("[Provider] PowerDNS configured", "Server", p.serverID, "Zones", zones)
+	return nil
+}
+
+func (p *powerDNS) Zones() []string {
+	return p.zones
+}
+
+// Check that the zone still exists on the server and the API key can access it.
+func (p *powerDNS) Check(ctx context.Context, z string) error {
+	var found []zone
+
+	query := url.Values{"zone": {canonical(z)}}
+	if err := p.do(ctx, http.MethodGet, p.path("zones"), query, nil, &found); err != nil {
+		return fmt.Errorf("PB-PDNS-#0003: Could not look up the zone %s -- %w", z, err)
+	}
+
+	if len(found) == 0 {
+		return fmt.Errorf("PB-PDNS-#0003: Zone %s does not exist on server %s", z, p.serverID)
+	}
+
+	return nil
+}
+
+// Create the record's rrset. PowerDNS' REPLACE change type creates the rrset when it
+// doesn't exist yet.
+func (p *powerDNS) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := p.patch(ctx, &record, p.resourceRecordSet(&record, "REPLACE")); err != nil {
+		return providers.FromHTTPStatus(fmt.Errorf("PB-PDNS-#0004: Failed to create DNS record -- %w", err), statusCode(err), "")
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "PowerDNS record created")
+	return nil
+}
+
+// Update replaces the rrset with the record's targets and TTL.
+func (p *powerDNS) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := p.patch(ctx, &record, p.resourceRecordSet(&record, "REPLACE")); err != nil {
+		return providers.FromHTTPStatus(fmt.Errorf("PB-PDNS-#0005: Failed to update DNS record -- %w", err), statusCode(err), "")
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "PowerDNS record updated")
+	return nil
+}
+
+// Delete the record's rrset. Deleting an rrset that doesn't exist succeeds.
+func (p *powerDNS) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	set := rrset{
+		Name:       canonical(record.Spec.Name, record.Spec.Zone),
+		Type:       record.Spec.RecordType,
+		ChangeType: "DELETE",
+		Records:    []resourceRecord{},
+	}
+
+	if err := p.patch(ctx, &record, set); err != nil {
+		return providers.FromHTTPStatus(fmt.Errorf("PB-PDNS-#0006: Failed to delete DNS record -- %w", err), statusCode(err), "")
+	}
+
+	su.StageCondition(konditions.ConditionTerminated, "PowerDNS record deleted")
+	return nil
+}
+
+// Read the record's rrset. The zone is retrieved with a filter on the rrset's name and type so the
+// whole zone isn't sent back. Older versions of PowerDNS ignore the filter, so rrsets are still filtered here.
+func (p *powerDNS) Read(ctx context.Context, r phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	name := canonical(r.Spec.Name, r.Spec.Zone)
+	query := url.Values{"rrset_name": {name}, "rrset_type": {r.Spec.RecordType}}
+
+	var z zone
+	if err := p.do(ctx, http.MethodGet, p.path("zones", canonical(r.Spec.Zone)), query, nil, &z); err != nil {
+		return nil, providers.FromHTTPStatus(fmt.Errorf("PB-PDNS-#0007: Failed to read DNS record -- %w", err), statusCode(err), "")
+	}
+
+	for _, set := range z.RRSets {
+		if !strings.EqualFold(set.Name, name) || set.Type != r.Spec.RecordType {
+			continue
+		}
+
+		ttl := set.TTL
+		remote := &providers.RemoteRecord{TTL: &ttl}
+		for _, rec := range set.Records {
+			if !rec.Disabled {
+				remote.Targets = append(remote.Targets, rec.Content)
+			}
+		}
+
+		return remote, nil
+	}
+
+	return nil, providers.ErrRecordNotFound
+}
+
+func (p *powerDNS) patch(ctx context.Context, r *phonebook.DNSRecord, set rrset) error {
+	body := struct {
+		RRSets []rrset `json:"rrsets"`
+	}{
+		RRSets: []rrset{set},
+	}
+
+	return p.do(ctx, http.MethodPatch, p.path("zones", canonical(r.Spec.Zone)), nil, body, nil)
+}
+
+// Convert the record to an rrset. PowerDNS expects content to be formatted as it would be in a zone
+// file: names in a target (ie. CNAME, MX) are canonical and TXT values are quoted.
+func (p *powerDNS) resourceRecordSet(r *phonebook.DNSRecord, changeType string) rrset {
+	ttl := defaultTTL
+	if r.Spec.TTL != nil {
+		ttl = *r.Spec.TTL
+	}
+
+	set := rrset{
+		Name:       canonical(r.Spec.Name, r.Spec.Zone),
+		Type:       r.Spec.RecordType,
+		TTL:        ttl,
+		ChangeType: changeType,
+	}
+
+	for _, target := range r.Spec.Targets {
+		switch r.Spec.RecordType {
+		case "TXT":
+			target = fmt.Sprintf("\"%s\"", strings.Trim(target, "\""))
+		case "CNAME", "NS", "PTR", "MX", "SRV":
+			// The hostname is always the last field (ie. `10 mail.mydomain.com` for MX)
+			fields := strings.Fields(target)
+			fields[len(fields)-1] = canonical(fields[len(fields)-1])
+			target = strings.Join(fields, " ")
+		}
+
+		set.Records = append(set.Records, resourceRecord{Content: target})
+	}
+
+	return set
+}
+
+// Build the path of an endpoint for the configured server. Each segment is escaped, which
+// matters for zone IDs as they can contain characters like `/` for classless reverse zones.
+func (p *powerDNS) path(segments ...string) string {
+	path := []string{"api", "v1", "servers", url.PathEscape(p.serverID)}
+	for _, s := range segments {
+		path = append(path, url.PathEscape(s))
+	}
+
+	return strings.Join(path, "/")
+}
+
+// Send a request to the API. The body, if any, is encoded as JSON and the response is
+// decoded into out when it's set.
+func (p *powerDNS) do(ctx context.Context, method, path string, query url.Values, body any, out any) error {
+	endpoint, err := url.Parse(strings.TrimSuffix(p.baseURL.String(), "/") + "/" + path)
+	if err != nil {
+		return err
+	}
+	endpoint.RawQuery = query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), reader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-API-Key", p.apiKey)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &apiError{StatusCode: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+
+		return apiErr
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// apiError is the error PowerDNS returns in the body of failed requests.
+type apiError struct {
+	StatusCode int    `json:"-"`
+	Message    string `json:"error"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+// Return the status code of the API's response, or zero if the request failed before a response was received.
+func statusCode(err error) int {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+
+	return 0
+}
+
+// Join the labels into a canonical name, with the trailing dot.
+func canonical(labels ...string) string {
+	return strings.TrimSuffix(strings.Join(labels, "."), ".") + "."
+}
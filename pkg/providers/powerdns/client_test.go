@@ -0,0 +1,208 @@
+package powerdns
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"github.com/pier-oliviert/phonebook/pkg/providers/conformance"
+)
+
+// fakePowerDNS mimics the subset of PowerDNS' API used by the provider. Like PowerDNS, it
+// refuses names that aren't canonical or that are outside of the zone.
+type fakePowerDNS struct {
+	mu     sync.Mutex
+	zones  map[string]map[string]rrset
+	status int
+}
+
+var supportedTypes = map[string]bool{"A": true, "AAAA": true, "CAA": true, "CNAME": true, "MX": true, "NS": true, "PTR": true, "SRV": true, "TXT": true}
+
+func (f *fakePowerDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fail := func(status int, message string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+	}
+
+	if r.Header.Get("X-API-Key") != "secret" {
+		fail(http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if f.status != 0 {
+		fail(f.status, http.StatusText(f.status))
+		return
+	}
+
+	path, found := strings.CutPrefix(r.URL.Path, "/api/v1/servers/localhost/zones")
+	if !found {
+		fail(http.StatusNotFound, "Not Found")
+		return
+	}
+
+	if path == "" {
+		zones := []zone{}
+		if _, ok := f.zones[r.URL.Query().Get("zone")]; ok {
+			zones = append(zones, zone{ID: r.URL.Query().Get("zone"), Name: r.URL.Query().Get("zone")})
+		}
+		json.NewEncoder(w).Encode(zones)
+		return
+	}
+
+	name := strings.TrimPrefix(path, "/")
+	rrsets, ok := f.zones[name]
+	if !ok {
+		fail(http.StatusNotFound, "Could not find domain '"+name+"'")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		z := zone{ID: name, Name: name}
+		for _, set := range rrsets {
+			if set.Name == r.URL.Query().Get("rrset_name") && set.Type == r.URL.Query().Get("rrset_type") {
+				z.RRSets = append(z.RRSets, set)
+			}
+		}
+		json.NewEncoder(w).Encode(z)
+
+	case http.MethodPatch:
+		var body struct {
+			RRSets []rrset `json:"rrsets"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		for _, set := range body.RRSets {
+			if !strings.HasSuffix(set.Name, "."+name) {
+				fail(http.StatusUnprocessableEntity, "RRset "+set.Name+" is not canonical or not in zone "+name)
+				return
+			}
+
+			if !supportedTypes[set.Type] {
+				fail(http.StatusUnprocessableEntity, "RRset "+set.Name+" IN "+set.Type+": unknown type given")
+				return
+			}
+
+			switch set.ChangeType {
+			case "REPLACE":
+				rrsets[set.Name+set.Type] = rrset{Name: set.Name, Type: set.Type, TTL: set.TTL, Records: set.Records}
+			case "DELETE":
+				delete(rrsets, set.Name+set.Type)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newTestClient(t *testing.T, fake *fakePowerDNS) *powerDNS {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	t.Setenv(kPowerDNSAPIURL, server.URL)
+	t.Setenv(kPowerDNSAPIKey, "secret")
+	t.Setenv(kPowerDNSServerID, "")
+
+	p, err := NewClient(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestConfigureUnknownZone(t *testing.T) {
+	fake := &fakePowerDNS{zones: map[string]map[string]rrset{"mydomain.com.": {}}}
+	p := newTestClient(t, fake)
+
+	err := p.Configure(context.TODO(), "powerdns-test", []string{"mydomain.com", "unknown.com"})
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-PDNS-#0003") {
+		t.Errorf("Expected an error for a zone that doesn't exist, got: %v", err)
+	}
+}
+
+func TestResourceRecordSet(t *testing.T) {
+	p := &powerDNS{}
+
+	tests := []struct {
+		recordType string
+		targets    []string
+		expected   []string
+	}{
+		{recordType: "TXT", targets: []string{"some value"}, expected: []string{"\"some value\""}},
+		{recordType: "MX", targets: []string{"10 mail.mydomain.com"}, expected: []string{"10 mail.mydomain.com."}},
+		{recordType: "SRV", targets: []string{"10 5 5060 sip.mydomain.com."}, expected: []string{"10 5 5060 sip.mydomain.com."}},
+		{recordType: "A", targets: []string{"127.0.0.1", "127.0.0.2"}, expected: []string{"127.0.0.1", "127.0.0.2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.recordType, func(t *testing.T) {
+			set := p.resourceRecordSet(&phonebook.DNSRecord{
+				Spec: phonebook.DNSRecordSpec{
+					Zone:       "mydomain.com.",
+					Name:       "record",
+					RecordType: tt.recordType,
+					Targets:    tt.targets,
+				},
+			}, "REPLACE")
+
+			if set.Name != "record.mydomain.com." {
+				t.Errorf("Expected a canonical name, got %s", set.Name)
+			}
+
+			var contents []string
+			for _, r := range set.Records {
+				contents = append(contents, r.Content)
+			}
+
+			if strings.Join(contents, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, contents)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	fake := &fakePowerDNS{zones: map[string]map[string]rrset{"mydomain.com.": {}}}
+	p := newTestClient(t, fake)
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+
+	p.apiKey = "wrong"
+	err := p.Create(context.TODO(), record, &mocks.Updater{})
+	if err == nil || !strings.Contains(err.Error(), "401: Unauthorized") || providers.Retryable(err) {
+		t.Errorf("Expected a permanent error with the API's message, got: %v", err)
+	}
+
+	p.apiKey = "secret"
+	fake.status = http.StatusServiceUnavailable
+	err = p.Create(context.TODO(), record, &mocks.Updater{})
+	if !strings.HasPrefix(err.Error(), "PB-PDNS-#0004") || providers.Kind(err) != providers.ErrorTransient {
+		t.Errorf("Expected a transient error, got: %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, conformance.Suite{
+		Zone: "mydomain.com",
+		NewProvider: func(t *testing.T) providers.Provider {
+			return newTestClient(t, &fakePowerDNS{zones: map[string]map[string]rrset{"mydomain.com.": {}}})
+		},
+	})
+}
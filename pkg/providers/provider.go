@@ -35,6 +35,11 @@ type Provider interface {
 	// Create a DNS Record
 	Create(context.Context, phonebook.DNSRecord, phonebook.StagingUpdater) error
 
+	// Update a DNS Record that was previously created by the provider. The DNSRecord
+	// passed has the new spec while its status still includes the RemoteInfo staged
+	// by the provider when the record was created.
+	Update(context.Context, phonebook.DNSRecord, phonebook.StagingUpdater) error
+
 	// Delete a DNS Record
 	Delete(context.Context, phonebook.DNSRecord, phonebook.StagingUpdater) error
 
@@ -42,10 +47,25 @@ type Provider interface {
 	Zones() []string
 }
 
+// Image of the provider binary that includes every provider that ships with Phonebook. The
+// provider is selected by the name set in PB_PROVIDER.
+var ProvidersImage = fmt.Sprintf("ghcr.io/pier-oliviert/providers:v%s", ProviderVersion)
+
+// Default image for each provider. Providers that aren't listed need their image set in the integration.
 var ProviderImages = map[string]string{
-	"aws":        fmt.Sprintf("ghcr.io/pier-oliviert/providers-aws:v%s", ProviderVersion),
-	"azure":      fmt.Sprintf("ghcr.io/pier-oliviert/providers-azure:v%s", ProviderVersion),
-	"cloudflare": fmt.Sprintf("ghcr.io/pier-oliviert/providers-cloudflare:v%s", ProviderVersion),
-	"desec":      fmt.Sprintf("ghcr.io/pier-oliviert/providers-desec:v%s", ProviderVersion),
-	"gcore":      fmt.Sprintf("ghcr.io/pier-oliviert/providers-gcore:v%s", ProviderVersion),
+	"aws":          ProvidersImage,
+	"azure":        ProvidersImage,
+	"cloudflare":   ProvidersImage,
+	"coredns":      ProvidersImage,
+	"desec":        ProvidersImage,
+	"digitalocean": ProvidersImage,
+	"embedded":     ProvidersImage,
+	"fake":         ProvidersImage,
+	"gcore":        ProvidersImage,
+	"googledns":    ProvidersImage,
+	"hetzner":      ProvidersImage,
+	"plugin":       ProvidersImage,
+	"powerdns":     ProvidersImage,
+	"rfc2136":      ProvidersImage,
+	"webhook":      ProvidersImage,
 }
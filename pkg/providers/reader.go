@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+var ErrRecordNotFound = errors.New("PB-#0101: Record was not found on the provider")
+
+// Reader is an optional interface a Provider can implement to let Phonebook read
+// records directly from the provider. When a Provider implements it, the server
+// periodically compares what the provider has with the DNSRecord's spec
+// to detect changes that were made outside of Phonebook (ie. someone edited the record
+// in the provider's console).
+type Reader interface {
+	// Read the record on the provider that matches the DNSRecord's name, zone and type. If
+	// the record doesn't exist on the provider, ErrRecordNotFound needs to be returned.
+	Read(context.Context, phonebook.DNSRecord) (*RemoteRecord, error)
+}
+
+// RemoteRecord represents the values of a DNS Record as stored by the provider.
+type RemoteRecord struct {
+	Targets []string
+
+	// TTL is optional as some provider do not expose it in all cases, eg. AWS's Alias Target.
+	TTL *int64
+}
+
+// Matches returns true if the remote record has the same values as the spec. Targets are
+// compared without taking the order into account as providers don't always
+// return them in the order they were created. Targets are also normalized as some
+// provider returns fully qualified names, or quoted TXT values.
+//
+// The TTL is only compared when the spec has one set, since each provider has its own
+// default value.
+func (rr *RemoteRecord) Matches(spec phonebook.DNSRecordSpec) bool {
+	if len(rr.Targets) != len(spec.Targets) {
+		return false
+	}
+
+	remote := normalizeTargets(rr.Targets)
+	local := normalizeTargets(spec.Targets)
+	if !slices.Equal(remote, local) {
+		return false
+	}
+
+	if spec.TTL != nil && rr.TTL != nil && *spec.TTL != *rr.TTL {
+		return false
+	}
+
+	return true
+}
+
+// MatchesTarget returns true if the value is one of the spec's targets. Values are normalized the same way
+// Matches does. Providers that create a remote record per target use it to find the records that were
+// created for a spec when their IDs weren't stored.
+func MatchesTarget(spec phonebook.DNSRecordSpec, value string) bool {
+	return slices.Contains(normalizeTargets(spec.Targets), normalizeTargets([]string{value})[0])
+}
+
+func normalizeTargets(targets []string) []string {
+	normalized := make([]string, len(targets))
+	for i, t := range targets {
+		t = strings.TrimSuffix(strings.Trim(t, "\""), ".")
+		normalized[i] = strings.ToLower(t)
+	}
+
+	slices.Sort(normalized)
+	return normalized
+}
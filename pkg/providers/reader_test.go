@@ -0,0 +1,79 @@
+package providers
+
+import (
+	"testing"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+func TestRemoteRecordMatches(t *testing.T) {
+	ttl := int64(60)
+	other := int64(300)
+
+	tests := []struct {
+		name     string
+		remote   RemoteRecord
+		spec     phonebook.DNSRecordSpec
+		expected bool
+	}{
+		{
+			name:     "Same targets in a different order",
+			remote:   RemoteRecord{Targets: []string{"127.0.0.2", "127.0.0.1"}},
+			spec:     phonebook.DNSRecordSpec{Targets: []string{"127.0.0.1", "127.0.0.2"}},
+			expected: true,
+		},
+		{
+			name:     "Quoted TXT and fully qualified names",
+			remote:   RemoteRecord{Targets: []string{"\"some-value\"", "Target.mydomain.com."}},
+			spec:     phonebook.DNSRecordSpec{Targets: []string{"some-value", "target.mydomain.com"}},
+			expected: true,
+		},
+		{
+			name:     "Different targets",
+			remote:   RemoteRecord{Targets: []string{"127.0.0.3"}},
+			spec:     phonebook.DNSRecordSpec{Targets: []string{"127.0.0.1"}},
+			expected: false,
+		},
+		{
+			name:     "Missing target",
+			remote:   RemoteRecord{Targets: []string{"127.0.0.1"}},
+			spec:     phonebook.DNSRecordSpec{Targets: []string{"127.0.0.1", "127.0.0.2"}},
+			expected: false,
+		},
+		{
+			name:     "TTL ignored when the spec doesn't have one",
+			remote:   RemoteRecord{Targets: []string{"127.0.0.1"}, TTL: &other},
+			spec:     phonebook.DNSRecordSpec{Targets: []string{"127.0.0.1"}},
+			expected: true,
+		},
+		{
+			name:     "Different TTL",
+			remote:   RemoteRecord{Targets: []string{"127.0.0.1"}, TTL: &other},
+			spec:     phonebook.DNSRecordSpec{Targets: []string{"127.0.0.1"}, TTL: &ttl},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.remote.Matches(tt.spec); result != tt.expected {
+				t.Errorf("Expected Matches() to return %t, got %t", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMatchesTarget(t *testing.T) {
+	spec := phonebook.DNSRecordSpec{Targets: []string{"some-value", "10 mail.mydomain.com"}}
+
+	for value, expected := range map[string]bool{
+		"\"some-value\"":        true,
+		"10 Mail.mydomain.com.": true,
+		"created by hand":       false,
+		"20 mail.mydomain.com":  false,
+	} {
+		if result := MatchesTarget(spec, value); result != expected {
+			t.Errorf("Expected MatchesTarget(%q) to return %t, got %t", value, expected, result)
+		}
+	}
+}
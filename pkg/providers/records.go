@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"strings"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+const kRecordIDs = "recordIDs"
+
+// RecordSet is used by providers that create a remote record for each of a DNSRecord's targets. The IDs of
+// the remote records are kept in the DNSRecord's RemoteInfo so they can be updated and deleted later.
+type RecordSet[T any] struct {
+	Create func(record T) (id string, err error)
+	Update func(id string, record T) error
+
+	// Delete needs to ignore records that were already deleted on the provider.
+	Delete func(id string) error
+}
+
+// Sync the remote records with the IDs given with the records built from the targets. The remote records are updated
+// in place, records are created for new targets, and the remote records left over when targets were removed are deleted.
+//
+// The IDs of the remote records that exist when Sync returns are staged, even when it fails part way, so the
+// next attempt resumes from them instead of creating records that would be orphaned.
+func (rs RecordSet[T]) Sync(su phonebook.StagingUpdater, existing []string, records []T) error {
+	var ids []string
+	stage := func(err error) error {
+		if err == nil || len(ids) != 0 {
+			su.StageRemoteInfo(phonebook.IntegrationInfo{kRecordIDs: strings.Join(ids, ",")})
+		}
+
+		return err
+	}
+
+	for i, record := range records {
+		if i < len(existing) {
+			if err := rs.Update(existing[i], record); err != nil {
+				// The records that weren't reached are still on the provider.
+				ids = append(ids, existing[i:]...)
+				return stage(err)
+			}
+
+			ids = append(ids, existing[i])
+			continue
+		}
+
+		id, err := rs.Create(record)
+		if err != nil {
+			return stage(err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	for i := len(records); i < len(existing); i++ {
+		if err := rs.Delete(existing[i]); err != nil {
+			ids = append(ids, existing[i:]...)
+			return stage(err)
+		}
+	}
+
+	return stage(nil)
+}
+
+// DeleteAll deletes the remote records. When it fails part way, the IDs of the remote records that weren't deleted
+// are staged.
+func (rs RecordSet[T]) DeleteAll(su phonebook.StagingUpdater, ids []string) error {
+	for i, id := range ids {
+		if err := rs.Delete(id); err != nil {
+			su.StageRemoteInfo(phonebook.IntegrationInfo{kRecordIDs: strings.Join(ids[i:], ",")})
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecordIDs returns the IDs of the remote records that were staged by a RecordSet for the integration.
+func RecordIDs(record *phonebook.DNSRecord, integration string) []string {
+	var ids []string
+	for _, id := range strings.Split(record.Status.RemoteInfo[integration][kRecordIDs], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
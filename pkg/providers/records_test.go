@@ -0,0 +1,134 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+)
+
+// remoteRecords stores records by ID and fails the operations once the quota of operations is reached.
+type remoteRecords struct {
+	records map[string]string
+	nextID  int
+	quota   int
+}
+
+func (rr *remoteRecords) set() RecordSet[string] {
+	exceeded := errors.New("quota exceeded")
+
+	return RecordSet[string]{
+		Create: func(value string) (string, error) {
+			if rr.quota == 0 {
+				return "", exceeded
+			}
+			rr.quota--
+			rr.nextID++
+
+			id := fmt.Sprintf("rec%d", rr.nextID)
+			rr.records[id] = value
+			return id, nil
+		},
+		Update: func(id string, value string) error {
+			if rr.quota == 0 {
+				return exceeded
+			}
+			rr.quota--
+
+			rr.records[id] = value
+			return nil
+		},
+		Delete: func(id string) error {
+			if rr.quota == 0 {
+				return exceeded
+			}
+			rr.quota--
+
+			delete(rr.records, id)
+			return nil
+		},
+	}
+}
+
+func TestRecordSetSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		records  []string
+		quota    int
+		staged   string
+		fails    bool
+	}{
+		{name: "Create", records: []string{"a", "b"}, quota: 2, staged: "rec1,rec2"},
+		{name: "Update and create", existing: []string{"old1"}, records: []string{"a", "b"}, quota: 2, staged: "old1,rec1"},
+		{name: "Delete left over records", existing: []string{"old1", "old2", "old3"}, records: []string{"a"}, quota: 3, staged: "old1"},
+		{name: "Create fails part way", records: []string{"a", "b"}, quota: 1, staged: "rec1", fails: true},
+		{name: "Update fails part way", existing: []string{"old1", "old2"}, records: []string{"a", "b"}, quota: 1, staged: "old1,old2", fails: true},
+		{name: "Delete fails part way", existing: []string{"old1", "old2", "old3"}, records: []string{"a"}, quota: 2, staged: "old1,old3", fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := &remoteRecords{records: map[string]string{}, quota: tt.quota}
+			for _, id := range tt.existing {
+				rr.records[id] = "old"
+			}
+
+			su := &mocks.Updater{}
+			err := rr.set().Sync(su, tt.existing, tt.records)
+			if (err != nil) != tt.fails {
+				t.Fatalf("Expected the sync to fail: %t, got: %v", tt.fails, err)
+			}
+
+			if su.Info[kRecordIDs] != tt.staged {
+				t.Errorf("Expected the IDs %q to be staged, got: %q", tt.staged, su.Info[kRecordIDs])
+			}
+		})
+	}
+}
+
+func TestRecordSetSyncFailsBeforeCreatingAnything(t *testing.T) {
+	rr := &remoteRecords{records: map[string]string{}}
+
+	su := &mocks.Updater{}
+	if err := rr.set().Sync(su, nil, []string{"a"}); err == nil {
+		t.Fatal("Expected the sync to fail")
+	}
+
+	if su.Info != nil {
+		t.Errorf("Expected nothing to be staged, got: %v", su.Info)
+	}
+}
+
+func TestRecordSetDeleteAll(t *testing.T) {
+	rr := &remoteRecords{records: map[string]string{"rec1": "a", "rec2": "b", "rec3": "c"}, quota: 1}
+
+	su := &mocks.Updater{}
+	if err := rr.set().DeleteAll(su, []string{"rec1", "rec2", "rec3"}); err == nil {
+		t.Fatal("Expected the deletion to fail")
+	}
+
+	if su.Info[kRecordIDs] != "rec2,rec3" {
+		t.Errorf("Expected the IDs of the records left to be staged, got: %v", su.Info)
+	}
+}
+
+func TestRecordIDs(t *testing.T) {
+	record := &phonebook.DNSRecord{
+		Status: phonebook.DNSRecordStatus{
+			RemoteInfo: map[string]phonebook.IntegrationInfo{
+				"test": {kRecordIDs: "rec1, rec2,"},
+			},
+		},
+	}
+
+	if ids := RecordIDs(record, "test"); len(ids) != 2 || ids[0] != "rec1" || ids[1] != "rec2" {
+		t.Errorf("Expected the stored IDs, got: %v", ids)
+	}
+
+	if ids := RecordIDs(record, "other"); len(ids) != 0 {
+		t.Errorf("Expected no IDs for another integration, got: %v", ids)
+	}
+}
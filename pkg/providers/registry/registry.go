@@ -0,0 +1,267 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	DefaultPrefix = "phonebook-"
+
+	kHeritage = "heritage=phonebook"
+	kOwner    = "phonebook/owner="
+	kResource = "phonebook/resource="
+
+	// RemoteInfo staged by the provider for the companion record is stored alongside the
+	// record's own RemoteInfo using this prefix.
+	kInfoPrefix = "registry."
+)
+
+var ErrReaderRequired = errors.New("PB-REG-#0001: The provider needs to support reading records to use the registry")
+
+// Registry wraps a Provider so that it only modifies records it owns. Ownership is tracked with
+// a companion TXT record that is created alongside each record. The TXT record stores the owner ID
+// of the integration that created the record.
+//
+// Before creating a record, the registry makes sure that there isn't already a record with the same name and type
+// on the provider. Updates and deletions are only done when the companion record matches the owner ID. When the registry
+// refuses to modify a record, the provider's condition is set to Refused.
+type Registry struct {
+	providers.Provider
+
+	reader      providers.Reader
+	integration string
+	ownerID     string
+	prefix      string
+}
+
+func NewRegistry(p providers.Provider, ownerID string, prefix string) (*Registry, error) {
+	reader, ok := p.(providers.Reader)
+	if !ok {
+		return nil, ErrReaderRequired
+	}
+
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	return &Registry{
+		Provider: p,
+		reader:   reader,
+		ownerID:  ownerID,
+		prefix:   prefix,
+	}, nil
+}
+
+func (r *Registry) Configure(ctx context.Context, integration string, zones []string) error {
+	r.integration = integration
+	return r.Provider.Configure(ctx, integration, zones)
+}
+
+func (r *Registry) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	return r.reader.Read(ctx, record)
+}
+
+// Check runs the check of the wrapped provider, when it has one.
+func (r *Registry) Check(ctx context.Context, zone string) error {
+	if checker, ok := r.Provider.(providers.Checker); ok {
+		return checker.Check(ctx, zone)
+	}
+
+	return nil
+}
+
+func (r *Registry) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	owner, found, err := r.owner(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	if found && owner != r.ownerID {
+		su.StageCondition(phonebook.ConditionRefused, fmt.Sprintf("Record is owned by another integration (%s)", owner))
+		return nil
+	}
+
+	if !found {
+		// Without a companion record, any record with the same name and type was created
+		// outside of Phonebook.
+		_, err := r.reader.Read(ctx, record)
+		if err == nil {
+			su.StageCondition(phonebook.ConditionRefused, "A record with the same name and type already exists on the provider and isn't owned by Phonebook")
+			return nil
+		}
+
+		if !errors.Is(err, providers.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	stage := newStage(record, r.integration)
+	if !found {
+		if err := r.Provider.Create(ctx, r.companion(record), stage.companion()); err != nil {
+			return err
+		}
+	}
+
+	if err := r.Provider.Create(ctx, record, stage); err != nil {
+		if !found && !stage.partial {
+			r.release(ctx, record, stage)
+			return err
+		}
+
+		// The provider created part of the record before failing. The companion is kept to own what was created
+		// and the RemoteInfo is committed so the next attempt resumes from it.
+		stage.commit(su)
+		return err
+	}
+
+	stage.commit(su)
+	return nil
+}
+
+// Delete the companion created for a record that the provider failed to create, when nothing was created. Otherwise,
+// the companion would claim a name for this integration even though the record doesn't exist.
+func (r *Registry) release(ctx context.Context, record phonebook.DNSRecord, created *stage) {
+	record.Status.RemoteInfo = map[string]phonebook.IntegrationInfo{r.integration: created.info}
+
+	companion := r.companion(record)
+	if err := r.Provider.Delete(ctx, companion, newStage(companion, r.integration).companion()); err != nil {
+		log.FromContext(ctx).Error(err, "[Registry] Could not delete the companion of a record that failed to be created", "Name", companion.Spec.Name)
+	}
+}
+
+// Update the record if it's owned by this integration. Records created before the registry was enabled don't
+// have a companion record, the companion is created so the record is owned from then on. The provider only calls
+// Update on records that were created by this integration, so it is safe to claim it.
+func (r *Registry) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	owner, found, err := r.owner(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	if found && owner != r.ownerID {
+		su.StageCondition(phonebook.ConditionRefused, fmt.Sprintf("Record is owned by another integration (%s)", owner))
+		return nil
+	}
+
+	stage := newStage(record, r.integration)
+	if !found {
+		log.FromContext(ctx).Info("[Registry] Claiming record", "Name", record.Spec.Name, "Type", record.Spec.RecordType)
+		if err := r.Provider.Create(ctx, r.companion(record), stage.companion()); err != nil {
+			return err
+		}
+	}
+
+	if err := r.Provider.Update(ctx, record, stage); err != nil {
+		stage.commit(su)
+		return err
+	}
+
+	stage.commit(su)
+	return nil
+}
+
+// Delete the record and its companion if the record is owned by this integration. Records that are not owned
+// are left untouched on the provider, but the condition is still terminated so the DNSRecord can be deleted.
+func (r *Registry) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	owner, found, err := r.owner(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	if !found || owner != r.ownerID {
+		su.StageCondition(konditions.ConditionTerminated, "Record isn't owned by this integration, it was left untouched on the provider")
+		return nil
+	}
+
+	stage := newStage(record, r.integration)
+	if err := r.Provider.Delete(ctx, record, stage); err != nil {
+		return err
+	}
+
+	if err := r.Provider.Delete(ctx, r.companion(record), stage.companion()); err != nil {
+		return err
+	}
+
+	stage.commit(su)
+	return nil
+}
+
+// owner reads the companion record and returns the owner ID stored in it. Found is false when
+// there is no companion record on the provider. A TXT record that exists at the companion's name but wasn't
+// created by Phonebook is considered owned by someone else.
+func (r *Registry) owner(ctx context.Context, record phonebook.DNSRecord) (owner string, found bool, err error) {
+	remote, err := r.reader.Read(ctx, r.companion(record))
+	if errors.Is(err, providers.ErrRecordNotFound) {
+		return "", false, nil
+	}
+
+	if err != nil {
+		return "", false, err
+	}
+
+	for _, target := range remote.Targets {
+		target = strings.Trim(target, "\"")
+		if !strings.HasPrefix(target, kHeritage) {
+			continue
+		}
+
+		for _, field := range strings.Split(target, ",") {
+			if id, ok := strings.CutPrefix(field, kOwner); ok {
+				return id, true, nil
+			}
+		}
+	}
+
+	return "unknown", true, nil
+}
+
+// companion returns the TXT record that tracks the ownership of the record. The RemoteInfo that was
+// staged for the companion record is restored so the provider can find it.
+//
+// The companion of a record at the zone's apex is named after the record's type only.
+func (r *Registry) companion(record phonebook.DNSRecord) phonebook.DNSRecord {
+	name := r.prefix + strings.ToLower(record.Spec.RecordType)
+	if record.Spec.Name != "" && record.Spec.Name != "@" {
+		name = fmt.Sprintf("%s-%s", name, strings.ReplaceAll(record.Spec.Name, "*", "wildcard"))
+	}
+
+	companion := phonebook.DNSRecord{
+		ObjectMeta: record.ObjectMeta,
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       record.Spec.Zone,
+			Name:       name,
+			RecordType: "TXT",
+			Targets: []string{
+				strings.Join([]string{
+					kHeritage,
+					kOwner + r.ownerID,
+					fmt.Sprintf("%s%s/%s", kResource, record.Namespace, record.Name),
+				}, ","),
+			},
+			TTL: record.Spec.TTL,
+		},
+	}
+
+	if info, ok := record.Status.RemoteInfo[r.integration]; ok {
+		companionInfo := phonebook.IntegrationInfo{}
+		for k, v := range info {
+			if key, ok := strings.CutPrefix(k, kInfoPrefix); ok {
+				companionInfo[key] = v
+			}
+		}
+
+		companion.Status.RemoteInfo = map[string]phonebook.IntegrationInfo{
+			r.integration: companionInfo,
+		}
+	}
+
+	return companion
+}
@@ -0,0 +1,279 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// memory is a provider that stores records in a map keyed by name and type.
+type memory struct {
+	records map[string][]string
+
+	// Create fails for the records with these keys.
+	failures map[string]error
+
+	// Create stages the record's RemoteInfo and then fails for the records with these keys,
+	// like a provider that created part of the record.
+	partials map[string]error
+}
+
+func (m *memory) key(record phonebook.DNSRecord) string {
+	return fmt.Sprintf("%s.%s/%s", record.Spec.Name, record.Spec.Zone, record.Spec.RecordType)
+}
+
+func (m *memory) Configure(ctx context.Context, integration string, zones []string) error {
+	return nil
+}
+
+func (m *memory) Zones() []string {
+	return []string{"mydomain.com"}
+}
+
+func (m *memory) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := m.failures[m.key(record)]; err != nil {
+		return err
+	}
+
+	m.records[m.key(record)] = record.Spec.Targets
+	su.StageRemoteInfo(phonebook.IntegrationInfo{"recordID": m.key(record)})
+	if err := m.partials[m.key(record)]; err != nil {
+		return err
+	}
+	su.StageCondition(konditions.ConditionCreated, "Created")
+	return nil
+}
+
+func (m *memory) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	m.records[m.key(record)] = record.Spec.Targets
+	su.StageCondition(konditions.ConditionCreated, "Updated")
+	return nil
+}
+
+func (m *memory) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	delete(m.records, m.key(record))
+	su.StageCondition(konditions.ConditionTerminated, "Deleted")
+	return nil
+}
+
+func (m *memory) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	targets, ok := m.records[m.key(record)]
+	if !ok {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	return &providers.RemoteRecord{Targets: targets}, nil
+}
+
+func newRecord() phonebook.DNSRecord {
+	return phonebook.DNSRecord{
+		ObjectMeta: meta.ObjectMeta{
+			Name:      "www",
+			Namespace: "default",
+		},
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+}
+
+func TestNewRegistryRequiresReader(t *testing.T) {
+	type writeOnly struct{ providers.Provider }
+
+	_, err := NewRegistry(writeOnly{&memory{}}, "cluster-a", "")
+	if !errors.Is(err, ErrReaderRequired) {
+		t.Errorf("Expected ErrReaderRequired, got: %v", err)
+	}
+}
+
+func TestCreateClaimsRecord(t *testing.T) {
+	m := &memory{records: map[string][]string{}}
+	r, err := NewRegistry(m, "cluster-a", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Configure(context.Background(), "test", nil)
+
+	record := newRecord()
+	su := &mocks.Updater{}
+	if err := r.Create(context.Background(), record, su); err != nil {
+		t.Fatal(err)
+	}
+
+	if *su.Status != konditions.ConditionCreated {
+		t.Errorf("Expected the record to be created, got: %s", *su.Status)
+	}
+
+	companion, ok := m.records["phonebook-a-www.mydomain.com/TXT"]
+	if !ok {
+		t.Fatalf("Expected a companion record to be created: %v", m.records)
+	}
+
+	if !strings.Contains(companion[0], "phonebook/owner=cluster-a") {
+		t.Errorf("Expected the companion record to include the owner, got: %s", companion[0])
+	}
+
+	if su.Info["recordID"] != "www.mydomain.com/A" || su.Info["registry.recordID"] != "phonebook-a-www.mydomain.com/TXT" {
+		t.Errorf("Expected the remote info for both records to be staged, got: %v", su.Info)
+	}
+}
+
+func TestCreateFailureReleasesCompanion(t *testing.T) {
+	failure := errors.New("invalid record")
+	m := &memory{
+		records:  map[string][]string{},
+		failures: map[string]error{"www.mydomain.com/A": failure},
+	}
+	r, err := NewRegistry(m, "cluster-a", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Configure(context.Background(), "test", nil)
+
+	if err := r.Create(context.Background(), newRecord(), &mocks.Updater{}); !errors.Is(err, failure) {
+		t.Fatalf("Expected the provider's error, got: %v", err)
+	}
+
+	if len(m.records) != 0 {
+		t.Errorf("Expected the companion record to be deleted, got: %v", m.records)
+	}
+}
+
+func TestCreatePartialFailureKeepsRemoteInfo(t *testing.T) {
+	failure := errors.New("service unavailable")
+	m := &memory{
+		records:  map[string][]string{},
+		partials: map[string]error{"www.mydomain.com/A": failure},
+	}
+	r, err := NewRegistry(m, "cluster-a", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Configure(context.Background(), "test", nil)
+
+	su := &mocks.Updater{}
+	if err := r.Create(context.Background(), newRecord(), su); !errors.Is(err, failure) {
+		t.Fatalf("Expected the provider's error, got: %v", err)
+	}
+
+	if _, ok := m.records["phonebook-a-www.mydomain.com/TXT"]; !ok {
+		t.Errorf("Expected the companion record to be kept, got: %v", m.records)
+	}
+
+	if su.Info["recordID"] != "www.mydomain.com/A" || su.Info["registry.recordID"] != "phonebook-a-www.mydomain.com/TXT" {
+		t.Fatalf("Expected the remote info staged before the failure to be committed, got: %v", su.Info)
+	}
+
+	// The next attempt finds the companion and resumes the creation.
+	delete(m.partials, "www.mydomain.com/A")
+	record := newRecord()
+	record.Status.RemoteInfo = map[string]phonebook.IntegrationInfo{"test": su.Info}
+
+	su = &mocks.Updater{}
+	if err := r.Create(context.Background(), record, su); err != nil {
+		t.Fatal(err)
+	}
+
+	if *su.Status != konditions.ConditionCreated {
+		t.Errorf("Expected the record to be created, got: %s", *su.Status)
+	}
+}
+
+func TestCompanionAtApex(t *testing.T) {
+	r, _ := NewRegistry(&memory{}, "cluster-a", "")
+
+	for _, name := range []string{"", "@"} {
+		record := newRecord()
+		record.Spec.Name = name
+
+		if companion := r.companion(record); companion.Spec.Name != "phonebook-a" {
+			t.Errorf("Expected the companion of the apex record %q to be named phonebook-a, got: %s", name, companion.Spec.Name)
+		}
+	}
+
+	record := newRecord()
+	record.Spec.Name = "*"
+	if companion := r.companion(record); companion.Spec.Name != "phonebook-a-wildcard" {
+		t.Errorf("Expected the companion of a wildcard record to be named phonebook-a-wildcard, got: %s", companion.Spec.Name)
+	}
+}
+
+func TestCreateRefusesUnownedRecord(t *testing.T) {
+	m := &memory{records: map[string][]string{
+		"www.mydomain.com/A": {"10.0.0.1"},
+	}}
+	r, _ := NewRegistry(m, "cluster-a", "")
+
+	su := &mocks.Updater{}
+	if err := r.Create(context.Background(), newRecord(), su); err != nil {
+		t.Fatal(err)
+	}
+
+	if *su.Status != phonebook.ConditionRefused {
+		t.Errorf("Expected the registry to refuse the record, got: %s", *su.Status)
+	}
+
+	if m.records["www.mydomain.com/A"][0] != "10.0.0.1" {
+		t.Error("Expected the existing record to be left untouched")
+	}
+}
+
+func TestRecordOwnedByAnotherCluster(t *testing.T) {
+	m := &memory{records: map[string][]string{}}
+	other, _ := NewRegistry(m, "cluster-b", "")
+	if err := other.Create(context.Background(), newRecord(), &mocks.Updater{}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := NewRegistry(m, "cluster-a", "")
+
+	su := &mocks.Updater{}
+	if err := r.Update(context.Background(), newRecord(), su); err != nil {
+		t.Fatal(err)
+	}
+
+	if *su.Status != phonebook.ConditionRefused {
+		t.Errorf("Expected the registry to refuse the update, got: %s", *su.Status)
+	}
+
+	su = &mocks.Updater{}
+	if err := r.Delete(context.Background(), newRecord(), su); err != nil {
+		t.Fatal(err)
+	}
+
+	if *su.Status != konditions.ConditionTerminated {
+		t.Errorf("Expected the condition to be terminated, got: %s", *su.Status)
+	}
+
+	if _, ok := m.records["www.mydomain.com/A"]; !ok {
+		t.Error("Expected the record owned by another cluster to still exist")
+	}
+}
+
+func TestDeleteOwnedRecord(t *testing.T) {
+	m := &memory{records: map[string][]string{}}
+	r, _ := NewRegistry(m, "cluster-a", "custom-")
+
+	if err := r.Create(context.Background(), newRecord(), &mocks.Updater{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Delete(context.Background(), newRecord(), &mocks.Updater{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.records) != 0 {
+		t.Errorf("Expected the record and its companion to be deleted, got: %v", m.records)
+	}
+}
@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+// stage collects the values staged by the provider for both the record and its companion. Once the
+// operations returned, the values are merged and staged on the StagingUpdater given by the server.
+type stage struct {
+	status *konditions.ConditionStatus
+	reason string
+
+	// RemoteInfo that was stored on the record when the operation started. Providers only
+	// stage the info they know about, so the existing values are kept.
+	info   phonebook.IntegrationInfo
+	staged bool
+
+	// partial is true when the provider staged RemoteInfo for the record itself, meaning some of it
+	// exists on the provider even if the operation failed.
+	partial bool
+}
+
+func newStage(record phonebook.DNSRecord, integration string) *stage {
+	info := phonebook.IntegrationInfo{}
+	for k, v := range record.Status.RemoteInfo[integration] {
+		info[k] = v
+	}
+
+	return &stage{info: info}
+}
+
+func (s *stage) StageCondition(status konditions.ConditionStatus, reason string) {
+	s.status = &status
+	s.reason = reason
+}
+
+func (s *stage) StageRemoteInfo(info phonebook.IntegrationInfo) {
+	for k, v := range info {
+		s.info[k] = v
+	}
+	s.staged = true
+	s.partial = true
+}
+
+func (s *stage) companion() phonebook.StagingUpdater {
+	return &companionStage{stage: s}
+}
+
+// commit the values to the StagingUpdater. If the provider didn't stage a condition, none is
+// staged here either so the server can report it.
+func (s *stage) commit(su phonebook.StagingUpdater) {
+	if s.staged {
+		su.StageRemoteInfo(s.info)
+	}
+
+	if s.status != nil {
+		su.StageCondition(*s.status, s.reason)
+	}
+}
+
+// companionStage stores the RemoteInfo staged for the companion record under a prefix so it doesn't
+// conflict with the record's own info. The condition staged for the companion is ignored as
+// only the record's condition is reported.
+type companionStage struct {
+	stage *stage
+}
+
+func (cs *companionStage) StageCondition(status konditions.ConditionStatus, reason string) {}
+
+func (cs *companionStage) StageRemoteInfo(info phonebook.IntegrationInfo) {
+	for k, v := range info {
+		cs.stage.info[kInfoPrefix+k] = v
+	}
+	cs.stage.staged = true
+}
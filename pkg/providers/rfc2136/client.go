@@ -0,0 +1,308 @@
+package rfc2136
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	utils "github.com/pier-oliviert/phonebook/pkg/utils"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	kRFC2136Server        = "RFC2136_SERVER"
+	kRFC2136Transport     = "RFC2136_TRANSPORT"
+	kRFC2136TSIGKey       = "RFC2136_TSIG_KEY"
+	kRFC2136TSIGSecret    = "RFC2136_TSIG_SECRET"
+	kRFC2136TSIGAlgorithm = "RFC2136_TSIG_ALGORITHM"
+	kDefaultPort          = "53"
+	defaultTTL            = int64(300) // Default TTL for DNS records in seconds if not specified
+)
+
+// Algorithms supported to sign the updates. The names are the same as the ones used
+// by BIND's tsig-keygen.
+var algorithms = map[string]string{
+	"hmac-sha256": dns.HmacSHA256,
+	"hmac-sha512": dns.HmacSHA512,
+}
+
+type rfc2136 struct {
+	integration string
+	zones       []string
+
+	// Address (host:port) of the primary nameserver that accepts the updates.
+	server string
+
+	// TSIG key name and algorithm. The key is empty when the updates aren't signed.
+	key       string
+	algorithm string
+
+	client *dns.Client
+}
+
+func init() {
+	providers.Register("rfc2136", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
+}
+
+// NewClient creates a provider that sends DNS UPDATE messages (RFC 2136) to the server set with RFC2136_SERVER. Updates
+// are signed with TSIG when RFC2136_TSIG_KEY and RFC2136_TSIG_SECRET are set. The secret is the base64 encoded value
+// from the key file (ie. generated by `tsig-keygen`).
+func NewClient(ctx context.Context) (*rfc2136, error) {
+	server, err := utils.RetrieveValue(ctx, kRFC2136Server)
+	if err != nil {
+		return nil, fmt.Errorf("PB-RFC-#0001: Server address not found -- %w", err)
+	}
+
+	server = strings.TrimSpace(server)
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, kDefaultPort)
+	}
+
+	transport, _ := utils.RetrieveValue(ctx, kRFC2136Transport)
+	transport = strings.ToLower(strings.TrimSpace(transport))
+	switch transport {
+	case "":
+		transport = "udp"
+	case "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("PB-RFC-#0008: Transport needs to be either udp or tcp, got %s", transport)
+	}
+
+	c := &rfc2136{
+		server: server,
+		client: &dns.Client{Net: transport, Timeout: 10 * time.Second},
+	}
+
+	key, _ := utils.RetrieveValue(ctx, kRFC2136TSIGKey)
+	if key = strings.TrimSpace(key); key == "" {
+		return c, nil
+	}
+
+	secret, err := utils.RetrieveValue(ctx, kRFC2136TSIGSecret)
+	if err != nil {
+		return nil, fmt.Errorf("PB-RFC-#0002: TSIG secret not found for key %s -- %w", key, err)
+	}
+
+	algorithm, _ := utils.RetrieveValue(ctx, kRFC2136TSIGAlgorithm)
+	algorithm = strings.ToLower(strings.TrimSpace(algorithm))
+	if algorithm == "" {
+		algorithm = "hmac-sha256"
+	}
+
+	if _, ok := algorithms[algorithm]; !ok {
+		return nil, fmt.Errorf("PB-RFC-#0002: TSIG algorithm %s is not supported, use hmac-sha256 or hmac-sha512", algorithm)
+	}
+
+	c.key = dns.Fqdn(key)
+	c.algorithm = algorithms[algorithm]
+	c.client.TsigSecret = map[string]string{c.key: strings.TrimSpace(secret)}
+
+	return c, nil
+}
+
+func (c *rfc2136) Configure(ctx context.Context, integration string, zones []string) error {
+	c.integration = integration
+	c.zones = zones
+
+	log.FromContext(ctx).Info("[Provider] RFC2136 configured", "Server", c.server, "Transport", c.client.Net, "TSIG", c.key != "")
+	return nil
+}
+
+func (c *rfc2136) Zones() []string {
+	return c.zones
+}
+
+// Check that the nameserver answers for the zone. The query is signed when a TSIG key is
+// configured, so an invalid key fails the check.
+func (c *rfc2136) Check(ctx context.Context, zone string) error {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(zone), dns.TypeSOA)
+
+	if err := c.exchange(ctx, msg); err != nil {
+		return fmt.Errorf("PB-RFC-#0009: Failed to query the SOA of %s on %s -- %w", zone, c.server, err)
+	}
+
+	return nil
+}
+
+// Create the record's RRset. Any RRset with the same name and type is replaced so creating a record
+// that already exists on the server doesn't fail.
+func (c *rfc2136) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := c.replace(ctx, &record); err != nil {
+		return fmt.Errorf("PB-RFC-#0003: Failed to create DNS record -- %w", err)
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "RFC2136 record created")
+	return nil
+}
+
+// Update replaces the record's RRset with the record's targets and TTL.
+func (c *rfc2136) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := c.replace(ctx, &record); err != nil {
+		return fmt.Errorf("PB-RFC-#0004: Failed to update DNS record -- %w", err)
+	}
+
+	su.StageCondition(konditions.ConditionCreated, "RFC2136 record updated")
+	return nil
+}
+
+// Delete the record's RRset. Removing an RRset that doesn't exist is not an error for the server.
+func (c *rfc2136) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	rrtype, err := recordType(&record)
+	if err != nil {
+		return err
+	}
+
+	msg := new(dns.Msg)
+	msg.SetUpdate(dns.Fqdn(record.Spec.Zone))
+	msg.RemoveRRset([]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: fqdn(&record), Rrtype: rrtype, Class: dns.ClassINET}}})
+
+	if err := c.exchange(ctx, msg); err != nil {
+		return fmt.Errorf("PB-RFC-#0005: Failed to delete DNS record -- %w", err)
+	}
+
+	su.StageCondition(konditions.ConditionTerminated, "RFC2136 record deleted")
+	return nil
+}
+
+// Read queries the server for the record's RRset. The query is sent without recursion so
+// the answer comes from the zone itself.
+func (c *rfc2136) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	rrtype, err := recordType(&record)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(fqdn(&record), rrtype)
+	msg.RecursionDesired = false
+
+	response, _, err := c.client.ExchangeContext(ctx, msg, c.server)
+	if err != nil {
+		return nil, providers.Transient(fmt.Errorf("PB-RFC-#0006: Failed to read DNS record -- %w", err))
+	}
+
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, classify(fmt.Errorf("PB-RFC-#0006: Failed to read DNS record -- %w", rcodeError(response.Rcode)))
+	}
+
+	remote := &providers.RemoteRecord{}
+	for _, rr := range response.Answer {
+		if rr.Header().Rrtype != rrtype {
+			continue
+		}
+
+		ttl := int64(rr.Header().Ttl)
+		remote.TTL = &ttl
+		remote.Targets = append(remote.Targets, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+
+	if len(remote.Targets) == 0 {
+		return nil, providers.ErrRecordNotFound
+	}
+
+	return remote, nil
+}
+
+// Replace the record's RRset on the server with the record's targets. The removal and the additions are
+// sent in the same message, which the server applies atomically.
+func (c *rfc2136) replace(ctx context.Context, record *phonebook.DNSRecord) error {
+	rrs, err := resourceRecords(record)
+	if err != nil {
+		return err
+	}
+
+	msg := new(dns.Msg)
+	msg.SetUpdate(dns.Fqdn(record.Spec.Zone))
+	msg.RemoveRRset(rrs)
+	msg.Insert(rrs)
+
+	return c.exchange(ctx, msg)
+}
+
+// Send the update to the server, signing it if a TSIG key is configured.
+func (c *rfc2136) exchange(ctx context.Context, msg *dns.Msg) error {
+	if c.key != "" {
+		msg.SetTsig(c.key, c.algorithm, 300, time.Now().Unix())
+	}
+
+	response, _, err := c.client.ExchangeContext(ctx, msg, c.server)
+	if err != nil {
+		if errors.Is(err, dns.ErrSecret) || errors.Is(err, dns.ErrSig) || errors.Is(err, dns.ErrKeyAlg) {
+			return providers.Permanent(err)
+		}
+
+		// Network failures (ie. the server is restarting) are expected to go away.
+		return providers.Transient(err)
+	}
+
+	if response.Rcode != dns.RcodeSuccess {
+		return classify(rcodeError(response.Rcode))
+	}
+
+	return nil
+}
+
+// Convert the record's targets to resource records. The targets are parsed the same way they would be in a zone
+// file, so names in a target (ie. CNAME) are fully qualified and TXT values are quoted.
+func resourceRecords(record *phonebook.DNSRecord) ([]dns.RR, error) {
+	ttl := defaultTTL
+	if record.Spec.TTL != nil {
+		ttl = *record.Spec.TTL
+	}
+
+	var rrs []dns.RR
+	for _, target := range record.Spec.Targets {
+		if strings.EqualFold(record.Spec.RecordType, "TXT") {
+			target = fmt.Sprintf("\"%s\"", strings.Trim(target, "\""))
+		}
+
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", fqdn(record), ttl, record.Spec.RecordType, target))
+		if err != nil || rr == nil {
+			return nil, providers.Permanent(fmt.Errorf("PB-RFC-#0007: Invalid target (%s) for %s record -- %v", target, record.Spec.RecordType, err))
+		}
+
+		rrs = append(rrs, rr)
+	}
+
+	return rrs, nil
+}
+
+func recordType(record *phonebook.DNSRecord) (uint16, error) {
+	rrtype, ok := dns.StringToType[strings.ToUpper(record.Spec.RecordType)]
+	if !ok {
+		return 0, providers.Permanent(fmt.Errorf("PB-RFC-#0007: Unsupported record type %s", record.Spec.RecordType))
+	}
+
+	return rrtype, nil
+}
+
+func fqdn(record *phonebook.DNSRecord) string {
+	return dns.Fqdn(fmt.Sprintf("%s.%s", record.Spec.Name, record.Spec.Zone))
+}
+
+type rcodeError int
+
+func (e rcodeError) Error() string {
+	return fmt.Sprintf("server responded with %s", dns.RcodeToString[int(e)])
+}
+
+// classify wraps err with its kind. A server that fails to process an update (SERVFAIL) is expected
+// to recover, while a refused update (ie. the key isn't allowed to update the zone) needs to be fixed by the user.
+func classify(err error) error {
+	var rcode rcodeError
+	if errors.As(err, &rcode) && int(rcode) == dns.RcodeServerFailure {
+		return providers.Transient(err)
+	}
+
+	return providers.Permanent(err)
+}
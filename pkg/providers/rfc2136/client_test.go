@@ -0,0 +1,263 @@
+package rfc2136
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+)
+
+const (
+	kTestKey    = "phonebook."
+	kTestSecret = "c2VjcmV0LXNoYXJlZC13aXRoLXRoZS1zZXJ2ZXI="
+)
+
+// authoritative is an in-process nameserver for mydomain.com that accepts updates signed
+// with the test key.
+type authoritative struct {
+	mu      sync.Mutex
+	records map[string][]dns.RR
+}
+
+func (a *authoritative) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	msg := new(dns.Msg)
+	msg.SetReply(req)
+	msg.Authoritative = true
+
+	switch {
+	case req.Opcode == dns.OpcodeUpdate && (req.IsTsig() == nil || w.TsigStatus() != nil):
+		msg.Rcode = dns.RcodeRefused
+
+	case req.Opcode == dns.OpcodeUpdate:
+		for _, rr := range req.Ns {
+			h := rr.Header()
+			key := h.Name + dns.TypeToString[h.Rrtype]
+
+			switch h.Class {
+			case dns.ClassANY:
+				delete(a.records, key)
+			case dns.ClassINET:
+				a.records[key] = append(a.records[key], rr)
+			}
+		}
+
+	default:
+		q := req.Question[0]
+		msg.Answer = a.records[q.Name+dns.TypeToString[q.Qtype]]
+	}
+
+	if req.IsTsig() != nil && w.TsigStatus() == nil {
+		msg.SetTsig(kTestKey, dns.HmacSHA256, 300, time.Now().Unix())
+	}
+
+	w.WriteMsg(msg)
+}
+
+func startAuthoritative(t *testing.T, transport string) (*authoritative, string) {
+	a := &authoritative{records: map[string][]dns.RR{}}
+	server := &dns.Server{
+		Handler:    a,
+		TsigSecret: map[string]string{kTestKey: kTestSecret},
+
+		// The default function rejects updates.
+		MsgAcceptFunc: func(dns.Header) dns.MsgAcceptAction { return dns.MsgAccept },
+	}
+
+	var addr string
+	if transport == "tcp" {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.Listener = l
+		addr = l.Addr().String()
+	} else {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.PacketConn = pc
+		addr = pc.LocalAddr().String()
+	}
+
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+
+	return a, addr
+}
+
+func newTestClient(t *testing.T, transport, addr, secret string) *rfc2136 {
+	t.Setenv(kRFC2136Server, addr)
+	t.Setenv(kRFC2136Transport, transport)
+	t.Setenv(kRFC2136TSIGKey, "phonebook")
+	t.Setenv(kRFC2136TSIGSecret, secret)
+
+	c, err := NewClient(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Configure(context.TODO(), "rfc2136-test", []string{"mydomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestLifecycle(t *testing.T) {
+	for _, transport := range []string{"udp", "tcp"} {
+		t.Run(transport, func(t *testing.T) {
+			a, addr := startAuthoritative(t, transport)
+			c := newTestClient(t, transport, addr, kTestSecret)
+
+			record := phonebook.DNSRecord{
+				Spec: phonebook.DNSRecordSpec{
+					Zone:       "mydomain.com",
+					Name:       "www",
+					RecordType: "A",
+					Targets:    []string{"127.0.0.1", "127.0.0.2"},
+				},
+			}
+
+			updater := &mocks.Updater{}
+			if err := c.Create(context.TODO(), record, updater); err != nil {
+				t.Fatal(err)
+			}
+
+			if *updater.Status != konditions.ConditionCreated {
+				t.Errorf("Expected the record to be created, got: %s", *updater.Status)
+			}
+
+			if len(a.records["www.mydomain.com.A"]) != 2 {
+				t.Errorf("Expected the server to have both targets, got: %v", a.records)
+			}
+
+			record.Spec.Targets = []string{"127.0.0.3"}
+			if err := c.Update(context.TODO(), record, updater); err != nil {
+				t.Fatal(err)
+			}
+
+			remote, err := c.Read(context.TODO(), record)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !remote.Matches(record.Spec) {
+				t.Errorf("Expected the remote record to match the spec, got: %v", remote.Targets)
+			}
+
+			if err := c.Delete(context.TODO(), record, updater); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := c.Read(context.TODO(), record); !errors.Is(err, providers.ErrRecordNotFound) {
+				t.Errorf("Expected the record to not be found, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestTXTRecord(t *testing.T) {
+	a, addr := startAuthoritative(t, "udp")
+	c := newTestClient(t, "udp", addr, kTestSecret)
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "_acme-challenge",
+			RecordType: "TXT",
+			Targets:    []string{"some challenge"},
+		},
+	}
+
+	if err := c.Create(context.TODO(), record, &mocks.Updater{}); err != nil {
+		t.Fatal(err)
+	}
+
+	txt, ok := a.records["_acme-challenge.mydomain.com.TXT"][0].(*dns.TXT)
+	if !ok || strings.Join(txt.Txt, "") != "some challenge" {
+		t.Errorf("Expected the TXT value to be kept as a single string, got: %v", a.records)
+	}
+
+	remote, err := c.Read(context.TODO(), record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !remote.Matches(record.Spec) {
+		t.Errorf("Expected the remote record to match the spec, got: %v", remote.Targets)
+	}
+}
+
+func TestRefusedUpdate(t *testing.T) {
+	_, addr := startAuthoritative(t, "udp")
+	c := newTestClient(t, "udp", addr, "d3Jvbmctc2VjcmV0")
+
+	err := c.Create(context.TODO(), phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}, &mocks.Updater{})
+
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-RFC-#0003") {
+		t.Fatalf("Expected the update to fail, got: %v", err)
+	}
+
+	if providers.Retryable(err) {
+		t.Errorf("Expected a refused update to be permanent, got: %v", err)
+	}
+}
+
+func TestNewClientConfiguration(t *testing.T) {
+	t.Setenv(kRFC2136Server, "ns1.mydomain.com")
+	t.Setenv(kRFC2136Transport, "")
+	t.Setenv(kRFC2136TSIGKey, "")
+
+	c, err := NewClient(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.server != "ns1.mydomain.com:53" || c.client.Net != "udp" || c.key != "" {
+		t.Errorf("Expected the default port and transport without TSIG, got: %s %s %s", c.server, c.client.Net, c.key)
+	}
+
+	t.Setenv(kRFC2136Transport, "quic")
+	if _, err := NewClient(context.TODO()); err == nil || !strings.HasPrefix(err.Error(), "PB-RFC-#0008") {
+		t.Errorf("Expected an invalid transport to fail, got: %v", err)
+	}
+
+	t.Setenv(kRFC2136Transport, "tcp")
+	t.Setenv(kRFC2136TSIGKey, "phonebook")
+	t.Setenv(kRFC2136TSIGSecret, kTestSecret)
+	t.Setenv(kRFC2136TSIGAlgorithm, "hmac-md5")
+	if _, err := NewClient(context.TODO()); err == nil || !strings.HasPrefix(err.Error(), "PB-RFC-#0002") {
+		t.Errorf("Expected an unsupported algorithm to fail, got: %v", err)
+	}
+
+	t.Setenv(kRFC2136TSIGAlgorithm, "HMAC-SHA512")
+	c, err = NewClient(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.algorithm != dns.HmacSHA512 || c.key != "phonebook." {
+		t.Errorf("Expected the key to be configured, got: %s %s", c.key, c.algorithm)
+	}
+}
@@ -0,0 +1,232 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	utils "github.com/pier-oliviert/phonebook/pkg/utils"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	kWebhookURL     = "WEBHOOK_URL"
+	kWebhookToken   = "WEBHOOK_TOKEN"
+	kWebhookTimeout = "WEBHOOK_TIMEOUT"
+	defaultTimeout  = 30 * time.Second
+)
+
+// webhook is a provider that forwards every operation to a webhook over HTTP, which lets providers be implemented
+// outside of Phonebook in any language. The protocol is described by the types in protocol.go.
+type webhook struct {
+	integration string
+	zones       []string
+
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func init() {
+	providers.Register("webhook", func(ctx context.Context) (providers.Provider, error) {
+		return NewClient(ctx)
+	})
+}
+
+// NewClient creates a provider for the webhook located at WEBHOOK_URL. When WEBHOOK_TOKEN is set, it's sent
+// as a bearer token with every request.
+func NewClient(ctx context.Context) (*webhook, error) {
+	rawURL, err := utils.RetrieveValue(ctx, kWebhookURL)
+	if err != nil {
+		return nil, fmt.Errorf("PB-WH-#0001: Webhook URL not found -- %w", err)
+	}
+
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("PB-WH-#0001: Webhook URL (%s) is not a valid URL -- %v", rawURL, err)
+	}
+
+	timeout := defaultTimeout
+	if value, _ := utils.RetrieveValue(ctx, kWebhookTimeout); strings.TrimSpace(value) != "" {
+		if timeout, err = time.ParseDuration(strings.TrimSpace(value)); err != nil {
+			return nil, fmt.Errorf("PB-WH-#0001: Invalid value for %s -- %w", kWebhookTimeout, err)
+		}
+	}
+
+	token, _ := utils.RetrieveValue(ctx, kWebhookToken)
+
+	return &webhook{
+		baseURL: strings.TrimSuffix(u.String(), "/"),
+		token:   strings.TrimSpace(token),
+		client:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Configure sends the integration and its zones to the webhook.
+func (w *webhook) Configure(ctx context.Context, integration string, zones []string) error {
+	if err := w.post(ctx, PathConfigure, ConfigureRequest{Integration: integration, Zones: zones}, nil); err != nil {
+		return fmt.Errorf("PB-WH-#0002: Webhook refused the configuration -- %w", err)
+	}
+
+	w.integration = integration
+	w.zones = zones
+
+	log.FromContext(ctx).Info("[Provider] Webhook configured", "URL", w.baseURL, "Zones", zones)
+	return nil
+}
+
+func (w *webhook) Zones() []string {
+	return w.zones
+}
+
+func (w *webhook) Create(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := w.stage(ctx, PathCreate, &record, su, konditions.ConditionCreated); err != nil {
+		return fmt.Errorf("PB-WH-#0003: Failed to create DNS record -- %w", err)
+	}
+
+	return nil
+}
+
+func (w *webhook) Update(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := w.stage(ctx, PathUpdate, &record, su, konditions.ConditionCreated); err != nil {
+		return fmt.Errorf("PB-WH-#0004: Failed to update DNS record -- %w", err)
+	}
+
+	return nil
+}
+
+func (w *webhook) Delete(ctx context.Context, record phonebook.DNSRecord, su phonebook.StagingUpdater) error {
+	if err := w.stage(ctx, PathDelete, &record, su, konditions.ConditionTerminated); err != nil {
+		return fmt.Errorf("PB-WH-#0005: Failed to delete DNS record -- %w", err)
+	}
+
+	return nil
+}
+
+// Read the record from the webhook. A 404 means the record doesn't exist.
+func (w *webhook) Read(ctx context.Context, record phonebook.DNSRecord) (*providers.RemoteRecord, error) {
+	var response ReadResponse
+	if err := w.post(ctx, PathRead, w.request(&record), &response); err != nil {
+		if statusCode(err) == http.StatusNotFound {
+			return nil, providers.ErrRecordNotFound
+		}
+
+		return nil, fmt.Errorf("PB-WH-#0006: Failed to read DNS record -- %w", err)
+	}
+
+	return &providers.RemoteRecord{Targets: response.Targets, TTL: response.TTL}, nil
+}
+
+// Send the record to the webhook and stage the values it returned. When the webhook doesn't return a
+// status, the status expected for the operation is staged.
+func (w *webhook) stage(ctx context.Context, path string, record *phonebook.DNSRecord, su phonebook.StagingUpdater, status konditions.ConditionStatus) error {
+	var response RecordResponse
+	if err := w.post(ctx, path, w.request(record), &response); err != nil {
+		return err
+	}
+
+	if response.RemoteInfo != nil {
+		su.StageRemoteInfo(response.RemoteInfo)
+	}
+
+	if response.Status != "" {
+		status = response.Status
+	}
+
+	su.StageCondition(status, response.Reason)
+	return nil
+}
+
+func (w *webhook) request(record *phonebook.DNSRecord) RecordRequest {
+	return RecordRequest{
+		Integration: w.integration,
+		Record: Record{
+			Namespace:  record.Namespace,
+			Name:       record.Name,
+			Spec:       record.Spec,
+			RemoteInfo: record.Status.RemoteInfo[w.integration],
+		},
+	}
+}
+
+// Send the body to the webhook's endpoint and decode the response into out. Responses without
+// a body (ie. 204) leave out untouched.
+func (w *webhook) post(ctx context.Context, path string, body any, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return providers.Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return providers.Permanent(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		// The webhook couldn't be reached (ie. the sidecar is restarting), network errors are classified as transient.
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &apiError{StatusCode: resp.StatusCode}
+
+		var body ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err == nil {
+			apiErr.Message = body.Error
+		}
+
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+
+		return providers.FromHTTPStatus(apiErr, resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return providers.Permanent(fmt.Errorf("PB-WH-#0007: Invalid response from %s -- %w", path, err))
+	}
+
+	return nil
+}
+
+// apiError is the error returned by the webhook, as described by ErrorResponse.
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+// Return the status code of the webhook's response, or zero if the request failed before a response was received.
+func statusCode(err error) int {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+
+	return 0
+}
@@ -0,0 +1,162 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/mocks"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"github.com/pier-oliviert/phonebook/pkg/providers/webhook"
+	"github.com/pier-oliviert/phonebook/pkg/providers/webhook/webhooktest"
+)
+
+type provider interface {
+	providers.Provider
+	Read(context.Context, phonebook.DNSRecord) (*providers.RemoteRecord, error)
+}
+
+func newTestProvider(t *testing.T, server *webhooktest.Server, token string) provider {
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	t.Setenv("WEBHOOK_URL", ts.URL)
+	t.Setenv("WEBHOOK_TOKEN", token)
+	t.Setenv("WEBHOOK_TIMEOUT", "")
+
+	p, err := webhook.NewClient(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestLifecycle(t *testing.T) {
+	server := webhooktest.NewServer()
+	server.Token = "secret"
+
+	p := newTestProvider(t, server, "secret")
+	if err := p.Configure(context.TODO(), "webhook-test", []string{"mydomain.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	record := phonebook.DNSRecord{
+		Spec: phonebook.DNSRecordSpec{
+			Zone:       "mydomain.com",
+			Name:       "www",
+			RecordType: "A",
+			Targets:    []string{"127.0.0.1"},
+		},
+	}
+	record.Name = "www"
+	record.Namespace = "default"
+
+	updater := &mocks.Updater{}
+	if err := p.Create(context.TODO(), record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	if *updater.Status != konditions.ConditionCreated || updater.Info["id"] != "1" {
+		t.Fatalf("Expected the record to be created with its ID, got: %s, %v", *updater.Status, updater.Info)
+	}
+
+	// The reconciler stores the RemoteInfo in the record's status, which is sent back to the webhook.
+	record.Status.RemoteInfo = map[string]phonebook.IntegrationInfo{"webhook-test": updater.Info}
+	record.Spec.Targets = []string{"127.0.0.2"}
+
+	updater = &mocks.Updater{}
+	if err := p.Update(context.TODO(), record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	// The webhook doesn't return a status on update, which defaults to Created.
+	if *updater.Status != konditions.ConditionCreated {
+		t.Errorf("Expected the record to be created, got: %s", *updater.Status)
+	}
+
+	remote, err := p.Read(context.TODO(), record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !remote.Matches(record.Spec) {
+		t.Errorf("Expected the remote record to match the spec, got: %v", remote.Targets)
+	}
+
+	updater = &mocks.Updater{}
+	if err := p.Delete(context.TODO(), record, updater); err != nil {
+		t.Fatal(err)
+	}
+
+	if *updater.Status != konditions.ConditionTerminated {
+		t.Errorf("Expected the record to be deleted, got: %s", *updater.Status)
+	}
+
+	if _, err := p.Read(context.TODO(), record); !errors.Is(err, providers.ErrRecordNotFound) {
+		t.Errorf("Expected the record to not be found, got: %v", err)
+	}
+}
+
+func TestConfigureRefused(t *testing.T) {
+	server := webhooktest.NewServer()
+	server.Zones = []string{"otherdomain.com"}
+
+	p := newTestProvider(t, server, "")
+	err := p.Configure(context.TODO(), "webhook-test", []string{"mydomain.com"})
+	if err == nil || !strings.HasPrefix(err.Error(), "PB-WH-#0002") || !strings.Contains(err.Error(), "not managed") {
+		t.Errorf("Expected the configuration to be refused, got: %v", err)
+	}
+}
+
+func TestInvalidToken(t *testing.T) {
+	server := webhooktest.NewServer()
+	server.Token = "secret"
+
+	p := newTestProvider(t, server, "invalid")
+	err := p.Configure(context.TODO(), "webhook-test", []string{"mydomain.com"})
+	if err == nil || providers.Retryable(err) {
+		t.Errorf("Expected an invalid token to be a permanent error, got: %v", err)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		status    int
+		retryable bool
+	}{
+		{status: http.StatusTooManyRequests, retryable: true},
+		{status: http.StatusServiceUnavailable, retryable: true},
+		{status: http.StatusBadRequest, retryable: false},
+		{status: http.StatusUnprocessableEntity, retryable: false},
+	}
+
+	for _, tt := range tests {
+		server := webhooktest.NewServer()
+		p := newTestProvider(t, server, "")
+		server.FailWith = tt.status
+
+		err := p.Create(context.TODO(), phonebook.DNSRecord{}, &mocks.Updater{})
+		if err == nil || !strings.HasPrefix(err.Error(), "PB-WH-#0003") {
+			t.Fatalf("Expected the creation to fail, got: %v", err)
+		}
+
+		if providers.Retryable(err) != tt.retryable {
+			t.Errorf("Expected status %d to be retryable: %t, got: %v", tt.status, tt.retryable, err)
+		}
+	}
+}
+
+func TestMissingURL(t *testing.T) {
+	t.Setenv("WEBHOOK_URL", "")
+
+	if _, err := webhook.NewClient(context.TODO()); err == nil || !strings.HasPrefix(err.Error(), "PB-WH-#0001") {
+		t.Errorf("Expected a missing URL to fail, got: %v", err)
+	}
+}
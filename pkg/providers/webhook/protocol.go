@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+)
+
+// Version of the protocol, used as the prefix of every path (ie. /v1/records/create). Changes that
+// aren't backward compatible are made in a new version so webhooks can support more than one.
+const ProtocolVersion = "v1"
+
+// Paths of the endpoints, relative to the webhook's URL. All the endpoints are called with POST and
+// JSON bodies.
+const (
+	PathConfigure = "/" + ProtocolVersion + "/configure"
+	PathCreate    = "/" + ProtocolVersion + "/records/create"
+	PathUpdate    = "/" + ProtocolVersion + "/records/update"
+	PathDelete    = "/" + ProtocolVersion + "/records/delete"
+	PathRead      = "/" + ProtocolVersion + "/records/read"
+)
+
+// ConfigureRequest is sent once, when the provider starts. The webhook can refuse the configuration (ie. a zone
+// it can't manage) by returning an error, which stops the provider.
+type ConfigureRequest struct {
+	Integration string   `json:"integration"`
+	Zones       []string `json:"zones"`
+}
+
+// RecordRequest is the body sent to the create, update, delete and read endpoints.
+type RecordRequest struct {
+	Integration string `json:"integration"`
+	Record      Record `json:"record"`
+}
+
+// Record is the DNSRecord the operation applies to. The spec is resolved, which means the zone is always
+// set and the name is relative to it.
+type Record struct {
+	// Namespace and name of the DNSRecord in Kubernetes.
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	Spec phonebook.DNSRecordSpec `json:"spec"`
+
+	// Information the webhook staged for this record in previous operations.
+	RemoteInfo phonebook.IntegrationInfo `json:"remoteInfo,omitempty"`
+}
+
+// RecordResponse is returned by the create, update and delete endpoints. It holds the values staged
+// for the record's condition and RemoteInfo. The response is optional; when the status is empty, the record
+// is marked as Created by create and update, and as Terminated by delete.
+type RecordResponse struct {
+	Status konditions.ConditionStatus `json:"status,omitempty"`
+	Reason string                     `json:"reason,omitempty"`
+
+	// RemoteInfo replaces the information stored for the record when it's set.
+	RemoteInfo phonebook.IntegrationInfo `json:"remoteInfo,omitempty"`
+}
+
+// ReadResponse is returned by the read endpoint with the values stored by the webhook's DNS service. A record that
+// doesn't exist is reported with a 404 status code.
+type ReadResponse struct {
+	Targets []string `json:"targets"`
+	TTL     *int64   `json:"ttl,omitempty"`
+}
+
+// ErrorResponse is the body of any response with a status code of 400 or more. Errors are retried when the
+// status code is 408, 429 or 5xx, after the delay set by the Retry-After header if there's one.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,146 @@
+// Package webhooktest provides a reference implementation of the webhook protocol. It keeps records in memory
+// and can be used to test the webhook provider, or as a starting point to implement a webhook.
+package webhooktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers/webhook"
+)
+
+// Server is an http.Handler that implements the webhook protocol. Each record is stored with an ID
+// that's returned in the record's RemoteInfo, the same way a provider stores the ID of a remote record.
+type Server struct {
+	mu sync.Mutex
+
+	// Token expected in the Authorization header. Requests aren't authenticated when it's empty.
+	Token string
+
+	// Zones the server accepts when it's configured. All zones are accepted when it's empty.
+	Zones []string
+
+	// Records stored by the server, indexed by their ID.
+	Records map[string]webhook.ReadResponse
+
+	// When set, every request fails with this status code.
+	FailWith int
+
+	// Requests received by the server, indexed by their path.
+	Requests map[string]int
+
+	nextID int
+}
+
+func NewServer() *Server {
+	return &Server{
+		Records:  map[string]webhook.ReadResponse{},
+		Requests: map[string]int{},
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Requests[r.URL.Path]++
+
+	if s.Token != "" && r.Header.Get("Authorization") != "Bearer "+s.Token {
+		fail(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	if s.FailWith != 0 {
+		w.Header().Set("Retry-After", "1")
+		fail(w, s.FailWith, http.StatusText(s.FailWith))
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		fail(w, http.StatusMethodNotAllowed, "only POST is supported")
+		return
+	}
+
+	if r.URL.Path == webhook.PathConfigure {
+		var req webhook.ConfigureRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		for _, zone := range req.Zones {
+			if len(s.Zones) != 0 && !slices.Contains(s.Zones, zone) {
+				fail(w, http.StatusUnprocessableEntity, fmt.Sprintf("zone %s is not managed by this webhook", zone))
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var req webhook.RecordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id := req.Record.RemoteInfo["id"]
+
+	switch r.URL.Path {
+	case webhook.PathCreate:
+		s.nextID++
+		id = strconv.Itoa(s.nextID)
+		s.Records[id] = webhook.ReadResponse{Targets: req.Record.Spec.Targets, TTL: req.Record.Spec.TTL}
+
+		respond(w, webhook.RecordResponse{
+			Status:     konditions.ConditionCreated,
+			Reason:     fmt.Sprintf("Record %s created", id),
+			RemoteInfo: phonebook.IntegrationInfo{"id": id},
+		})
+
+	case webhook.PathUpdate:
+		if _, ok := s.Records[id]; !ok {
+			fail(w, http.StatusNotFound, fmt.Sprintf("record %s not found", id))
+			return
+		}
+
+		s.Records[id] = webhook.ReadResponse{Targets: req.Record.Spec.Targets, TTL: req.Record.Spec.TTL}
+		respond(w, webhook.RecordResponse{Reason: fmt.Sprintf("Record %s updated", id)})
+
+	case webhook.PathDelete:
+		delete(s.Records, id)
+
+		// The status defaults to Terminated when the webhook doesn't return one.
+		w.WriteHeader(http.StatusNoContent)
+
+	case webhook.PathRead:
+		record, ok := s.Records[id]
+		if !ok {
+			fail(w, http.StatusNotFound, fmt.Sprintf("record %s not found", id))
+			return
+		}
+
+		respond(w, record)
+
+	default:
+		fail(w, http.StatusNotFound, fmt.Sprintf("%s is not part of protocol %s", r.URL.Path, webhook.ProtocolVersion))
+	}
+}
+
+func respond(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func fail(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(webhook.ErrorResponse{Error: message})
+}
@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ParseZoneOverrides parses the value of a provider's zone override variable (ie. AWS_ZONE_ID). The value is
+// a comma separated list of `<zone>=<value>` entries that lets the user skip the lookup a provider does
+// for each zone it's configured with.
+//
+// A single value without a zone (ie. `Z1234`) is supported for integrations that only have one zone, which
+// is how providers used to be configured before an integration could manage multiple zones.
+func ParseZoneOverrides(value string, zones []string) (map[string]string, error) {
+	overrides := map[string]string{}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return overrides, nil
+	}
+
+	if !strings.Contains(value, "=") {
+		if len(zones) != 1 {
+			return nil, fmt.Errorf("PB-#0102: Zone override (%s) needs to specify the zone it applies to when the integration has more than one zone", value)
+		}
+
+		overrides[zones[0]] = value
+		return overrides, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		zone, v, found := strings.Cut(strings.TrimSpace(entry), "=")
+		zone, v = strings.TrimSpace(zone), strings.TrimSpace(v)
+		if !found || zone == "" || v == "" {
+			return nil, fmt.Errorf("PB-#0102: Zone override (%s) needs to be formatted as <zone>=<value>", entry)
+		}
+
+		if !slices.Contains(zones, zone) {
+			return nil, fmt.Errorf("PB-#0102: Zone override (%s) refers to a zone the integration doesn't have authority over", entry)
+		}
+
+		overrides[zone] = v
+	}
+
+	return overrides, nil
+}
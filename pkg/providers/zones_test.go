@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseZoneOverrides(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		zones    []string
+		expected map[string]string
+		err      bool
+	}{
+		{
+			name:     "No overrides",
+			value:    "",
+			zones:    []string{"mydomain.com", "otherdomain.com"},
+			expected: map[string]string{},
+		},
+		{
+			name:     "Single value for a single zone",
+			value:    "Z1234",
+			zones:    []string{"mydomain.com"},
+			expected: map[string]string{"mydomain.com": "Z1234"},
+		},
+		{
+			name:  "Single value for multiple zones",
+			value: "Z1234",
+			zones: []string{"mydomain.com", "otherdomain.com"},
+			err:   true,
+		},
+		{
+			name:     "Values per zone",
+			value:    "mydomain.com=Z1234, otherdomain.com = Z5678",
+			zones:    []string{"mydomain.com", "otherdomain.com"},
+			expected: map[string]string{"mydomain.com": "Z1234", "otherdomain.com": "Z5678"},
+		},
+		{
+			name:  "Unknown zone",
+			value: "unknown.com=Z1234",
+			zones: []string{"mydomain.com"},
+			err:   true,
+		},
+		{
+			name:  "Missing value",
+			value: "mydomain.com=",
+			zones: []string{"mydomain.com"},
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			overrides, err := ParseZoneOverrides(test.value, test.zones)
+			if test.err {
+				if err == nil || !strings.HasPrefix(err.Error(), "PB-#0102") {
+					t.Errorf("Expected a PB-#0102 error, got: %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(overrides, test.expected) {
+				t.Errorf("Expected %v, got: %v", test.expected, overrides)
+			}
+		})
+	}
+}
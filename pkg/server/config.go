@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	reconcilers "github.com/pier-oliviert/phonebook/internal/reconcilers/provider"
+	"github.com/pier-oliviert/phonebook/pkg/propagation"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"github.com/pier-oliviert/phonebook/pkg/providers/registry"
+)
+
+// config holds the settings of a provider that are common to every provider. The values are read from
+// the environment when the provider runs in its own deployment, or from the integration when it runs
+// inside the controller.
+type config struct {
+	integration    string
+	zones          []string
+	resyncInterval time.Duration
+	remediate      bool
+	healthInterval time.Duration
+	propagation    *propagation.Checker
+	ownerID        string
+	prefix         string
+}
+
+// Lookup returns the value for name, or def if the value isn't set.
+type lookup func(name, def string) string
+
+func loadConfig(get lookup) (*config, error) {
+	var err error
+	c := &config{
+		integration: get("PB_INTEGRATION", ""),
+		zones:       strings.Split(get("PB_ZONES", ""), ","),
+		ownerID:     get("PB_REGISTRY_OWNER_ID", ""),
+		prefix:      get("PB_REGISTRY_PREFIX", registry.DefaultPrefix),
+	}
+
+	// Records are read back from the provider at this interval to detect drift. Setting
+	// the interval to 0 disables drift detection.
+	c.resyncInterval, err = time.ParseDuration(get("PB_RESYNC_INTERVAL", "10m"))
+	if err != nil {
+		return nil, fmt.Errorf("PB#0007: Invalid value for PB_RESYNC_INTERVAL -- %w", err)
+	}
+
+	c.remediate, err = strconv.ParseBool(get("PB_RESYNC_REMEDIATE", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("PB#0007: Invalid value for PB_RESYNC_REMEDIATE -- %w", err)
+	}
+
+	// The provider's self-check runs for each zone at this interval.
+	c.healthInterval, err = time.ParseDuration(get("PB_HEALTH_INTERVAL", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("PB#0007: Invalid value for PB_HEALTH_INTERVAL -- %w", err)
+	}
+
+	if c.healthInterval <= 0 {
+		return nil, fmt.Errorf("PB#0007: Invalid value for PB_HEALTH_INTERVAL -- %s is not a positive duration", c.healthInterval)
+	}
+
+	propagate, err := strconv.ParseBool(get("PB_PROPAGATION_CHECK", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("PB#0007: Invalid value for PB_PROPAGATION_CHECK -- %w", err)
+	}
+
+	if propagate {
+		if c.propagation, err = propagationChecker(get); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Configure the provider held by the store for the integration's zones. When an owner ID is set, the
+// provider is wrapped by the registry so it only modifies records owned by this integration.
+func (c *config) configure(ctx context.Context, store *providers.ProviderStore) error {
+	if c.ownerID != "" {
+		r, err := registry.NewRegistry(store.Provider(), c.ownerID, c.prefix)
+		if err != nil {
+			return err
+		}
+
+		store.Store(r)
+	}
+
+	// Error coming from a Provider should already be coded, so returning it as is.
+	return store.Provider().Configure(ctx, c.integration, c.zones)
+}
+
+func (c *config) reconciler(mgr ctrl.Manager, store *providers.ProviderStore) *reconcilers.ProviderReconciler {
+	return &reconcilers.ProviderReconciler{
+		Integration:    c.integration,
+		Store:          store,
+		ResyncInterval: c.resyncInterval,
+		Remediate:      c.remediate,
+		Propagation:    c.propagation,
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		EventRecorder:  mgr.GetEventRecorderFor("dnsrecord"),
+	}
+}
+
+func (c *config) health(mgr ctrl.Manager, store *providers.ProviderStore) *health {
+	return &health{
+		Client:      mgr.GetClient(),
+		integration: c.integration,
+		zones:       store.Provider().Zones(),
+		interval:    c.healthInterval,
+		store:       store,
+		elected:     mgr.Elected(),
+	}
+}
+
+// Build the checker used to verify that records propagated to the zone's authoritative nameservers.
+func propagationChecker(get lookup) (*propagation.Checker, error) {
+	interval, err := time.ParseDuration(get("PB_PROPAGATION_INTERVAL", "5s"))
+	if err != nil {
+		return nil, fmt.Errorf("PB#0007: Invalid value for PB_PROPAGATION_INTERVAL -- %w", err)
+	}
+
+	timeout, err := time.ParseDuration(get("PB_PROPAGATION_TIMEOUT", "10m"))
+	if err != nil {
+		return nil, fmt.Errorf("PB#0007: Invalid value for PB_PROPAGATION_TIMEOUT -- %w", err)
+	}
+
+	var resolvers []string
+	for _, resolver := range strings.Split(get("PB_PROPAGATION_RESOLVERS", ""), ",") {
+		if resolver = strings.TrimSpace(resolver); resolver != "" {
+			resolvers = append(resolvers, resolver)
+		}
+	}
+
+	return propagation.NewChecker(resolvers, interval, timeout)
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func lookupFrom(values map[string]string) lookup {
+	return func(name, def string) string {
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return def
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	c, err := loadConfig(lookupFrom(map[string]string{
+		"PB_INTEGRATION":       "test",
+		"PB_ZONES":             "mydomain.com,otherdomain.com",
+		"PB_RESYNC_INTERVAL":   "1m",
+		"PB_RESYNC_REMEDIATE":  "true",
+		"PB_REGISTRY_OWNER_ID": "cluster-a",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.integration != "test" || len(c.zones) != 2 {
+		t.Errorf("Expected the integration and its zones to be set, got: %s, %v", c.integration, c.zones)
+	}
+
+	if c.resyncInterval != time.Minute || !c.remediate {
+		t.Errorf("Expected the resync settings to be set, got: %s, %t", c.resyncInterval, c.remediate)
+	}
+
+	if c.healthInterval != 5*time.Minute || c.propagation != nil {
+		t.Errorf("Expected the default settings, got: %s, %v", c.healthInterval, c.propagation)
+	}
+
+	if c.ownerID != "cluster-a" || c.prefix != "phonebook-" {
+		t.Errorf("Expected the registry to be set, got: %s, %s", c.ownerID, c.prefix)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	for _, name := range []string{"PB_RESYNC_INTERVAL", "PB_RESYNC_REMEDIATE", "PB_HEALTH_INTERVAL", "PB_PROPAGATION_CHECK"} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := loadConfig(lookupFrom(map[string]string{name: "invalid"})); err == nil || !strings.HasPrefix(err.Error(), "PB#0007") {
+				t.Errorf("Expected an invalid value error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigHealthInterval(t *testing.T) {
+	for _, value := range []string{"0s", "-1m"} {
+		t.Run(value, func(t *testing.T) {
+			_, err := loadConfig(lookupFrom(map[string]string{"PB_HEALTH_INTERVAL": value}))
+			if err == nil || !strings.HasPrefix(err.Error(), "PB#0007") || !strings.Contains(err.Error(), "not a positive duration") {
+				t.Errorf("Expected the interval to be refused, got: %v", err)
+			}
+		})
+	}
+}
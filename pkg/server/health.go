@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/api/v1alpha1/integrations"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+)
+
+const (
+	kCheckTimeout = 30 * time.Second
+	kReasonLength = 1024
+)
+
+// health runs the provider's self-check for each zone when the provider starts, and at every interval after that. The
+// provider is ready once every zone passed its check. Each replica runs the check so its readiness reflects what it
+// sees, but only the leader writes the results to the DNSIntegration's status, as a condition for each zone.
+//
+// Providers that don't implement providers.Checker pass their check as long as they were configured.
+type health struct {
+	client.Client
+	integration string
+	zones       []string
+	interval    time.Duration
+	store       *providers.ProviderStore
+
+	// Closed when this replica is elected as the leader.
+	elected <-chan struct{}
+
+	mu       sync.Mutex
+	checked  bool
+	failures map[string]error
+}
+
+func (h *health) Start(ctx context.Context) error {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	elected := h.elected
+	for {
+		if h.check(ctx) {
+			elected = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-elected:
+			// The replica was elected since its last check, the zones are checked again right away so
+			// the results are reported without waiting for the next interval.
+		}
+	}
+}
+
+// The check runs on every replica, not only the leader.
+func (h *health) NeedLeaderElection() bool {
+	return false
+}
+
+// Ready is the readiness check of the provider. It fails until every zone passed its check.
+func (h *health) Ready(_ *http.Request) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.checked {
+		return errors.New("PB#0013: The zones haven't been checked yet")
+	}
+
+	if len(h.failures) != 0 {
+		zones := []string{}
+		for _, zone := range h.zones {
+			if _, ok := h.failures[zone]; ok {
+				zones = append(zones, zone)
+			}
+		}
+
+		return fmt.Errorf("PB#0013: The provider's check failed for %s", strings.Join(zones, ", "))
+	}
+
+	return nil
+}
+
+// check every zone and report the results if this replica is the leader. It returns true when the results were reported.
+func (h *health) check(ctx context.Context) bool {
+	logger := log.FromContext(ctx)
+	checker, _ := h.store.Provider().(providers.Checker)
+
+	results := map[string]error{}
+	failures := map[string]error{}
+	for _, zone := range h.zones {
+		var err error
+		if checker != nil {
+			cctx, cancel := context.WithTimeout(ctx, kCheckTimeout)
+			err = checker.Check(cctx, zone)
+			cancel()
+		}
+
+		if err != nil {
+			logger.Error(err, "[Provider] Zone failed its check", "Zone", zone)
+			failures[zone] = err
+		}
+
+		results[zone] = err
+	}
+
+	h.mu.Lock()
+	h.checked = true
+	h.failures = failures
+	h.mu.Unlock()
+
+	select {
+	case <-h.elected:
+	default:
+		return false
+	}
+
+	if err := h.report(ctx, results); err != nil {
+		logger.Error(err, "[Provider] Could not report the zones' checks")
+	}
+
+	return true
+}
+
+// Write the result of the check for each zone in the DNSIntegration's status. The conditions of zones the provider
+// doesn't manage anymore are removed. The status is only updated when one of the conditions changed.
+func (h *health) report(ctx context.Context, results map[string]error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var integration phonebook.DNSIntegration
+		if err := h.Get(ctx, types.NamespacedName{Name: h.integration}, &integration); err != nil {
+			return fmt.Errorf("PB#0002: Couldn't retrieve the integration (%s) -- %w", h.integration, err)
+		}
+
+		changed := false
+		reported := map[konditions.ConditionType]bool{}
+		for zone, err := range results {
+			conditionType := konditions.ConditionType(fmt.Sprintf("%s%s", integrations.ZoneConditionPrefix, strings.TrimSuffix(zone, ".")))
+			reported[conditionType] = true
+
+			condition := integration.Status.Conditions.FindOrInitializeFor(conditionType)
+
+			status, reason := konditions.ConditionCompleted, "Zone passed the provider's check"
+			if err != nil {
+				status, reason = konditions.ConditionError, err.Error()
+			}
+
+			if len(reason) > kReasonLength {
+				reason = reason[:kReasonLength]
+			}
+
+			if condition.Status == status && condition.Reason == reason {
+				continue
+			}
+
+			condition.Status = status
+			condition.Reason = reason
+			if err := integration.Status.Conditions.SetCondition(condition); err != nil {
+				return err
+			}
+			changed = true
+		}
+
+		for _, condition := range integration.Status.Conditions.DeepCopy() {
+			if strings.HasPrefix(string(condition.Type), integrations.ZoneConditionPrefix) && !reported[condition.Type] {
+				integration.Status.Conditions.RemoveConditionWith(condition.Type)
+				changed = true
+			}
+		}
+
+		if !changed {
+			return nil
+		}
+
+		return h.Status().Update(ctx, &integration)
+	})
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pier-oliviert/konditionner/pkg/konditions"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+)
+
+// checkedProvider fails the check of the zones set in failures.
+type checkedProvider struct {
+	providers.Provider
+	failures map[string]error
+}
+
+func (p *checkedProvider) Check(ctx context.Context, zone string) error {
+	return p.failures[zone]
+}
+
+func newTestHealth(t *testing.T, p providers.Provider, elected bool) *health {
+	integration := &phonebook.DNSIntegration{ObjectMeta: meta.ObjectMeta{Name: "test"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(integration).WithStatusSubresource(integration).Build()
+
+	store := &providers.ProviderStore{}
+	store.Store(p)
+
+	ch := make(chan struct{})
+	if elected {
+		close(ch)
+	}
+
+	return &health{
+		Client:      c,
+		integration: "test",
+		zones:       []string{"mydomain.com", "otherdomain.com"},
+		store:       store,
+		elected:     ch,
+	}
+}
+
+func zoneStatus(t *testing.T, h *health, zone string) konditions.ConditionStatus {
+	var integration phonebook.DNSIntegration
+	if err := h.Get(context.TODO(), types.NamespacedName{Name: "test"}, &integration); err != nil {
+		t.Fatal(err)
+	}
+
+	condition := integration.Status.Conditions.FindType(konditions.ConditionType("zone." + zone))
+	if condition == nil {
+		return ""
+	}
+
+	return condition.Status
+}
+
+func TestHealthReady(t *testing.T) {
+	p := &checkedProvider{failures: map[string]error{"otherdomain.com": errors.New("PB-TEST-#0001: zone not found")}}
+	h := newTestHealth(t, p, true)
+
+	if err := h.Ready(nil); err == nil {
+		t.Error("Expected the provider to not be ready before its first check")
+	}
+
+	h.check(context.TODO())
+
+	err := h.Ready(nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "PB#0013") || !strings.Contains(err.Error(), "otherdomain.com") || strings.Contains(err.Error(), "mydomain.com") {
+		t.Errorf("Expected the failing zone to make the provider not ready, got: %v", err)
+	}
+
+	if status := zoneStatus(t, h, "mydomain.com"); status != konditions.ConditionCompleted {
+		t.Errorf("Expected the zone to pass its check, got: %s", status)
+	}
+
+	if status := zoneStatus(t, h, "otherdomain.com"); status != konditions.ConditionError {
+		t.Errorf("Expected the zone to fail its check, got: %s", status)
+	}
+
+	p.failures = nil
+	h.check(context.TODO())
+
+	if err := h.Ready(nil); err != nil {
+		t.Errorf("Expected the provider to be ready, got: %v", err)
+	}
+
+	if status := zoneStatus(t, h, "otherdomain.com"); status != konditions.ConditionCompleted {
+		t.Errorf("Expected the zone to pass its check, got: %s", status)
+	}
+}
+
+func TestHealthWithoutChecker(t *testing.T) {
+	var p struct{ providers.Provider }
+	h := newTestHealth(t, &p, true)
+
+	h.check(context.TODO())
+	if err := h.Ready(nil); err != nil {
+		t.Errorf("Expected a provider without a check to be ready, got: %v", err)
+	}
+}
+
+func TestHealthFollower(t *testing.T) {
+	h := newTestHealth(t, &checkedProvider{}, false)
+
+	h.check(context.TODO())
+	if err := h.Ready(nil); err != nil {
+		t.Errorf("Expected the provider to be ready, got: %v", err)
+	}
+
+	if status := zoneStatus(t, h, "mydomain.com"); status != "" {
+		t.Errorf("Expected only the leader to report the zones' checks, got: %s", status)
+	}
+}
+
+func TestHealthReportsOnElection(t *testing.T) {
+	h := newTestHealth(t, &checkedProvider{}, false)
+	h.interval = time.Hour
+
+	elected := make(chan struct{})
+	h.elected = elected
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go h.Start(ctx)
+
+	// The first check runs before the replica is elected.
+	for h.Ready(nil) != nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(elected)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for zoneStatus(t, h, "mydomain.com") != konditions.ConditionCompleted {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the zones' checks to be reported as soon as the replica is elected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHealthRemovesZones(t *testing.T) {
+	p := &checkedProvider{failures: map[string]error{"otherdomain.com": errors.New("PB-TEST-#0001: zone not found")}}
+	h := newTestHealth(t, p, true)
+
+	h.check(context.TODO())
+	if status := zoneStatus(t, h, "otherdomain.com"); status != konditions.ConditionError {
+		t.Fatalf("Expected the zone to fail its check, got: %s", status)
+	}
+
+	// The failing zone is removed from the integration.
+	h.zones = []string{"mydomain.com"}
+	h.check(context.TODO())
+
+	if status := zoneStatus(t, h, "otherdomain.com"); status != "" {
+		t.Errorf("Expected the condition of the removed zone to be deleted, got: %s", status)
+	}
+
+	if status := zoneStatus(t, h, "mydomain.com"); status != konditions.ConditionCompleted {
+		t.Errorf("Expected the zone to pass its check, got: %s", status)
+	}
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
+	"github.com/pier-oliviert/phonebook/pkg/providers"
+	"github.com/pier-oliviert/phonebook/pkg/utils"
+)
+
+// InProcess runs providers inside the controller's manager instead of their own deployment. Each integration
+// gets its own provider, ProviderReconciler and self-check. They're started when the integration is run, and
+// stopped when the integration is removed, changes, or when the manager stops.
+//
+// Only the leader runs providers, like it would be the case for the provider's deployment.
+type InProcess struct {
+	mgr          ctrl.Manager
+	constructors map[string]providers.Constructor
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	running map[string]*instance
+}
+
+type instance struct {
+	revision string
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewInProcess(mgr ctrl.Manager, constructors map[string]providers.Constructor) *InProcess {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &InProcess{
+		mgr:          mgr,
+		constructors: constructors,
+		ctx:          ctx,
+		cancel:       cancel,
+		running:      map[string]*instance{},
+	}
+}
+
+// Start blocks until the manager stops, and then stops every provider that is still running.
+func (p *InProcess) Start(ctx context.Context) error {
+	<-ctx.Done()
+	p.cancel()
+
+	p.mu.Lock()
+	names := []string{}
+	for name := range p.running {
+		names = append(names, name)
+	}
+	p.mu.Unlock()
+
+	for _, name := range names {
+		p.Stop(name)
+	}
+
+	return nil
+}
+
+// Supports returns true when the provider can run inside the controller.
+func (p *InProcess) Supports(provider string) bool {
+	_, ok := p.constructors[provider]
+	return ok
+}
+
+// Running returns true when the integration's provider runs with the given revision.
+func (p *InProcess) Running(integration, revision string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i, ok := p.running[integration]
+	return ok && i.revision == revision
+}
+
+// Run starts the provider for the integration. The values are the configuration of the provider, as
+// they would be set in the environment of the provider's deployment. If the integration's provider is
+// already running, it's stopped first. The revision identifies the values the provider was started with.
+func (p *InProcess) Run(integration, provider string, values map[string]string, revision string) error {
+	constructor, ok := p.constructors[provider]
+	if !ok {
+		return fmt.Errorf("PB#0014: Provider %s can't run in the controller", provider)
+	}
+
+	p.Stop(integration)
+
+	cfg, err := loadConfig(func(name, def string) string {
+		if value := values[name]; value != "" {
+			return value
+		}
+		return def
+	})
+	if err != nil {
+		return err
+	}
+
+	// The provider reads its configuration from the context instead of the controller's environment.
+	ctx, cancel := context.WithCancel(utils.WithValues(p.ctx, values))
+	ctx = log.IntoContext(ctx, p.mgr.GetLogger().WithValues("Integration", integration))
+
+	i := &instance{revision: revision, cancel: cancel}
+	if err := p.start(ctx, i, cfg, constructor); err != nil {
+		cancel()
+		return err
+	}
+
+	p.mu.Lock()
+	p.running[integration] = i
+	p.mu.Unlock()
+
+	log.FromContext(ctx).Info("[InProcess] Provider started", "Provider", provider, "Revision", revision)
+	return nil
+}
+
+func (p *InProcess) start(ctx context.Context, i *instance, cfg *config, constructor providers.Constructor) error {
+	provider, err := constructor(ctx)
+	if err != nil {
+		// Error coming from a Provider should already be coded, so returning it as is.
+		return err
+	}
+
+	// Providers that hold resources, like a plugin's process, release them when the provider stops.
+	if closer, ok := provider.(interface{ Close() }); ok {
+		i.wg.Add(1)
+		go func() {
+			defer i.wg.Done()
+			<-ctx.Done()
+			closer.Close()
+		}()
+	}
+
+	store := &providers.ProviderStore{}
+	store.Store(provider)
+
+	if err := cfg.configure(ctx, store); err != nil {
+		return err
+	}
+
+	// The controller isn't added to the manager as it would keep running until the manager stops. Its
+	// name is the same every time the integration's provider is started, so the name isn't validated.
+	c, err := controller.NewUnmanaged(fmt.Sprintf("provider-%s", cfg.integration), p.mgr, controller.Options{
+		Reconciler:         cfg.reconciler(p.mgr, store),
+		SkipNameValidation: ptr.To(true),
+	})
+	if err != nil {
+		return fmt.Errorf("PB#0004: Unable to create controller -- %w", err)
+	}
+
+	if err := c.Watch(source.Kind(p.mgr.GetCache(), &phonebook.DNSRecord{}, &handler.TypedEnqueueRequestForObject[*phonebook.DNSRecord]{})); err != nil {
+		return fmt.Errorf("PB#0004: Unable to create controller -- %w", err)
+	}
+
+	h := cfg.health(p.mgr, store)
+
+	i.wg.Add(2)
+	go func() {
+		defer i.wg.Done()
+		if err := c.Start(ctx); err != nil {
+			log.FromContext(ctx).Error(err, "PB#0004: Could not start controller")
+		}
+	}()
+
+	go func() {
+		defer i.wg.Done()
+		h.Start(ctx)
+	}()
+
+	return nil
+}
+
+// Stop the integration's provider and wait for it to return. Nothing happens if the provider isn't running.
+func (p *InProcess) Stop(integration string) {
+	p.mu.Lock()
+	i, ok := p.running[integration]
+	delete(p.running, integration)
+	p.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	i.cancel()
+	i.wg.Wait()
+}
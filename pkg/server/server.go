@@ -5,7 +5,6 @@ import (
 	"crypto/tls"
 	"flag"
 	"fmt"
-	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -22,7 +21,6 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	phonebook "github.com/pier-oliviert/phonebook/api/v1alpha1"
-	reconcilers "github.com/pier-oliviert/phonebook/internal/reconcilers/provider"
 	"github.com/pier-oliviert/phonebook/pkg/providers"
 )
 
@@ -65,39 +63,39 @@ func (s *server) Run() error {
 	}
 
 	tlsOpts = append(tlsOpts, disableHTTP2)
-	integration := env.GetString("PB_INTEGRATION", "")
-	zones := strings.Split(env.GetString("PB_ZONES", ""), ",")
+	cfg, err := loadConfig(env.GetString)
+	if err != nil {
+		return err
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                        scheme,
 		HealthProbeBindAddress:        ":8081",
 		LeaderElection:                true,
-		LeaderElectionID:              fmt.Sprintf("%s-provider.phonebook.se.quencer.io", integration),
+		LeaderElectionID:              fmt.Sprintf("%s-provider.phonebook.se.quencer.io", cfg.integration),
 		LeaderElectionReleaseOnCancel: true,
 	})
 	if err != nil {
 		return fmt.Errorf("PB#0004: Unable to start manager -- %w", err)
 	}
 
-	if err = s.Provider().Configure(context.Background(), integration, zones); err != nil {
-		// Error coming from a Provider should already be coded, so returning it as is.
+	if err = cfg.configure(context.Background(), &s.ProviderStore); err != nil {
 		return err
 	}
 
-	if err = (&reconcilers.ProviderReconciler{
-		Integration:   integration,
-		Store:         &s.ProviderStore,
-		Client:        mgr.GetClient(),
-		Scheme:        mgr.GetScheme(),
-		EventRecorder: mgr.GetEventRecorderFor("dnsrecord"),
-	}).SetupWithManager(mgr); err != nil {
+	if err = cfg.reconciler(mgr, &s.ProviderStore).SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("PB#0004: Unable to create controller -- %w", err)
 	}
 
+	h := cfg.health(mgr, &s.ProviderStore)
+	if err := mgr.Add(h); err != nil {
+		return fmt.Errorf("PB#0004: Unable to set up the provider's check -- %w", err)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		return fmt.Errorf("PB#0004: Unable to set up health check -- %w", err)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	if err := mgr.AddReadyzCheck("readyz", h.Ready); err != nil {
 		return fmt.Errorf("PB#0004: Unable to set up ready check -- %w", err)
 	}
 
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -9,6 +10,31 @@ import (
 
 const kPhonebookConfighPath = "/var/run/configs/provider"
 
+type valuesKey struct{}
+
+// WithValues returns a context that holds the configuration of a provider. When a provider runs
+// inside the controller, the environment is shared by all the integrations so each provider
+// reads its values from the context instead.
+func WithValues(ctx context.Context, values map[string]string) context.Context {
+	return context.WithValue(ctx, valuesKey{}, values)
+}
+
+// RetrieveValue returns the value stored in the context by WithValues. If the context doesn't hold
+// any values, the value is retrieved from the environment or from a file.
+func RetrieveValue(ctx context.Context, name string) (string, error) {
+	values, ok := ctx.Value(valuesKey{}).(map[string]string)
+	if !ok {
+		return RetrieveValueFromEnvOrFile(name)
+	}
+
+	content := values[name]
+	if content == "" {
+		return "", fmt.Errorf("E#4002: %s is not set for this integration", name)
+	}
+
+	return content, nil
+}
+
 // First check if the environment variable is set, if not, let's look for the
 // token at `${kProviderConfigPath}/${kCloudflareAPIKeyName}` and read the content
 // of that file into token